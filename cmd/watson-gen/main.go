@@ -0,0 +1,61 @@
+// Command watson-gen generates MarshalWatson and UnmarshalWatson methods for struct types.
+//
+// Usage:
+//
+//   watson-gen -type=T1,T2,... [-output=FILE] [DIR]
+//
+// It is intended to be used with `go generate`:
+//
+//   //go:generate watson-gen -type=Config
+//
+// See the documentation of pkg/watsongen for details.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/genkami/watson/pkg/watsongen"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of type names; must be set")
+	output := flag.String("output", "", "output file name; default DIR/<type>_watson.go")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: watson-gen -type=T1,T2,... [-output=FILE] [DIR]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	types := strings.Split(*typeNames, ",")
+	src, err := watsongen.Generate(dir, types)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "watson-gen: %s\n", err.Error())
+		os.Exit(1)
+	}
+	path := *output
+	if path == "" {
+		path = filepath.Join(dir, strings.ToLower(types[0])+"_watson.go")
+	}
+	err = ioutil.WriteFile(path, src, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "watson-gen: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
@@ -6,34 +6,40 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 
 	"github.com/genkami/watson/cmd/watson/util"
-	"github.com/genkami/watson/pkg/converter/cbor"
-	"github.com/genkami/watson/pkg/converter/json"
-	"github.com/genkami/watson/pkg/converter/msgpack"
-	"github.com/genkami/watson/pkg/converter/yaml"
+	"github.com/genkami/watson/pkg/converter"
 	"github.com/genkami/watson/pkg/lexer"
 	"github.com/genkami/watson/pkg/types"
 	"github.com/genkami/watson/pkg/vm"
 )
 
 type Runner struct {
-	outType   util.Type
-	mode      util.Mode
-	files     []string
-	m         *vm.VM
-	stackSize int
+	outType     util.Type
+	mode        util.Mode
+	files       []string
+	m           *vm.VM
+	stackSize   int
+	strict      bool
+	strictAllow string
 }
 
+// whitespaces are always allowed by the strict lexer.
+const whitespaces = " \t\r\n"
+
 func NewRunner() *Runner {
 	return &Runner{}
 }
 
 func (r *Runner) parseArgs(args []string) {
 	fs := flag.NewFlagSet("watson decode", flag.ExitOnError)
-	fs.Var(&r.outType, "t", "input type")
+	fs.Var(&r.outType, "t", r.outType.Usage("output type"))
 	fs.Var(&r.mode, "initial-mode", "initial mode of the lexer")
 	fs.IntVar(&r.stackSize, "stack-size", vm.DefaultStackSize, "stack size of the Watson VM")
+	fs.BoolVar(&r.strict, "strict", false, "reject characters that are neither instructions nor whitespaces")
+	fs.StringVar(&r.strictAllow, "strict-allow", "", "characters that are allowed by -strict in addition to whitespaces (escape sequences such as \\t are accepted)")
+	converter.RegisterFlags(fs)
 	err := fs.Parse(args)
 	if errors.Is(err, flag.ErrHelp) {
 		os.Exit(0)
@@ -42,6 +48,9 @@ func (r *Runner) parseArgs(args []string) {
 		fs.PrintDefaults()
 		os.Exit(1)
 	}
+	if unquoted, err := strconv.Unquote(`"` + r.strictAllow + `"`); err == nil {
+		r.strictAllow = unquoted
+	}
 	r.m = vm.NewVM(vm.WithStackSize(r.stackSize))
 	r.files = fs.Args()
 }
@@ -82,21 +91,14 @@ func (r *Runner) openers() []util.Opener {
 }
 
 func (rn *Runner) buildLexer(r io.Reader, name string) *lexer.Lexer {
-	return lexer.NewLexer(
-		r,
+	opts := []lexer.LexerOption{
 		lexer.WithFileName(name),
 		lexer.WithInitialLexerMode(lexer.Mode(rn.mode)),
-	)
-}
-
-type parseError struct {
-	tok *lexer.Token
-	err error
-}
-
-func (p *parseError) Error() string {
-	return fmt.Sprintf("error %+v\n at %#v line %d, column %d\n",
-		p.err, p.tok.FileName, p.tok.Line+1, p.tok.Column+1)
+	}
+	if rn.strict {
+		opts = append(opts, lexer.WithStrict([]byte(whitespaces+rn.strictAllow)...))
+	}
+	return lexer.NewLexer(r, opts...)
 }
 
 func (r *Runner) parseAllFiles() error {
@@ -106,7 +108,7 @@ func (r *Runner) parseAllFiles() error {
 			return err
 		}
 		lex := r.buildLexer(file, o.Name())
-		err = r.parseWatson(lex)
+		err = util.Execute(r.m, lex)
 		file.Close()
 		if err != nil {
 			return err
@@ -116,33 +118,6 @@ func (r *Runner) parseAllFiles() error {
 	return nil
 }
 
-func (r *Runner) parseWatson(lex *lexer.Lexer) error {
-	for {
-		tok, err := lex.Next()
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return &parseError{tok: tok, err: err}
-		}
-		err = r.m.Feed(tok.Op)
-		if err != nil {
-			return &parseError{tok: tok, err: err}
-		}
-	}
-	return nil
-}
-
 func (r *Runner) decode(w io.Writer, v *types.Value) error {
-	switch r.outType {
-	case util.Yaml:
-		return yaml.Decode(w, v)
-	case util.Json:
-		return json.Decode(w, v)
-	case util.Msgpack:
-		return msgpack.Decode(w, v)
-	case util.Cbor:
-		return cbor.Decode(w, v)
-	default:
-		panic("unknown output type")
-	}
+	return r.outType.Converter().Decode(w, v)
 }
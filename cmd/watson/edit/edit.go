@@ -0,0 +1,110 @@
+package edit
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/genkami/watson/cmd/watson/util"
+	"github.com/genkami/watson/pkg/converter"
+	"github.com/genkami/watson/pkg/edit"
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+// Runner runs `watson set` or `watson delete`, which edit a Watson file in place.
+type Runner struct {
+	name      string
+	set       bool
+	valueType util.Type
+	mode      util.Mode
+	stackSize int
+	file      string
+	path      string
+	value     string
+}
+
+// NewSetRunner returns a Runner of `watson set`.
+func NewSetRunner() *Runner {
+	return &Runner{name: "set", set: true}
+}
+
+// NewDeleteRunner returns a Runner of `watson delete`.
+func NewDeleteRunner() *Runner {
+	return &Runner{name: "delete"}
+}
+
+func (r *Runner) parseArgs(args []string) {
+	fs := flag.NewFlagSet("watson "+r.name, flag.ExitOnError)
+	if r.set {
+		fs.Var(&r.valueType, "t", r.valueType.Usage("type of VALUE"))
+		converter.RegisterFlags(fs)
+	}
+	fs.Var(&r.mode, "initial-mode", "initial mode of the lexer")
+	fs.IntVar(&r.stackSize, "stack-size", vm.DefaultStackSize, "stack size of the Watson VM")
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s", err.Error())
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	nargs := 2
+	if r.set {
+		nargs = 3
+	}
+	if fs.NArg() != nargs {
+		fmt.Fprintf(os.Stderr, "expected %d arguments but got %d\n", nargs, fs.NArg())
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	r.file = fs.Arg(0)
+	r.path = fs.Arg(1)
+	if r.set {
+		r.value = fs.Arg(2)
+	}
+}
+
+func (r *Runner) Run(args []string) {
+	r.parseArgs(args)
+	if err := r.run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// run edits the file. The file is left untouched if it returns an error.
+func (r *Runner) run() error {
+	info, err := os.Stat(r.file)
+	if err != nil {
+		return fmt.Errorf("error loading %s: %w", r.file, err)
+	}
+	src, err := ioutil.ReadFile(r.file)
+	if err != nil {
+		return fmt.Errorf("error loading %s: %w", r.file, err)
+	}
+	opts := []edit.Option{edit.WithInitialMode(lexer.Mode(r.mode)), edit.WithStackSize(r.stackSize)}
+	var out []byte
+	if r.set {
+		var v *types.Value
+		v, err = r.valueType.Converter().Encode(strings.NewReader(r.value))
+		if err != nil {
+			return fmt.Errorf("error parsing VALUE: %w", err)
+		}
+		out, err = edit.Set(src, r.path, v, opts...)
+	} else {
+		out, err = edit.Delete(src, r.path, opts...)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", r.file, err)
+	}
+	if err := ioutil.WriteFile(r.file, out, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("error writing %s: %w", r.file, err)
+	}
+	return nil
+}
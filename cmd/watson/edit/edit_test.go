@@ -0,0 +1,104 @@
+package edit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/genkami/watson/pkg/converter/json"
+	"github.com/genkami/watson/pkg/edit"
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+func copyExample(t *testing.T, name string) (string, []byte) {
+	t.Helper()
+	src, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "examples", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "watson-edit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, src
+}
+
+func newSetRunner(t *testing.T, file, path, value string) *Runner {
+	t.Helper()
+	r := NewSetRunner()
+	if err := r.valueType.Set("json"); err != nil {
+		t.Fatal(err)
+	}
+	r.stackSize = vm.DefaultStackSize
+	r.file, r.path, r.value = file, path, value
+	return r
+}
+
+func TestRunLeavesFileUntouchedOnError(t *testing.T) {
+	file, src := copyExample(t, "nginx-deployment.watson")
+	r := newSetRunner(t, file, "no.such.path", "5")
+	if err := r.run(); !errors.Is(err, edit.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound but got %v", err)
+	}
+	r = NewDeleteRunner()
+	r.stackSize = vm.DefaultStackSize
+	r.file, r.path = file, "no.such.path"
+	if err := r.run(); !errors.Is(err, edit.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound but got %v", err)
+	}
+	got, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, got) {
+		t.Errorf("the file is modified: %d bytes -> %d bytes", len(src), len(got))
+	}
+}
+
+func TestRunWritesEditedFile(t *testing.T) {
+	file, _ := copyExample(t, "nginx-deployment.watson")
+	r := newSetRunner(t, file, "[0].spec.replicas", "5")
+	if err := r.run(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := vm.NewVM()
+	if err := m.FeedMulti(lexOps(t, got)); err != nil {
+		t.Fatal(err)
+	}
+	top, err := m.Top()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := top.Array()[0].Object()["spec"].Object()["replicas"].Int(); n != 5 {
+		t.Errorf("expected 5 but got %d", n)
+	}
+}
+
+func lexOps(t *testing.T, src []byte) []vm.Op {
+	t.Helper()
+	var ops []vm.Op
+	lex := lexer.NewLexer(bytes.NewReader(src))
+	for {
+		tok, err := lex.Next()
+		if err == io.EOF {
+			return ops
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		ops = append(ops, tok.Op)
+	}
+}
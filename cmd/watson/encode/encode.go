@@ -8,13 +8,8 @@ import (
 	"os"
 
 	"github.com/genkami/watson/cmd/watson/util"
-	"github.com/genkami/watson/pkg/converter/cbor"
-	"github.com/genkami/watson/pkg/converter/json"
-	"github.com/genkami/watson/pkg/converter/msgpack"
-	"github.com/genkami/watson/pkg/converter/yaml"
-	"github.com/genkami/watson/pkg/dumper"
+	"github.com/genkami/watson/pkg/converter"
 	"github.com/genkami/watson/pkg/lexer"
-	"github.com/genkami/watson/pkg/prettifier"
 	"github.com/genkami/watson/pkg/types"
 )
 
@@ -30,8 +25,9 @@ func NewRunner() *Runner {
 
 func (r *Runner) parseArgs(args []string) {
 	fs := flag.NewFlagSet("watson encode", flag.ExitOnError)
-	fs.Var(&r.inType, "t", "input type")
+	fs.Var(&r.inType, "t", r.inType.Usage("input type"))
 	fs.Var(&r.mode, "initial-mode", "initial mode of the unlexer")
+	converter.RegisterFlags(fs)
 	err := fs.Parse(args)
 	if errors.Is(err, flag.ErrHelp) {
 		os.Exit(0)
@@ -74,22 +70,10 @@ func (r *Runner) Run(args []string) {
 }
 
 func (rn *Runner) encode(r io.Reader) (*types.Value, error) {
-	switch rn.inType {
-	case util.Yaml:
-		return yaml.Encode(r)
-	case util.Json:
-		return json.Encode(r)
-	case util.Msgpack:
-		return msgpack.Encode(r)
-	case util.Cbor:
-		return cbor.Encode(r)
-	default:
-		panic("unknown input type")
-	}
+	r = rn.inType.Detect(rn.opener.Name(), r)
+	return rn.inType.Converter().Encode(r)
 }
 
 func (r *Runner) dump(w io.Writer, v *types.Value) error {
-	unl := prettifier.NewPrettifier(lexer.NewUnlexer(w, lexer.WithInitialUnlexerMode(lexer.Mode(r.mode))))
-	d := dumper.NewDumper(unl)
-	return d.Dump(v)
+	return util.Dump(w, v, lexer.Mode(r.mode))
 }
@@ -0,0 +1,70 @@
+package genstruct
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/genkami/watson/cmd/watson/util"
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/structgen"
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+type Runner struct {
+	typeName    string
+	packageName string
+	mode        util.Mode
+	stackSize   int
+	files       []util.Opener
+}
+
+func NewRunner() *Runner {
+	return &Runner{}
+}
+
+func (r *Runner) parseArgs(args []string) {
+	fs := flag.NewFlagSet("watson gen-struct", flag.ExitOnError)
+	fs.StringVar(&r.typeName, "type-name", structgen.DefaultTypeName, "name of the top-level type")
+	fs.StringVar(&r.packageName, "package", structgen.DefaultPackageName, "name of the package of the generated code")
+	fs.Var(&r.mode, "initial-mode", "initial mode of the lexer")
+	fs.IntVar(&r.stackSize, "stack-size", vm.DefaultStackSize, "stack size of the Watson VM")
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s", err.Error())
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	if fs.NArg() == 0 {
+		r.files = []util.Opener{util.NewRWCOpener("<stdin>", os.Stdin)}
+		return
+	}
+	for _, path := range fs.Args() {
+		r.files = append(r.files, util.NewFileOpener(path, os.O_RDONLY, 0))
+	}
+}
+
+func (r *Runner) Run(args []string) {
+	r.parseArgs(args)
+	samples := make([]*types.Value, 0, len(r.files))
+	for _, o := range r.files {
+		v, err := util.LoadValue(o, lexer.Mode(r.mode), r.stackSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading %s: %s\n", o.Name(), err.Error())
+			os.Exit(1)
+		}
+		samples = append(samples, v)
+	}
+	err := structgen.Generate(os.Stdout, samples,
+		structgen.WithTypeName(r.typeName),
+		structgen.WithPackageName(r.packageName),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error generating structs: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
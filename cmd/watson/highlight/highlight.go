@@ -0,0 +1,76 @@
+package highlight
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/genkami/watson/cmd/watson/util"
+	"github.com/genkami/watson/pkg/highlight"
+	"github.com/genkami/watson/pkg/lexer"
+)
+
+type Runner struct {
+	format highlight.Format
+	mode   util.Mode
+	file   util.Opener
+}
+
+func NewRunner() *Runner {
+	return &Runner{}
+}
+
+func (r *Runner) parseArgs(args []string) {
+	fs := flag.NewFlagSet("watson highlight", flag.ExitOnError)
+	fs.Var(&r.format, "format", "output format (ansi or html)")
+	fs.Var(&r.mode, "initial-mode", "initial mode of the lexer")
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s", err.Error())
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	switch fs.NArg() {
+	case 0:
+		r.file = util.NewRWCOpener("<stdin>", os.Stdin)
+	case 1:
+		r.file = util.NewFileOpener(fs.Arg(0), os.O_RDONLY, 0)
+	default:
+		fmt.Fprintf(os.Stderr, "too many files\n")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+}
+
+func (r *Runner) Run(args []string) {
+	r.parseArgs(args)
+	file, err := r.file.Open()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't open %s: %s\n", r.file.Name(), err.Error())
+		os.Exit(1)
+	}
+	src, err := ioutil.ReadAll(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't read %s: %s\n", r.file.Name(), err.Error())
+		os.Exit(1)
+	}
+	if r.format == highlight.HTML {
+		fmt.Fprintf(os.Stdout, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<style>\n%s</style>\n</head>\n<body>\n", highlight.HTMLStyle)
+	}
+	err = highlight.Highlight(os.Stdout, src,
+		highlight.WithFormat(r.format),
+		highlight.WithInitialMode(lexer.Mode(r.mode)),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't write the output: %s\n", err.Error())
+		os.Exit(1)
+	}
+	if r.format == highlight.HTML {
+		fmt.Fprintf(os.Stdout, "</body>\n</html>\n")
+	}
+}
@@ -0,0 +1,70 @@
+package inferschema
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/genkami/watson/cmd/watson/util"
+	"github.com/genkami/watson/pkg/converter"
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/schema"
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+type Runner struct {
+	outType   util.Type
+	maxEnum   int
+	mode      util.Mode
+	stackSize int
+	files     []util.Opener
+}
+
+func NewRunner() *Runner {
+	return &Runner{}
+}
+
+func (r *Runner) parseArgs(args []string) {
+	fs := flag.NewFlagSet("watson infer-schema", flag.ExitOnError)
+	fs.Var(&r.outType, "t", r.outType.Usage("output type"))
+	fs.IntVar(&r.maxEnum, "max-enum", schema.DefaultMaxEnum, "maximum number of distinct strings that become an enum (0 disables enums)")
+	fs.Var(&r.mode, "initial-mode", "initial mode of the lexer")
+	fs.IntVar(&r.stackSize, "stack-size", vm.DefaultStackSize, "stack size of the Watson VM")
+	converter.RegisterFlags(fs)
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s", err.Error())
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	if fs.NArg() == 0 {
+		r.files = []util.Opener{util.NewRWCOpener("<stdin>", os.Stdin)}
+		return
+	}
+	for _, path := range fs.Args() {
+		r.files = append(r.files, util.NewFileOpener(path, os.O_RDONLY, 0))
+	}
+}
+
+func (r *Runner) Run(args []string) {
+	r.parseArgs(args)
+	samples := make([]*types.Value, 0, len(r.files))
+	for _, o := range r.files {
+		v, err := util.LoadValue(o, lexer.Mode(r.mode), r.stackSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading %s: %s\n", o.Name(), err.Error())
+			os.Exit(1)
+		}
+		samples = append(samples, v)
+	}
+	s := schema.Infer(samples, schema.WithMaxEnum(r.maxEnum))
+	err := r.outType.Converter().Decode(os.Stdout, s)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't write the schema: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
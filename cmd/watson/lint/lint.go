@@ -0,0 +1,149 @@
+package lint
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/genkami/watson/cmd/watson/util"
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/lint"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+type format string
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+func (f *format) String() string {
+	if *f == "" {
+		return formatText
+	}
+	return string(*f)
+}
+
+func (f *format) Set(s string) error {
+	switch s {
+	case formatText, formatJSON:
+		*f = format(s)
+	default:
+		return fmt.Errorf("unknown format: %s", s)
+	}
+	return nil
+}
+
+// rules is a comma-separated list of lint.Rules.
+type rules []lint.Rule
+
+func (rs *rules) String() string {
+	names := make([]string, 0, len(*rs))
+	for _, r := range *rs {
+		names = append(names, string(r))
+	}
+	return strings.Join(names, ",")
+}
+
+func (rs *rules) Set(s string) error {
+	for _, name := range strings.Split(s, ",") {
+		r := lint.Rule(strings.TrimSpace(name))
+		if r.Description() == "" {
+			return fmt.Errorf("unknown rule: %s", r)
+		}
+		*rs = append(*rs, r)
+	}
+	return nil
+}
+
+type Runner struct {
+	format    format
+	only      rules
+	disable   rules
+	mode      util.Mode
+	stackSize int
+	files     []util.Opener
+}
+
+func NewRunner() *Runner {
+	return &Runner{}
+}
+
+func (r *Runner) parseArgs(args []string) {
+	fs := flag.NewFlagSet("watson lint", flag.ExitOnError)
+	fs.Var(&r.format, "format", "output format (text or json)")
+	fs.Var(&r.only, "only", "comma-separated list of the only rules to check")
+	fs.Var(&r.disable, "disable", "comma-separated list of rules not to check")
+	fs.Var(&r.mode, "initial-mode", "initial mode of the lexer")
+	fs.IntVar(&r.stackSize, "stack-size", vm.DefaultStackSize, "stack size of the Watson VM")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of watson lint:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "Rules:\n")
+		for _, rule := range lint.Rules() {
+			fmt.Fprintf(fs.Output(), "  %s: %s\n", rule, rule.Description())
+		}
+	}
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s", err.Error())
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	if fs.NArg() == 0 {
+		r.files = []util.Opener{util.NewRWCOpener("<stdin>", os.Stdin)}
+		return
+	}
+	for _, path := range fs.Args() {
+		r.files = append(r.files, util.NewFileOpener(path, os.O_RDONLY, 0))
+	}
+}
+
+func (r *Runner) Run(args []string) {
+	r.parseArgs(args)
+	all := make([]*lint.Diagnostic, 0)
+	for _, o := range r.files {
+		opts := []lint.Option{
+			lint.WithInitialMode(lexer.Mode(r.mode)),
+			lint.WithStackSize(r.stackSize),
+			lint.WithFileName(o.Name()),
+			lint.Disable(r.disable...),
+		}
+		if r.only != nil {
+			opts = append(opts, lint.Only(r.only...))
+		}
+		file, err := o.Open()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "can't open %s: %s\n", o.Name(), err.Error())
+			os.Exit(1)
+		}
+		diags, err := lint.Lint(file, opts...)
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error linting %s: %s\n", o.Name(), err.Error())
+			os.Exit(1)
+		}
+		all = append(all, diags...)
+	}
+	if r.format == formatJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(all); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing output: %s\n", err.Error())
+			os.Exit(1)
+		}
+	} else {
+		for _, d := range all {
+			fmt.Fprintln(os.Stdout, d.String())
+		}
+	}
+	if len(all) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,49 @@
+package lsp
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/genkami/watson/cmd/watson/util"
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/lsp"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+type Runner struct {
+	mode      util.Mode
+	stackSize int
+}
+
+func NewRunner() *Runner {
+	return &Runner{}
+}
+
+func (r *Runner) parseArgs(args []string) {
+	fs := flag.NewFlagSet("watson lsp", flag.ExitOnError)
+	fs.Var(&r.mode, "initial-mode", "initial mode of the lexer")
+	fs.IntVar(&r.stackSize, "stack-size", vm.DefaultStackSize, "stack size of the Watson VM")
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s", err.Error())
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+}
+
+func (r *Runner) Run(args []string) {
+	r.parseArgs(args)
+	s := lsp.NewServer(os.Stdin, os.Stdout,
+		lsp.WithInitialMode(lexer.Mode(r.mode)),
+		lsp.WithStackSize(r.stackSize),
+	)
+	err := s.Serve()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "language server stopped: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
@@ -5,7 +5,29 @@ import (
 	"os"
 
 	"github.com/genkami/watson/cmd/watson/decode"
+	"github.com/genkami/watson/cmd/watson/edit"
 	"github.com/genkami/watson/cmd/watson/encode"
+	"github.com/genkami/watson/cmd/watson/genstruct"
+	"github.com/genkami/watson/cmd/watson/highlight"
+	"github.com/genkami/watson/cmd/watson/inferschema"
+	"github.com/genkami/watson/cmd/watson/lint"
+	"github.com/genkami/watson/cmd/watson/lsp"
+	"github.com/genkami/watson/cmd/watson/merge"
+	"github.com/genkami/watson/cmd/watson/transcode"
+	"github.com/genkami/watson/cmd/watson/validate"
+
+	// Built-in converters. Import other packages here to make more formats available.
+	_ "github.com/genkami/watson/pkg/converter/cbor"
+	_ "github.com/genkami/watson/pkg/converter/csv"
+	_ "github.com/genkami/watson/pkg/converter/dotenv"
+	_ "github.com/genkami/watson/pkg/converter/gosrc"
+	_ "github.com/genkami/watson/pkg/converter/ini"
+	_ "github.com/genkami/watson/pkg/converter/json"
+	_ "github.com/genkami/watson/pkg/converter/msgpack"
+	_ "github.com/genkami/watson/pkg/converter/properties"
+	_ "github.com/genkami/watson/pkg/converter/toml"
+	_ "github.com/genkami/watson/pkg/converter/xml"
+	_ "github.com/genkami/watson/pkg/converter/yaml"
 )
 
 type Runner interface {
@@ -13,8 +35,18 @@ type Runner interface {
 }
 
 var allCmds = map[string]Runner{
-	"decode": decode.NewRunner(),
-	"encode": encode.NewRunner(),
+	"decode":       decode.NewRunner(),
+	"delete":       edit.NewDeleteRunner(),
+	"encode":       encode.NewRunner(),
+	"gen-struct":   genstruct.NewRunner(),
+	"highlight":    highlight.NewRunner(),
+	"infer-schema": inferschema.NewRunner(),
+	"lint":         lint.NewRunner(),
+	"lsp":          lsp.NewRunner(),
+	"merge":        merge.NewRunner(),
+	"set":          edit.NewSetRunner(),
+	"transcode":    transcode.NewRunner(),
+	"validate":     validate.NewRunner(),
 }
 
 func main() {
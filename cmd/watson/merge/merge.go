@@ -0,0 +1,157 @@
+package merge
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/genkami/watson/cmd/watson/util"
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+type objectStrategy types.ObjectStrategy
+
+func (s *objectStrategy) String() string {
+	switch types.ObjectStrategy(*s) {
+	case types.MergeObjects:
+		return "merge"
+	case types.ReplaceObjects:
+		return "replace"
+	default:
+		panic("unknown strategy")
+	}
+}
+
+func (s *objectStrategy) Set(name string) error {
+	switch name {
+	case "merge":
+		*s = objectStrategy(types.MergeObjects)
+	case "replace":
+		*s = objectStrategy(types.ReplaceObjects)
+	default:
+		return fmt.Errorf("unknown strategy: %s", name)
+	}
+	return nil
+}
+
+type arrayStrategy types.ArrayStrategy
+
+func (s *arrayStrategy) String() string {
+	switch types.ArrayStrategy(*s) {
+	case types.ReplaceArrays:
+		return "replace"
+	case types.AppendArrays:
+		return "append"
+	default:
+		panic("unknown strategy")
+	}
+}
+
+func (s *arrayStrategy) Set(name string) error {
+	switch name {
+	case "replace":
+		*s = arrayStrategy(types.ReplaceArrays)
+	case "append":
+		*s = arrayStrategy(types.AppendArrays)
+	default:
+		return fmt.Errorf("unknown strategy: %s", name)
+	}
+	return nil
+}
+
+type nilStrategy types.NilStrategy
+
+func (s *nilStrategy) String() string {
+	switch types.NilStrategy(*s) {
+	case types.NilReplaces:
+		return "replace"
+	case types.NilDeletes:
+		return "delete"
+	case types.NilIgnored:
+		return "ignore"
+	default:
+		panic("unknown strategy")
+	}
+}
+
+func (s *nilStrategy) Set(name string) error {
+	switch name {
+	case "replace":
+		*s = nilStrategy(types.NilReplaces)
+	case "delete":
+		*s = nilStrategy(types.NilDeletes)
+	case "ignore":
+		*s = nilStrategy(types.NilIgnored)
+	default:
+		return fmt.Errorf("unknown strategy: %s", name)
+	}
+	return nil
+}
+
+type Runner struct {
+	objects   objectStrategy
+	arrays    arrayStrategy
+	nils      nilStrategy
+	mode      util.Mode
+	outMode   util.Mode
+	stackSize int
+	files     []string
+}
+
+func NewRunner() *Runner {
+	return &Runner{}
+}
+
+func (r *Runner) parseArgs(args []string) {
+	fs := flag.NewFlagSet("watson merge", flag.ExitOnError)
+	fs.Var(&r.objects, "objects", "how to merge objects (merge or replace)")
+	fs.Var(&r.arrays, "arrays", "how to merge arrays (replace or append)")
+	fs.Var(&r.nils, "nil", "how to treat nil in overlays (replace, delete, or ignore)")
+	fs.Var(&r.mode, "initial-mode", "initial mode of the lexer")
+	fs.Var(&r.outMode, "output-mode", "initial mode of the unlexer")
+	fs.IntVar(&r.stackSize, "stack-size", vm.DefaultStackSize, "stack size of the Watson VM")
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s", err.Error())
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	r.files = fs.Args()
+	if len(r.files) == 0 {
+		fmt.Fprintf(os.Stderr, "no files specified\n")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+}
+
+func (r *Runner) Run(args []string) {
+	r.parseArgs(args)
+	var merged *types.Value
+	for _, path := range r.files {
+		o := util.NewFileOpener(path, os.O_RDONLY, 0)
+		v, err := util.LoadValue(o, lexer.Mode(r.mode), r.stackSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading %s: %s\n", o.Name(), err.Error())
+			os.Exit(1)
+		}
+		if merged == nil {
+			merged = v
+			continue
+		}
+		merged = types.Merge(merged, v,
+			types.WithObjectStrategy(types.ObjectStrategy(r.objects)),
+			types.WithArrayStrategy(types.ArrayStrategy(r.arrays)),
+			types.WithNilStrategy(types.NilStrategy(r.nils)),
+		)
+	}
+	err := util.Dump(os.Stdout, merged, lexer.Mode(r.outMode))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing output: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
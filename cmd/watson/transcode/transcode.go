@@ -0,0 +1,127 @@
+package transcode
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/genkami/watson/cmd/watson/util"
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+// defaultDialectName is the name of lexer.DefaultDialect that can be passed to -from and -to.
+const defaultDialectName = "watson"
+
+type Runner struct {
+	fromPath  string
+	toPath    string
+	from      *lexer.Dialect
+	to        *lexer.Dialect
+	mode      util.Mode
+	stackSize int
+	file      util.Opener
+}
+
+func NewRunner() *Runner {
+	return &Runner{}
+}
+
+func (r *Runner) parseArgs(args []string) {
+	fs := flag.NewFlagSet("watson transcode", flag.ExitOnError)
+	fs.StringVar(&r.fromPath, "from", defaultDialectName, "path to the dialect of the input, or \"watson\"")
+	fs.StringVar(&r.toPath, "to", defaultDialectName, "path to the dialect of the output, or \"watson\"")
+	fs.Var(&r.mode, "initial-mode", "initial mode of the lexer and the unlexer")
+	fs.IntVar(&r.stackSize, "stack-size", vm.DefaultStackSize, "stack size of the Watson VM that loads dialects")
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s", err.Error())
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	switch fs.NArg() {
+	case 0:
+		r.file = util.NewRWCOpener("<stdin>", os.Stdin)
+	case 1:
+		r.file = util.NewFileOpener(fs.Arg(0), os.O_RDONLY, 0)
+	default:
+		fmt.Fprintf(os.Stderr, "too many files\n")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+}
+
+func (r *Runner) Run(args []string) {
+	var err error
+	r.parseArgs(args)
+	r.from, err = r.loadDialect(r.fromPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading %s: %s\n", r.fromPath, err.Error())
+		os.Exit(1)
+	}
+	r.to, err = r.loadDialect(r.toPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading %s: %s\n", r.toPath, err.Error())
+		os.Exit(1)
+	}
+	err = r.transcode()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// loadDialect loads a dialect from the file at path, which is Watson unless it has an extension of a registered type.
+func (r *Runner) loadDialect(path string) (*lexer.Dialect, error) {
+	if path == defaultDialectName {
+		return lexer.DefaultDialect, nil
+	}
+	v, err := util.LoadFile(path, &util.Type{}, lexer.A, r.stackSize)
+	if err != nil {
+		return nil, err
+	}
+	return lexer.DialectFromValue(v)
+}
+
+// transcode converts each Op in the input into the output dialect. Bytes that don't represent any Op are dropped.
+func (r *Runner) transcode() error {
+	file, err := r.file.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	lex := lexer.NewLexer(bufio.NewReader(file),
+		lexer.WithFileName(r.file.Name()),
+		lexer.WithInitialLexerMode(lexer.Mode(r.mode)),
+		lexer.WithLexerDialect(r.from),
+	)
+	w := bufio.NewWriter(os.Stdout)
+	unl := lexer.NewUnlexer(w,
+		lexer.WithInitialUnlexerMode(lexer.Mode(r.mode)),
+		lexer.WithUnlexerDialect(r.to),
+	)
+	ops := make([]vm.Op, 1024)
+	for {
+		n, lexErr := lex.ReadOps(ops)
+		for _, op := range ops[:n] {
+			if err := unl.Write(op); err != nil {
+				return err
+			}
+		}
+		if lexErr == io.EOF {
+			break
+		} else if lexErr != nil {
+			return &util.ParseError{Err: lexErr}
+		}
+	}
+	_, err = w.WriteString("\n")
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
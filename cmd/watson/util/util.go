@@ -1,12 +1,20 @@
 package util
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
+	"github.com/genkami/watson/pkg/converter"
+	"github.com/genkami/watson/pkg/dumper"
 	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/prettifier"
+	"github.com/genkami/watson/pkg/sourcemap"
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
 )
 
 type Mode lexer.Mode
@@ -44,57 +52,72 @@ func (m *Mode) Set(s string) error {
 var assertModeIsValue = Mode(0)
 var _ flag.Value = &assertModeIsValue
 
-type Type int
+const defaultTypeName = "yaml"
 
-const (
-	Yaml Type = iota
-	Json
-	Msgpack
-	Cbor
-)
-
-const (
-	typeNameYaml    = "yaml"
-	typeNameJson    = "json"
-	typeNameMsgpack = "msgpack"
-	typeNameCbor    = "cbor"
-)
+// Type is a flag.Value that selects one of the converters registered to `pkg/converter`.
+type Type struct {
+	c converter.Converter
+}
 
 func (t *Type) String() string {
-	switch *t {
-	case Yaml:
-		return typeNameYaml
-	case Json:
-		return typeNameJson
-	case Msgpack:
-		return typeNameMsgpack
-	case Cbor:
-		return typeNameCbor
-	default:
-		panic("unknown type")
+	if t.c == nil {
+		return ""
 	}
+	return t.c.Name()
 }
 
 func (t *Type) Set(s string) error {
-	switch s {
-	case "":
-		*t = Yaml
-	case typeNameYaml:
-		*t = Yaml
-	case typeNameJson:
-		*t = Json
-	case typeNameMsgpack:
-		*t = Msgpack
-	case typeNameCbor:
-		*t = Cbor
-	default:
-		return fmt.Errorf("unknown type: %s", s)
+	c, ok := converter.Lookup(s)
+	if !ok {
+		return fmt.Errorf("unknown type: %s (available types: %s)", s, strings.Join(converter.Names(), ", "))
 	}
+	t.c = c
 	return nil
 }
 
-var assertTypeIsValue = Type(0)
-var _ flag.Value = &assertTypeIsValue
+// IsSet reports whether the type is explicitly specified.
+func (t *Type) IsSet() bool {
+	return t.c != nil
+}
+
+// Converter returns the selected converter, or the default one if nothing is selected.
+func (t *Type) Converter() converter.Converter {
+	if t.c != nil {
+		return t.c
+	}
+	c, ok := converter.Lookup(defaultTypeName)
+	if !ok {
+		panic("the default converter is not registered")
+	}
+	return c
+}
+
+// Detect selects a converter by the extension of name and the content of r if the type is not explicitly specified.
+// Only binary formats are detected by the content, since a text may be valid in more than one text format
+// (e.g. a YAML flow mapping looks like JSON); any other input is left to the default converter.
+// It returns a reader that must be used instead of r.
+func (t *Type) Detect(name string, r io.Reader) io.Reader {
+	if t.c != nil {
+		return r
+	}
+	if c, ok := converter.LookupByExtension(name); ok {
+		t.c = c
+		return r
+	}
+	br := bufio.NewReaderSize(r, converter.SniffSize)
+	head, _ := br.Peek(converter.SniffSize)
+	if c, ok := converter.SniffBinary(head); ok {
+		t.c = c
+	}
+	return br
+}
+
+// Usage returns a usage of the flag.
+func (t *Type) Usage(desc string) string {
+	return fmt.Sprintf("%s (%s)", desc, strings.Join(converter.Names(), ", "))
+}
+
+var _ flag.Value = &Type{}
 
 type Opener interface {
 	Name() string
@@ -146,3 +169,118 @@ func (o *FileOpener) Open() (io.ReadWriteCloser, error) {
 }
 
 var _ Opener = &FileOpener{}
+
+// ParseError is an error that occurred while executing a Watson file.
+type ParseError struct {
+	Token *lexer.Token
+	Err   error
+}
+
+func (p *ParseError) Error() string {
+	if p.Token == nil {
+		return fmt.Sprintf("error %+v\n", p.Err)
+	}
+	return fmt.Sprintf("error %+v\n at %#v line %d, column %d\n",
+		p.Err, p.Token.FileName, p.Token.Line+1, p.Token.Column+1)
+}
+
+func (p *ParseError) Unwrap() error {
+	return p.Err
+}
+
+// Execute feeds all Ops read by lex to m.
+func Execute(m *vm.VM, lex *lexer.Lexer) error {
+	return execute(lex, func(tok *lexer.Token) error {
+		return m.Feed(tok.Op)
+	})
+}
+
+// execute calls feed with each token read by lex.
+func execute(lex *lexer.Lexer, feed func(*lexer.Token) error) error {
+	toks := make([]lexer.Token, batchSize)
+	for {
+		n, lexErr := lex.NextN(toks)
+		for i := range toks[:n] {
+			err := feed(&toks[i])
+			if err != nil {
+				return &ParseError{Token: &toks[i], Err: err}
+			}
+		}
+		if lexErr == io.EOF {
+			return nil
+		} else if lexErr != nil {
+			return &ParseError{Err: lexErr}
+		}
+	}
+}
+
+// batchSize is the number of Tokens that Execute reads at once.
+const batchSize = 1024
+
+// LoadValue executes a Watson file opened by o on a fresh VM and returns the value at the top of its stack.
+func LoadValue(o Opener, mode lexer.Mode, stackSize int) (*types.Value, error) {
+	file, err := o.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	m := vm.NewVM(vm.WithStackSize(stackSize))
+	lex := lexer.NewLexer(file, lexer.WithFileName(o.Name()), lexer.WithInitialLexerMode(mode))
+	err = Execute(m, lex)
+	if err != nil {
+		return nil, err
+	}
+	return m.Top()
+}
+
+// LoadValueWithSourceMap is the same as LoadValue except that it also returns where each part of the value comes from.
+func LoadValueWithSourceMap(o Opener, mode lexer.Mode, stackSize int) (*types.Value, *sourcemap.SourceMap, error) {
+	file, err := o.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	rec := sourcemap.NewRecorder()
+	m := vm.NewVM(vm.WithStackSize(stackSize), vm.WithTracer(rec))
+	lex := lexer.NewLexer(file, lexer.WithFileName(o.Name()), lexer.WithInitialLexerMode(mode))
+	err = execute(lex, func(tok *lexer.Token) error {
+		return rec.Feed(m, tok)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	v, err := m.Top()
+	if err != nil {
+		return nil, nil, err
+	}
+	sm, err := rec.SourceMap()
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, sm, nil
+}
+
+// LoadFile loads a value from the file at path, such as a schema.
+// The file is converted by t if t is set or path has an extension of a registered converter; otherwise it is executed as Watson.
+func LoadFile(path string, t *Type, mode lexer.Mode, stackSize int) (*types.Value, error) {
+	o := NewFileOpener(path, os.O_RDONLY, 0)
+	if !t.IsSet() {
+		if _, ok := converter.LookupByExtension(path); !ok {
+			return LoadValue(o, mode, stackSize)
+		}
+	}
+	file, err := o.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	in := t.Detect(path, file)
+	return t.Converter().Encode(in)
+}
+
+// Dump writes the prettified Watson Representation of v to w.
+func Dump(w io.Writer, v *types.Value, mode lexer.Mode) error {
+	unl := prettifier.NewPrettifier(lexer.NewUnlexer(w, lexer.WithInitialUnlexerMode(mode)))
+	d := dumper.NewDumper(unl)
+	return d.Dump(v)
+}
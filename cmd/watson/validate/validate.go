@@ -0,0 +1,88 @@
+package validate
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/genkami/watson/cmd/watson/util"
+	"github.com/genkami/watson/pkg/converter"
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/schema"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+type Runner struct {
+	schemaPath string
+	schemaType util.Type
+	mode       util.Mode
+	stackSize  int
+	files      []util.Opener
+}
+
+func NewRunner() *Runner {
+	return &Runner{}
+}
+
+func (r *Runner) parseArgs(args []string) {
+	fs := flag.NewFlagSet("watson validate", flag.ExitOnError)
+	fs.StringVar(&r.schemaPath, "schema", "", "path to the schema (mandatory)")
+	fs.Var(&r.schemaType, "schema-type", r.schemaType.Usage("type of the schema if it is not Watson"))
+	fs.Var(&r.mode, "initial-mode", "initial mode of the lexer")
+	fs.IntVar(&r.stackSize, "stack-size", vm.DefaultStackSize, "stack size of the Watson VM")
+	converter.RegisterFlags(fs)
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s", err.Error())
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	if r.schemaPath == "" {
+		fmt.Fprintf(os.Stderr, "-schema is mandatory\n")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	if fs.NArg() == 0 {
+		r.files = []util.Opener{util.NewRWCOpener("<stdin>", os.Stdin)}
+		return
+	}
+	for _, path := range fs.Args() {
+		r.files = append(r.files, util.NewFileOpener(path, os.O_RDONLY, 0))
+	}
+}
+
+func (r *Runner) Run(args []string) {
+	r.parseArgs(args)
+	sv, err := util.LoadFile(r.schemaPath, &r.schemaType, lexer.Mode(r.mode), r.stackSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading %s: %s\n", r.schemaPath, err.Error())
+		os.Exit(1)
+	}
+	s, err := schema.Parse(sv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", r.schemaPath, err.Error())
+		os.Exit(1)
+	}
+	ok := true
+	for _, o := range r.files {
+		v, sm, err := util.LoadValueWithSourceMap(o, lexer.Mode(r.mode), r.stackSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading %s: %s\n", o.Name(), err.Error())
+			os.Exit(1)
+		}
+		for _, viol := range s.Validate(v) {
+			ok = false
+			loc := o.Name()
+			if span, found := sm.LookupNearest(viol.Path); found {
+				loc = span.String()
+			}
+			fmt.Fprintf(os.Stdout, "%s: %s\n", loc, viol.Error())
+		}
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
@@ -18,7 +18,7 @@ func (e *Email) UnmarshalWatson(v *types.Value) error {
 	if v.Kind != types.String {
 		return errors.New("expected string")
 	}
-	parts := strings.Split(string(v.String), "@")
+	parts := strings.Split(string(v.Bytes()), "@")
 	if len(parts) != 2 {
 		return errors.New("value must be like 'local@domain.example.com'")
 	}
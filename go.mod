@@ -3,9 +3,10 @@ module github.com/genkami/watson
 go 1.15
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/fxamacker/cbor/v2 v2.2.0
 	github.com/google/go-cmp v0.5.4
 	github.com/vmihailenco/msgpack v4.0.4+incompatible
 	google.golang.org/appengine v1.6.7 // indirect
-	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
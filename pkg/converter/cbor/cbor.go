@@ -2,13 +2,55 @@
 package cbor
 
 import (
+	"bytes"
 	"io"
 
 	"github.com/fxamacker/cbor/v2"
 
+	"github.com/genkami/watson/pkg/converter"
 	"github.com/genkami/watson/pkg/types"
 )
 
+func init() {
+	converter.Register(&Converter{})
+}
+
+var selfDescribeTag = []byte{0xd9, 0xd9, 0xf7}
+
+// Converter converts CBOR into types.Value and vice versa.
+type Converter struct{}
+
+func (c *Converter) Name() string {
+	return "cbor"
+}
+
+func (c *Converter) Extensions() []string {
+	return []string{".cbor"}
+}
+
+// Sniff recognizes the self-describe tag and maps.
+func (c *Converter) Sniff(head []byte) bool {
+	if bytes.HasPrefix(head, selfDescribeTag) {
+		return true
+	}
+	return len(head) > 0 && 0xa0 <= head[0] && head[0] <= 0xbf
+}
+
+// IsBinary returns true.
+func (c *Converter) IsBinary() bool {
+	return true
+}
+
+func (c *Converter) Encode(r io.Reader) (*types.Value, error) {
+	return Encode(r)
+}
+
+func (c *Converter) Decode(w io.Writer, v *types.Value) error {
+	return Decode(w, v)
+}
+
+var _ converter.BinaryConverter = &Converter{}
+
 func Decode(w io.Writer, val *types.Value) error {
 	obj := val.ToGoObject()
 	enc := cbor.NewEncoder(w)
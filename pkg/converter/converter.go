@@ -0,0 +1,153 @@
+// Package converter defines a common interface of converters between types.Value and other formats, and provides a registry of them.
+//
+// Each converter registers itself in its init function, so importing a converter package is enough to make it available:
+//
+//   import _ "github.com/genkami/watson/pkg/converter/json"
+//
+// Converters that come from other packages can be registered in the same way.
+package converter
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+// ErrUnsupported is returned by converters that support only one direction of conversion.
+var ErrUnsupported = errors.New("unsupported conversion")
+
+// SniffSize is the maximum number of bytes that are passed to Converter.Sniff.
+const SniffSize = 512
+
+// Converter converts a format into types.Value and vice versa.
+type Converter interface {
+	// Name returns a unique name of the format, which is used by the `-t` flag of the CLI.
+	Name() string
+
+	// Extensions returns file extensions (including leading dots) of the format.
+	Extensions() []string
+
+	// Sniff reports whether head, the first (at most SniffSize) bytes of an input, seems to be written in the format.
+	Sniff(head []byte) bool
+
+	// Encode reads the format from r and converts it into a Value.
+	Encode(r io.Reader) (*types.Value, error)
+
+	// Decode converts v into the format and writes it to w.
+	Decode(w io.Writer, v *types.Value) error
+}
+
+// FlagRegisterer can be implemented by Converters that have their own options.
+// Names of flags should be prefixed with the name of the converter so that they don't conflict with each other.
+type FlagRegisterer interface {
+	RegisterFlags(fs *flag.FlagSet)
+}
+
+// BinaryConverter can be implemented by Converters of binary formats.
+// Binary formats can be told apart by their first bytes, whereas an input in a text format is often valid in other text formats too
+// (e.g. a YAML flow mapping looks like JSON), so SniffBinary only trusts Sniff of BinaryConverters.
+type BinaryConverter interface {
+	Converter
+	IsBinary() bool
+}
+
+var (
+	mu         sync.RWMutex
+	converters = map[string]Converter{}
+)
+
+// Register makes c available by its name.
+// It panics if a converter with the same name has already been registered.
+func Register(c Converter) {
+	mu.Lock()
+	defer mu.Unlock()
+	name := c.Name()
+	if _, ok := converters[name]; ok {
+		panic(fmt.Errorf("converter %s is already registered", name))
+	}
+	converters[name] = c
+}
+
+// Lookup returns a converter that has the given name.
+func Lookup(name string) (Converter, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := converters[name]
+	return c, ok
+}
+
+// LookupByExtension returns a converter that handles the extension of the given path.
+func LookupByExtension(path string) (Converter, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return nil, false
+	}
+	for _, c := range All() {
+		for _, e := range c.Extensions() {
+			if e == ext {
+				return c, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Sniff returns the first converter (in the order of their names) that recognizes head.
+func Sniff(head []byte) (Converter, bool) {
+	for _, c := range All() {
+		if c.Sniff(head) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// SniffBinary is the same as Sniff except that it only tries BinaryConverters whose IsBinary returns true.
+func SniffBinary(head []byte) (Converter, bool) {
+	for _, c := range All() {
+		if b, ok := c.(BinaryConverter); ok && b.IsBinary() && c.Sniff(head) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// All returns all registered converters sorted by their names.
+func All() []Converter {
+	mu.RLock()
+	defer mu.RUnlock()
+	all := make([]Converter, 0, len(converters))
+	for _, c := range converters {
+		all = append(all, c)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Name() < all[j].Name()
+	})
+	return all
+}
+
+// Names returns names of all registered converters in sorted order.
+func Names() []string {
+	all := All()
+	names := make([]string, 0, len(all))
+	for _, c := range all {
+		names = append(names, c.Name())
+	}
+	return names
+}
+
+// RegisterFlags registers flags of all registered converters that implement FlagRegisterer to fs.
+func RegisterFlags(fs *flag.FlagSet) {
+	for _, c := range All() {
+		if fr, ok := c.(FlagRegisterer); ok {
+			fr.RegisterFlags(fs)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package converter
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+type fakeConverter struct {
+	name string
+	exts []string
+}
+
+func (c *fakeConverter) Name() string {
+	return c.name
+}
+
+func (c *fakeConverter) Extensions() []string {
+	return c.exts
+}
+
+func (c *fakeConverter) Sniff(head []byte) bool {
+	return bytes.HasPrefix(head, []byte(c.name))
+}
+
+func (c *fakeConverter) Encode(r io.Reader) (*types.Value, error) {
+	return nil, ErrUnsupported
+}
+
+func (c *fakeConverter) Decode(w io.Writer, v *types.Value) error {
+	return ErrUnsupported
+}
+
+type fakeBinaryConverter struct {
+	fakeConverter
+}
+
+func (c *fakeBinaryConverter) IsBinary() bool {
+	return true
+}
+
+func TestRegistry(t *testing.T) {
+	foo := &fakeConverter{name: "foo", exts: []string{".foo"}}
+	bar := &fakeConverter{name: "bar", exts: []string{".bar", ".baz"}}
+	bin := &fakeBinaryConverter{fakeConverter{name: "bin"}}
+	Register(foo)
+	Register(bar)
+	Register(bin)
+
+	if c, ok := Lookup("foo"); !ok || c != foo {
+		t.Errorf("expected foo but got %#v", c)
+	}
+	if _, ok := Lookup("qux"); ok {
+		t.Errorf("expected qux not to be found")
+	}
+	if c, ok := LookupByExtension("path/to/file.BAZ"); !ok || c != bar {
+		t.Errorf("expected bar but got %#v", c)
+	}
+	if _, ok := LookupByExtension("path/to/file"); ok {
+		t.Errorf("expected a file without extension not to be found")
+	}
+	if c, ok := Sniff([]byte("foo...")); !ok || c != foo {
+		t.Errorf("expected foo but got %#v", c)
+	}
+	if _, ok := Sniff([]byte("unknown")); ok {
+		t.Errorf("expected unknown input not to be sniffed")
+	}
+	if c, ok := SniffBinary([]byte("bin...")); !ok || c != bin {
+		t.Errorf("expected bin but got %#v", c)
+	}
+	if _, ok := SniffBinary([]byte("foo...")); ok {
+		t.Errorf("expected text formats not to be sniffed")
+	}
+	all := All()
+	for i := 1; i < len(all); i++ {
+		if all[i-1].Name() >= all[i].Name() {
+			t.Errorf("converters are not sorted: %#v", Names())
+		}
+	}
+}
+
+func TestRegisterPanicsOnDuplicateNames(t *testing.T) {
+	Register(&fakeConverter{name: "dup"})
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected Register to panic")
+		}
+	}()
+	Register(&fakeConverter{name: "dup"})
+}
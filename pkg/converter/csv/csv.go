@@ -0,0 +1,418 @@
+// Package csv provides a way to convert CSV (and TSV) into types.Value and vice versa.
+//
+// TSV has no quoting: each line is split at delimiters, and quotes are read as they are (see WithoutQuotes).
+//
+// A CSV file is converted into an Array of Objects, each of which corresponds to a row and is keyed by the header row.
+// Cells are converted into Strings unless type inference is enabled (see WithInference).
+//
+// An Array of Objects is written back as CSV. Objects don't keep the order of their keys, so the columns are the keys of the first row in sorted order
+// unless they are specified explicitly (see WithColumns); use WithColumns with the header to keep the order of columns on round-trips.
+// Floats are always written with a decimal point or an exponent so that WithInference converts them back into Floats.
+package csv
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/genkami/watson/pkg/converter"
+	"github.com/genkami/watson/pkg/types"
+)
+
+func init() {
+	converter.Register(NewConverter("csv", []string{".csv"}, ','))
+	tsv := NewConverter("tsv", []string{".tsv", ".tab"}, '\t')
+	tsv.NoQuotes = true
+	converter.Register(tsv)
+}
+
+// Converter converts CSV into types.Value and vice versa.
+type Converter struct {
+	name string
+	exts []string
+
+	// Delimiter is the field delimiter.
+	Delimiter rune
+	// Infer enables WithInference.
+	Infer bool
+	// Columns is a comma-separated list of columns that is passed to WithColumns.
+	Columns string
+	// NoQuotes enables WithoutQuotes.
+	NoQuotes bool
+}
+
+// NewConverter returns a new Converter that has the given name and file extensions, and uses delim as the field delimiter.
+func NewConverter(name string, exts []string, delim rune) *Converter {
+	return &Converter{
+		name:      name,
+		exts:      exts,
+		Delimiter: delim,
+	}
+}
+
+func (c *Converter) Name() string {
+	return c.name
+}
+
+func (c *Converter) Extensions() []string {
+	return c.exts
+}
+
+// Sniff always returns false since CSV has no distinctive header.
+func (c *Converter) Sniff(head []byte) bool {
+	return false
+}
+
+func (c *Converter) RegisterFlags(fs *flag.FlagSet) {
+	fs.Var((*delimiter)(&c.Delimiter), c.name+"-delimiter", "field delimiter of "+strings.ToUpper(c.name))
+	fs.BoolVar(&c.Infer, c.name+"-infer", false, "infer types of "+strings.ToUpper(c.name)+" cells")
+	fs.StringVar(&c.Columns, c.name+"-columns", "", "comma-separated list of columns of "+strings.ToUpper(c.name)+" output")
+}
+
+func (c *Converter) Encode(r io.Reader) (*types.Value, error) {
+	return Encode(r, c.options()...)
+}
+
+func (c *Converter) Decode(w io.Writer, v *types.Value) error {
+	return Decode(w, v, c.options()...)
+}
+
+func (c *Converter) options() []Option {
+	opts := []Option{WithDelimiter(c.Delimiter)}
+	if c.Infer {
+		opts = append(opts, WithInference())
+	}
+	if c.Columns != "" {
+		opts = append(opts, WithColumns(strings.Split(c.Columns, ",")...))
+	}
+	if c.NoQuotes {
+		opts = append(opts, WithoutQuotes())
+	}
+	return opts
+}
+
+var _ converter.Converter = &Converter{}
+var _ converter.FlagRegisterer = &Converter{}
+
+// delimiter is a flag.Value that accepts a single character or an escape sequence such as `\t`.
+type delimiter rune
+
+func (d *delimiter) String() string {
+	return strconv.QuoteRune(rune(*d))
+}
+
+func (d *delimiter) Set(s string) error {
+	if unquoted, err := strconv.Unquote(`"` + s + `"`); err == nil {
+		s = unquoted
+	}
+	if utf8.RuneCountInString(s) != 1 {
+		return fmt.Errorf("delimiter must be a single character: %#v", s)
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	*d = delimiter(r)
+	return nil
+}
+
+// Option configures Encode and Decode.
+type Option interface {
+	apply(*config)
+}
+
+type option func(*config)
+
+func (opt option) apply(c *config) {
+	opt(c)
+}
+
+// WithDelimiter sets the field delimiter. The default is a comma.
+func WithDelimiter(delim rune) Option {
+	return option(func(c *config) {
+		c.delim = delim
+	})
+}
+
+// WithInference makes Encode infer types of cells:
+//   * An empty cell is converted into Nil.
+//   * `true` and `false` are converted into Bools.
+//   * Integers are converted into Ints (or Uints if they don't fit in Int).
+//   * Decimal numbers are converted into Floats.
+//   * Any other cells are converted into Strings.
+func WithInference() Option {
+	return option(func(c *config) {
+		c.infer = true
+	})
+}
+
+// WithColumns specifies the columns that Decode writes and their order.
+func WithColumns(columns ...string) Option {
+	return option(func(c *config) {
+		c.columns = columns
+	})
+}
+
+// WithoutQuotes disables quoting as in TSV. Each line is split at delimiters, and quotes in cells are read and written as they are.
+// Decode fails if a cell contains a delimiter or a newline, which can't be written without quoting.
+func WithoutQuotes() Option {
+	return option(func(c *config) {
+		c.noQuotes = true
+	})
+}
+
+type config struct {
+	delim    rune
+	infer    bool
+	columns  []string
+	noQuotes bool
+}
+
+// recordReader is implemented by *csv.Reader and *plainReader.
+type recordReader interface {
+	Read() ([]string, error)
+}
+
+func (c *config) newReader(r io.Reader) recordReader {
+	if c.noQuotes {
+		return &plainReader{r: bufio.NewReader(r), delim: string(c.delim)}
+	}
+	cr := csv.NewReader(r)
+	cr.Comma = c.delim
+	return cr
+}
+
+// recordWriter is implemented by *csv.Writer and *plainWriter.
+type recordWriter interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+}
+
+func (c *config) newWriter(w io.Writer) recordWriter {
+	if c.noQuotes {
+		return &plainWriter{w: bufio.NewWriter(w), delim: string(c.delim)}
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = c.delim
+	return cw
+}
+
+// plainReader reads records that are separated by newlines and split at delimiters without quoting.
+// Like csv.Reader, it skips empty lines and requires every record to have the same number of fields as the first one.
+type plainReader struct {
+	r      *bufio.Reader
+	delim  string
+	line   int
+	fields int
+}
+
+func (r *plainReader) Read() ([]string, error) {
+	for {
+		line, err := r.r.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return nil, io.EOF
+		} else if err != nil && err != io.EOF {
+			return nil, err
+		}
+		r.line++
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if line == "" {
+			continue
+		}
+		record := strings.Split(line, r.delim)
+		if r.fields == 0 {
+			r.fields = len(record)
+		} else if len(record) != r.fields {
+			return nil, fmt.Errorf("line %d: wrong number of fields", r.line)
+		}
+		return record, nil
+	}
+}
+
+// plainWriter writes records that are read by plainReader.
+type plainWriter struct {
+	w     *bufio.Writer
+	delim string
+	err   error
+}
+
+func (w *plainWriter) Write(record []string) error {
+	for _, field := range record {
+		if strings.Contains(field, w.delim) || strings.ContainsAny(field, "\r\n") {
+			return fmt.Errorf("%q can't be written without quotes", field)
+		}
+	}
+	_, err := w.w.WriteString(strings.Join(record, w.delim) + "\n")
+	return err
+}
+
+func (w *plainWriter) Flush() {
+	w.err = w.w.Flush()
+}
+
+func (w *plainWriter) Error() error {
+	return w.err
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{delim: ','}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	return c
+}
+
+// Encode reads CSV from r and converts it into an Array of Objects.
+func Encode(r io.Reader, opts ...Option) (*types.Value, error) {
+	c := newConfig(opts)
+	cr := c.newReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return types.NewArrayValue([]*types.Value{}), nil
+	} else if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, name := range header {
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column: %s", name)
+		}
+		seen[name] = true
+	}
+	rows := make([]*types.Value, 0)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		row := make(map[string]*types.Value, len(header))
+		for i, name := range header {
+			row[name] = c.cellToValue(record[i])
+		}
+		rows = append(rows, types.NewObjectValue(row))
+	}
+	return types.NewArrayValue(rows), nil
+}
+
+var decimalPattern = regexp.MustCompile(`^[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?$`)
+
+func (c *config) cellToValue(cell string) *types.Value {
+	if !c.infer {
+		return types.NewStringValue([]byte(cell))
+	}
+	switch cell {
+	case "":
+		return types.NewNilValue()
+	case "true":
+		return types.NewBoolValue(true)
+	case "false":
+		return types.NewBoolValue(false)
+	}
+	if n, err := strconv.ParseInt(cell, 10, 64); err == nil {
+		return types.NewIntValue(n)
+	}
+	if n, err := strconv.ParseUint(cell, 10, 64); err == nil {
+		return types.NewUintValue(n)
+	}
+	if decimalPattern.MatchString(cell) {
+		if x, err := strconv.ParseFloat(cell, 64); err == nil {
+			return types.NewFloatValue(x)
+		}
+	}
+	return types.NewStringValue([]byte(cell))
+}
+
+// Decode writes val, which must be an Array of Objects, to w as CSV.
+func Decode(w io.Writer, val *types.Value, opts ...Option) error {
+	c := newConfig(opts)
+	if val.Kind != types.Array {
+		return fmt.Errorf("top-level value must be an Array, but got %#v", val.Kind)
+	}
+	for i, row := range val.Array() {
+		if row.Kind != types.Object {
+			return fmt.Errorf("<root>[%d] must be an Object, but got %#v", i, row.Kind)
+		}
+	}
+	columns := c.columns
+	if len(columns) == 0 {
+		if len(val.Array()) == 0 {
+			return errors.New("can't determine columns of an empty Array")
+		}
+		columns = make([]string, 0, len(val.Array()[0].Object()))
+		for k := range val.Array()[0].Object() {
+			columns = append(columns, k)
+		}
+		sort.Strings(columns)
+	}
+	known := map[string]bool{}
+	for _, name := range columns {
+		known[name] = true
+	}
+	cw := c.newWriter(w)
+	err := cw.Write(columns)
+	if err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for i, row := range val.Array() {
+		for k := range row.Object() {
+			if !known[k] {
+				return fmt.Errorf("<root>[%d] has unknown column %s", i, k)
+			}
+		}
+		for j, name := range columns {
+			cell, ok := row.Object()[name]
+			if !ok {
+				record[j] = ""
+				continue
+			}
+			record[j], err = cellOf(cell)
+			if err != nil {
+				return fmt.Errorf("<root>[%d].%s: %w", i, name, err)
+			}
+		}
+		err = cw.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func cellOf(v *types.Value) (string, error) {
+	switch v.Kind {
+	case types.Int:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case types.Uint:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case types.Float:
+		return formatFloat(v.Float()), nil
+	case types.String:
+		return string(v.Bytes()), nil
+	case types.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case types.Nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("%#v can't be written as a cell", v.Kind)
+	}
+}
+
+// formatFloat formats x so that it is not taken for an integer, e.g. 1 is written as "1.0" rather than "1".
+func formatFloat(x float64) string {
+	s := strconv.FormatFloat(x, 'g', -1, 64)
+	if strings.ContainsAny(s, ".eNI") {
+		// s already has a decimal point or an exponent, or it is NaN or Inf.
+		return s
+	}
+	return s + ".0"
+}
@@ -0,0 +1,141 @@
+package csv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func str(s string) *types.Value {
+	return types.NewStringValue([]byte(s))
+}
+
+func TestEncodeWithoutInference(t *testing.T) {
+	got, err := Encode(strings.NewReader("name,age\nTaro,25\nHanako,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewArrayValue([]*types.Value{
+		types.NewObjectValue(map[string]*types.Value{"name": str("Taro"), "age": str("25")}),
+		types.NewObjectValue(map[string]*types.Value{"name": str("Hanako"), "age": str("")}),
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncodeWithInference(t *testing.T) {
+	got, err := Encode(strings.NewReader("a\tb\tc\td\te\n1\t2.5\ttrue\t\tInf\n"), WithDelimiter('\t'), WithInference())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewArrayValue([]*types.Value{
+		types.NewObjectValue(map[string]*types.Value{
+			"a": types.NewIntValue(1),
+			"b": types.NewFloatValue(2.5),
+			"c": types.NewBoolValue(true),
+			"d": types.NewNilValue(),
+			"e": str("Inf"),
+		}),
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestQuotesInTSV(t *testing.T) {
+	src := "name\tdesc\ntv\t55\" screen\n\"quoted\"\tx\n"
+	got, err := Encode(strings.NewReader(src), WithDelimiter('\t'), WithoutQuotes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewArrayValue([]*types.Value{
+		types.NewObjectValue(map[string]*types.Value{"name": str("tv"), "desc": str(`55" screen`)}),
+		types.NewObjectValue(map[string]*types.Value{"name": str(`"quoted"`), "desc": str("x")}),
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = Decode(buf, got, WithDelimiter('\t'), WithoutQuotes(), WithColumns("name", "desc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("expected %#v but got %#v", src, buf.String())
+	}
+	bad := types.NewArrayValue([]*types.Value{types.NewObjectValue(map[string]*types.Value{"a": str("x\ty")})})
+	if err := Decode(bytes.NewBuffer(nil), bad, WithDelimiter('\t'), WithoutQuotes()); err == nil {
+		t.Errorf("expected an error but got nil")
+	}
+}
+
+func TestEncodeRejectsDuplicateColumns(t *testing.T) {
+	_, err := Encode(strings.NewReader("a,a\n1,2\n"))
+	if err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	v := types.NewArrayValue([]*types.Value{
+		types.NewObjectValue(map[string]*types.Value{"name": str("Taro"), "age": types.NewIntValue(25)}),
+		types.NewObjectValue(map[string]*types.Value{"name": str("Hana, Jr."), "age": types.NewNilValue()}),
+		types.NewObjectValue(map[string]*types.Value{"name": str("Jiro")}),
+	})
+	test := func(want string, opts ...Option) {
+		buf := bytes.NewBuffer(nil)
+		err := Decode(buf, v, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("expected %#v but got %#v", want, got)
+		}
+	}
+	test("age,name\n25,Taro\n,\"Hana, Jr.\"\n,Jiro\n")
+	test("name,age\nTaro,25\n\"Hana, Jr.\",\nJiro,\n", WithColumns("name", "age"))
+	test("age\tname\n25\tTaro\n\tHana, Jr.\n\tJiro\n", WithDelimiter('\t'))
+}
+
+func TestFloatsRoundTrip(t *testing.T) {
+	v := types.NewArrayValue([]*types.Value{
+		types.NewObjectValue(map[string]*types.Value{
+			"one":   types.NewFloatValue(1),
+			"neg":   types.NewFloatValue(-3),
+			"half":  types.NewFloatValue(0.5),
+			"large": types.NewFloatValue(1e21),
+			"int":   types.NewIntValue(1),
+		}),
+	})
+	buf := bytes.NewBuffer(nil)
+	err := Decode(buf, v, WithColumns("one", "neg", "half", "large", "int"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "one,neg,half,large,int\n1.0,-3.0,0.5,1e+21,1\n"; buf.String() != want {
+		t.Errorf("expected %#v but got %#v", want, buf.String())
+	}
+	got, err := Encode(buf, WithInference())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(v, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecodeRejectsUnknownColumns(t *testing.T) {
+	v := types.NewArrayValue([]*types.Value{
+		types.NewObjectValue(map[string]*types.Value{"a": types.NewIntValue(1)}),
+		types.NewObjectValue(map[string]*types.Value{"b": types.NewIntValue(2)}),
+	})
+	err := Decode(bytes.NewBuffer(nil), v)
+	if err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
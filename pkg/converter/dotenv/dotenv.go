@@ -0,0 +1,227 @@
+// Package dotenv provides a way to convert dotenv (`.env`) files into types.Value and vice versa.
+//
+// A dotenv file is converted into an Object whose values are Strings.
+// Each line is either blank, a comment that starts with `#`, or an assignment `KEY=VALUE` optionally prefixed with `export`.
+// Values are read as follows:
+//   * Unquoted values are trimmed, and anything after ` #` is treated as a comment.
+//   * Values in single quotes are read literally.
+//   * Values in double quotes may contain escape sequences `\n`, `\r`, `\t`, `\"`, `\\`, and `\$`.
+//
+// Keys are expanded into nested Objects if WithExpansion is given, and nested Objects are always flattened when writing.
+package dotenv
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/genkami/watson/pkg/converter"
+	"github.com/genkami/watson/pkg/converter/internal/flat"
+	"github.com/genkami/watson/pkg/types"
+)
+
+// DefaultSeparator is a separator that joins keys of nested Objects by default.
+// A dot is not used since it can't appear in names of environment variables.
+const DefaultSeparator = "__"
+
+func init() {
+	converter.Register(&Converter{Separator: DefaultSeparator})
+}
+
+// Converter converts dotenv into types.Value and vice versa.
+type Converter struct {
+	// Separator is passed to WithSeparator.
+	Separator string
+	// Expand enables WithExpansion.
+	Expand bool
+}
+
+func (c *Converter) Name() string {
+	return "dotenv"
+}
+
+func (c *Converter) Extensions() []string {
+	return []string{".env"}
+}
+
+// Sniff always returns false since dotenv has no distinctive header.
+func (c *Converter) Sniff(head []byte) bool {
+	return false
+}
+
+func (c *Converter) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Separator, "dotenv-separator", c.Separator, "separator that joins keys of nested objects")
+	fs.BoolVar(&c.Expand, "dotenv-expand", false, "expand keys that contain separators into nested objects")
+}
+
+func (c *Converter) Encode(r io.Reader) (*types.Value, error) {
+	return Encode(r, c.options()...)
+}
+
+func (c *Converter) Decode(w io.Writer, v *types.Value) error {
+	return Decode(w, v, c.options()...)
+}
+
+func (c *Converter) options() []Option {
+	opts := []Option{WithSeparator(c.Separator)}
+	if c.Expand {
+		opts = append(opts, WithExpansion())
+	}
+	return opts
+}
+
+var _ converter.Converter = &Converter{}
+var _ converter.FlagRegisterer = &Converter{}
+
+// Option configures Encode and Decode.
+type Option interface {
+	apply(*config)
+}
+
+type option func(*config)
+
+func (opt option) apply(c *config) {
+	opt(c)
+}
+
+// WithSeparator sets the separator that joins keys of nested Objects. The default is DefaultSeparator.
+func WithSeparator(sep string) Option {
+	return option(func(c *config) {
+		c.sep = sep
+	})
+}
+
+// WithExpansion makes Encode split keys by the separator and convert them into nested Objects.
+func WithExpansion() Option {
+	return option(func(c *config) {
+		c.expand = true
+	})
+}
+
+type config struct {
+	sep    string
+	expand bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{sep: DefaultSeparator}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	return c
+}
+
+// Encode reads dotenv from r and converts it into an Object.
+func Encode(r io.Reader, opts ...Option) (*types.Value, error) {
+	c := newConfig(opts)
+	sep := ""
+	if c.expand {
+		sep = c.sep
+	}
+	obj := map[string]*types.Value{}
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		i := strings.IndexByte(line, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: missing '='", lineNo)
+		}
+		key := strings.TrimSpace(line[:i])
+		if key == "" {
+			return nil, fmt.Errorf("line %d: empty key", lineNo)
+		}
+		value, err := parseValue(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		err = flat.Set(obj, key, types.NewStringValue([]byte(value)), sep)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return types.NewObjectValue(obj), nil
+}
+
+func parseValue(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, "'"):
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single quote")
+		}
+		return s[1 : end+1], nil
+	case strings.HasPrefix(s, `"`):
+		buf := strings.Builder{}
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '"':
+				return buf.String(), nil
+			case '\\':
+				i++
+				if i >= len(s) {
+					return "", fmt.Errorf("unterminated double quote")
+				}
+				switch s[i] {
+				case 'n':
+					buf.WriteByte('\n')
+				case 'r':
+					buf.WriteByte('\r')
+				case 't':
+					buf.WriteByte('\t')
+				case '"', '\\', '$':
+					buf.WriteByte(s[i])
+				default:
+					buf.WriteByte('\\')
+					buf.WriteByte(s[i])
+				}
+			default:
+				buf.WriteByte(s[i])
+			}
+		}
+		return "", fmt.Errorf("unterminated double quote")
+	default:
+		if i := strings.Index(s, " #"); i >= 0 {
+			s = s[:i]
+		}
+		return strings.TrimSpace(s), nil
+	}
+}
+
+// Decode writes val, which must be an Object, to w as dotenv.
+func Decode(w io.Writer, val *types.Value, opts ...Option) error {
+	c := newConfig(opts)
+	pairs, err := flat.Flatten(val, c.sep)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	for _, p := range pairs {
+		if p.Key == "" || strings.ContainsAny(p.Key, "= \t\r\n#\"'") {
+			return fmt.Errorf("invalid key: %#v", p.Key)
+		}
+		_, err = fmt.Fprintf(bw, "%s=%s\n", p.Key, quote(p.Value))
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func quote(s string) string {
+	if !strings.ContainsAny(s, " \t\r\n#\"'\\$") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,89 @@
+package dotenv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func str(s string) *types.Value {
+	return types.NewStringValue([]byte(s))
+}
+
+func TestEncode(t *testing.T) {
+	src := `# database
+DB__HOST=localhost
+export DB__PORT = 5432 # default port
+GREETING="hello\nworld"
+RAW='a\nb'
+EMPTY=
+`
+	got, err := Encode(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"DB__HOST": str("localhost"),
+		"DB__PORT": str("5432"),
+		"GREETING": str("hello\nworld"),
+		"RAW":      str(`a\nb`),
+		"EMPTY":    str(""),
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncodeWithExpansion(t *testing.T) {
+	got, err := Encode(strings.NewReader("DB__HOST=localhost\nDB__PORT=5432\n"), WithExpansion())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"DB": types.NewObjectValue(map[string]*types.Value{
+			"HOST": str("localhost"),
+			"PORT": str("5432"),
+		}),
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncodeRejectsConflicts(t *testing.T) {
+	_, err := Encode(strings.NewReader("DB=x\nDB__HOST=localhost\n"), WithExpansion())
+	if err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	v := types.NewObjectValue(map[string]*types.Value{
+		"DB": types.NewObjectValue(map[string]*types.Value{
+			"HOST": str("localhost"),
+			"PORT": types.NewIntValue(5432),
+		}),
+		"MOTD": str("it's $HOME"),
+	})
+	buf := bytes.NewBuffer(nil)
+	err := Decode(buf, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "DB__HOST=localhost\nDB__PORT=5432\nMOTD=\"it's \\$HOME\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %#v but got %#v", want, got)
+	}
+	got, err := Encode(buf, WithExpansion())
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Object()["DB"].Object()["PORT"] = str("5432")
+	if diff := cmp.Diff(v, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,260 @@
+// Package gosrc provides a way to convert types.Value into a Go expression.
+//
+// The expression is formatted by gofmt and has one of the following flavors:
+//   * ValueFlavor writes an expression that builds the *types.Value with constructors such as types.NewObjectValue.
+//   * PlainFlavor writes an expression that equals to the result of types.Value.ToGoObject, that is,
+//     a literal of map[string]interface{}, []interface{}, or a scalar.
+//
+// In both flavors, Ints, Uints, and Floats keep their types, keys of Objects are sorted,
+// and NaN, infinities, and negative zero are written with functions in the math package.
+//
+// The conversion is one-way, so Encode returns converter.ErrUnsupported.
+package gosrc
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/genkami/watson/pkg/converter"
+	"github.com/genkami/watson/pkg/types"
+)
+
+func init() {
+	converter.Register(&Converter{})
+}
+
+// Converter converts types.Value into a Go expression.
+type Converter struct {
+	// Flavor is passed to WithFlavor.
+	Flavor Flavor
+}
+
+func (c *Converter) Name() string {
+	return "go"
+}
+
+// Extensions returns nothing since Go source files can't be converted into Values.
+func (c *Converter) Extensions() []string {
+	return nil
+}
+
+func (c *Converter) Sniff(head []byte) bool {
+	return false
+}
+
+func (c *Converter) RegisterFlags(fs *flag.FlagSet) {
+	fs.Var(&c.Flavor, "go-flavor", "flavor of Go expressions (value or plain)")
+}
+
+func (c *Converter) Encode(r io.Reader) (*types.Value, error) {
+	return nil, converter.ErrUnsupported
+}
+
+func (c *Converter) Decode(w io.Writer, v *types.Value) error {
+	return Decode(w, v, WithFlavor(c.Flavor))
+}
+
+var _ converter.Converter = &Converter{}
+var _ converter.FlagRegisterer = &Converter{}
+
+// Flavor specifies which kind of expressions Decode writes.
+type Flavor int
+
+const (
+	// ValueFlavor writes types.Value constructors.
+	ValueFlavor Flavor = iota
+	// PlainFlavor writes literals of map[string]interface{}, []interface{}, and scalars.
+	PlainFlavor
+)
+
+func (f *Flavor) String() string {
+	switch *f {
+	case ValueFlavor:
+		return "value"
+	case PlainFlavor:
+		return "plain"
+	default:
+		panic("unknown flavor")
+	}
+}
+
+func (f *Flavor) Set(name string) error {
+	switch name {
+	case "value":
+		*f = ValueFlavor
+	case "plain":
+		*f = PlainFlavor
+	default:
+		return fmt.Errorf("unknown flavor: %s", name)
+	}
+	return nil
+}
+
+// Option configures Decode.
+type Option interface {
+	apply(*config)
+}
+
+type option func(*config)
+
+func (opt option) apply(c *config) {
+	opt(c)
+}
+
+// WithFlavor sets the flavor of expressions. The default is ValueFlavor.
+func WithFlavor(f Flavor) Option {
+	return option(func(c *config) {
+		c.flavor = f
+	})
+}
+
+type config struct {
+	flavor Flavor
+}
+
+// Decode writes val to w as a gofmt-formatted Go expression followed by a newline.
+func Decode(w io.Writer, val *types.Value, opts ...Option) error {
+	c := &config{flavor: ValueFlavor}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	src := bytes.NewBuffer(nil)
+	switch c.flavor {
+	case ValueFlavor:
+		writeValue(src, val)
+	case PlainFlavor:
+		writePlain(src, val)
+	default:
+		return fmt.Errorf("unknown flavor: %d", c.flavor)
+	}
+	fset := token.NewFileSet()
+	expr, err := parser.ParseExprFrom(fset, "", src.Bytes(), 0)
+	if err != nil {
+		// This should not happen.
+		return fmt.Errorf("generated invalid expression: %w", err)
+	}
+	err = format.Node(w, fset, expr)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte("\n"))
+	return err
+}
+
+func writeValue(buf *bytes.Buffer, val *types.Value) {
+	switch val.Kind {
+	case types.Int:
+		fmt.Fprintf(buf, "types.NewIntValue(%d)", val.Int())
+	case types.Uint:
+		fmt.Fprintf(buf, "types.NewUintValue(%d)", val.Uint())
+	case types.Float:
+		fmt.Fprintf(buf, "types.NewFloatValue(%s)", floatExpr(val.Float()))
+	case types.String:
+		fmt.Fprintf(buf, "types.NewStringValue([]byte(%s))", strconv.Quote(string(val.Bytes())))
+	case types.Object:
+		buf.WriteString("types.NewObjectValue(map[string]*types.Value{")
+		for _, k := range sortedKeys(val.Object()) {
+			fmt.Fprintf(buf, "\n%s: ", strconv.Quote(k))
+			writeValue(buf, val.Object()[k])
+			buf.WriteString(",")
+		}
+		buf.WriteString(newlineIf(len(val.Object()) > 0) + "})")
+	case types.Array:
+		buf.WriteString("types.NewArrayValue([]*types.Value{")
+		for _, elem := range val.Array() {
+			buf.WriteString("\n")
+			writeValue(buf, elem)
+			buf.WriteString(",")
+		}
+		buf.WriteString(newlineIf(len(val.Array()) > 0) + "})")
+	case types.Bool:
+		fmt.Fprintf(buf, "types.NewBoolValue(%t)", val.Bool())
+	case types.Nil:
+		buf.WriteString("types.NewNilValue()")
+	default:
+		panic(fmt.Errorf("invalid kind: %d", val.Kind))
+	}
+}
+
+func writePlain(buf *bytes.Buffer, val *types.Value) {
+	switch val.Kind {
+	case types.Int:
+		fmt.Fprintf(buf, "int64(%d)", val.Int())
+	case types.Uint:
+		fmt.Fprintf(buf, "uint64(%d)", val.Uint())
+	case types.Float:
+		if isSpecial(val.Float()) {
+			buf.WriteString(floatExpr(val.Float()))
+		} else {
+			fmt.Fprintf(buf, "float64(%s)", floatExpr(val.Float()))
+		}
+	case types.String:
+		buf.WriteString(strconv.Quote(string(val.Bytes())))
+	case types.Object:
+		buf.WriteString("map[string]interface{}{")
+		for _, k := range sortedKeys(val.Object()) {
+			fmt.Fprintf(buf, "\n%s: ", strconv.Quote(k))
+			writePlain(buf, val.Object()[k])
+			buf.WriteString(",")
+		}
+		buf.WriteString(newlineIf(len(val.Object()) > 0) + "}")
+	case types.Array:
+		buf.WriteString("[]interface{}{")
+		for _, elem := range val.Array() {
+			buf.WriteString("\n")
+			writePlain(buf, elem)
+			buf.WriteString(",")
+		}
+		buf.WriteString(newlineIf(len(val.Array()) > 0) + "}")
+	case types.Bool:
+		fmt.Fprintf(buf, "%t", val.Bool())
+	case types.Nil:
+		buf.WriteString("nil")
+	default:
+		panic(fmt.Errorf("invalid kind: %d", val.Kind))
+	}
+}
+
+// isSpecial reports whether f can't be written as a constant.
+func isSpecial(f float64) bool {
+	return math.IsNaN(f) || math.IsInf(f, 0) || (f == 0 && math.Signbit(f))
+}
+
+func floatExpr(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "math.NaN()"
+	case math.IsInf(f, 1):
+		return "math.Inf(1)"
+	case math.IsInf(f, -1):
+		return "math.Inf(-1)"
+	case f == 0 && math.Signbit(f):
+		return "math.Copysign(0, -1)"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+func sortedKeys(obj map[string]*types.Value) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newlineIf(cond bool) string {
+	if cond {
+		return "\n"
+	}
+	return ""
+}
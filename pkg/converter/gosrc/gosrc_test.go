@@ -0,0 +1,80 @@
+package gosrc
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/genkami/watson/pkg/converter"
+	"github.com/genkami/watson/pkg/types"
+)
+
+var sample = types.NewObjectValue(map[string]*types.Value{
+	"name":  types.NewStringValue([]byte("app\n")),
+	"port":  types.NewIntValue(8080),
+	"big":   types.NewUintValue(math.MaxUint64),
+	"ratio": types.NewFloatValue(0.5),
+	"nan":   types.NewFloatValue(math.NaN()),
+	"inf":   types.NewFloatValue(math.Inf(-1)),
+	"tags":  types.NewArrayValue([]*types.Value{types.NewBoolValue(true), types.NewNilValue()}),
+	"empty": types.NewObjectValue(map[string]*types.Value{}),
+})
+
+func TestDecodeValueFlavor(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := Decode(buf, sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `types.NewObjectValue(map[string]*types.Value{
+	"big":   types.NewUintValue(18446744073709551615),
+	"empty": types.NewObjectValue(map[string]*types.Value{}),
+	"inf":   types.NewFloatValue(math.Inf(-1)),
+	"name":  types.NewStringValue([]byte("app\n")),
+	"nan":   types.NewFloatValue(math.NaN()),
+	"port":  types.NewIntValue(8080),
+	"ratio": types.NewFloatValue(0.5),
+	"tags": types.NewArrayValue([]*types.Value{
+		types.NewBoolValue(true),
+		types.NewNilValue(),
+	}),
+})
+`
+	if got := buf.String(); got != want {
+		t.Errorf("expected:\n%s\nbut got:\n%s", want, got)
+	}
+}
+
+func TestDecodePlainFlavor(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := Decode(buf, sample, WithFlavor(PlainFlavor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `map[string]interface{}{
+	"big":   uint64(18446744073709551615),
+	"empty": map[string]interface{}{},
+	"inf":   math.Inf(-1),
+	"name":  "app\n",
+	"nan":   math.NaN(),
+	"port":  int64(8080),
+	"ratio": float64(0.5),
+	"tags": []interface{}{
+		true,
+		nil,
+	},
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("expected:\n%s\nbut got:\n%s", want, got)
+	}
+}
+
+func TestEncodeIsUnsupported(t *testing.T) {
+	_, err := (&Converter{}).Encode(strings.NewReader(""))
+	if !errors.Is(err, converter.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported but got %#v", err)
+	}
+}
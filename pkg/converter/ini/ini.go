@@ -0,0 +1,298 @@
+// Package ini provides a way to convert INI files into types.Value and vice versa.
+//
+// An INI file is converted into an Object as follows:
+//   * Keys that appear before any section headers are converted into keys of the top-level Object.
+//   * Each section `[name]` is converted into a nested Object under the key `name`.
+//   * Each line `key = value` (or `key: value`) is converted into a String. Values in double quotes may contain escape sequences `\n`, `\r`, `\t`, `\"`, and `\\`.
+//   * Lines that start with `;` or `#` are comments.
+//
+// If WithExpansion is given, both section names and keys are split by the separator and converted into nested Objects, so `[a.b]` followed by `c.d = 1` is converted into `{"a": {"b": {"c": {"d": "1"}}}}`.
+//
+// When writing INI, scalars in the top-level Object are written before any sections, Objects in the top-level Object are written as sections,
+// and deeper Objects are flattened into keys joined by the separator.
+package ini
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/genkami/watson/pkg/converter"
+	"github.com/genkami/watson/pkg/converter/internal/flat"
+	"github.com/genkami/watson/pkg/types"
+)
+
+// DefaultSeparator is a separator that joins keys of nested Objects by default.
+const DefaultSeparator = "."
+
+func init() {
+	converter.Register(&Converter{Separator: DefaultSeparator})
+}
+
+// Converter converts INI into types.Value and vice versa.
+type Converter struct {
+	// Separator is passed to WithSeparator.
+	Separator string
+	// Expand enables WithExpansion.
+	Expand bool
+}
+
+func (c *Converter) Name() string {
+	return "ini"
+}
+
+func (c *Converter) Extensions() []string {
+	return []string{".ini"}
+}
+
+// Sniff always returns false since INI has no distinctive header.
+func (c *Converter) Sniff(head []byte) bool {
+	return false
+}
+
+func (c *Converter) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Separator, "ini-separator", c.Separator, "separator that joins keys of nested objects")
+	fs.BoolVar(&c.Expand, "ini-expand", false, "expand section names and keys that contain separators into nested objects")
+}
+
+func (c *Converter) Encode(r io.Reader) (*types.Value, error) {
+	return Encode(r, c.options()...)
+}
+
+func (c *Converter) Decode(w io.Writer, v *types.Value) error {
+	return Decode(w, v, c.options()...)
+}
+
+func (c *Converter) options() []Option {
+	opts := []Option{WithSeparator(c.Separator)}
+	if c.Expand {
+		opts = append(opts, WithExpansion())
+	}
+	return opts
+}
+
+var _ converter.Converter = &Converter{}
+var _ converter.FlagRegisterer = &Converter{}
+
+// Option configures Encode and Decode.
+type Option interface {
+	apply(*config)
+}
+
+type option func(*config)
+
+func (opt option) apply(c *config) {
+	opt(c)
+}
+
+// WithSeparator sets the separator that joins keys of nested Objects. The default is DefaultSeparator.
+func WithSeparator(sep string) Option {
+	return option(func(c *config) {
+		c.sep = sep
+	})
+}
+
+// WithExpansion makes Encode split section names and keys by the separator and convert them into nested Objects.
+func WithExpansion() Option {
+	return option(func(c *config) {
+		c.expand = true
+	})
+}
+
+type config struct {
+	sep    string
+	expand bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{sep: DefaultSeparator}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	return c
+}
+
+// Encode reads INI from r and converts it into an Object.
+func Encode(r io.Reader, opts ...Option) (*types.Value, error) {
+	c := newConfig(opts)
+	sep := ""
+	if c.expand {
+		sep = c.sep
+	}
+	root := map[string]*types.Value{}
+	section := root
+	prefix := ""
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("line %d: unterminated section header", lineNo)
+			}
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if name == "" {
+				return nil, fmt.Errorf("line %d: empty section name", lineNo)
+			}
+			if c.expand {
+				section = root
+				prefix = name + sep
+				if _, ok := lookup(root, name, sep); !ok {
+					err := flat.Set(root, name, types.NewObjectValue(map[string]*types.Value{}), sep)
+					if err != nil {
+						return nil, fmt.Errorf("line %d: %w", lineNo, err)
+					}
+				}
+				continue
+			}
+			v, ok := root[name]
+			if !ok {
+				v = types.NewObjectValue(map[string]*types.Value{})
+				root[name] = v
+			} else if v.Kind != types.Object {
+				return nil, fmt.Errorf("line %d: section %s conflicts with a key", lineNo, name)
+			}
+			section = v.Object()
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: missing '=' or ':'", lineNo)
+		}
+		key := strings.TrimSpace(line[:i])
+		if key == "" {
+			return nil, fmt.Errorf("line %d: empty key", lineNo)
+		}
+		value, err := parseValue(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		err = flat.Set(section, prefix+key, types.NewStringValue([]byte(value)), sep)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return types.NewObjectValue(root), nil
+}
+
+func lookup(obj map[string]*types.Value, key, sep string) (*types.Value, bool) {
+	var v *types.Value
+	for _, k := range strings.Split(key, sep) {
+		var ok bool
+		v, ok = obj[k]
+		if !ok {
+			return nil, false
+		}
+		obj = v.Object()
+	}
+	return v, true
+}
+
+func parseValue(s string) (string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return s, nil
+	}
+	buf := strings.Builder{}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return buf.String(), nil
+		case '\\':
+			i++
+			if i >= len(s) {
+				return "", fmt.Errorf("unterminated double quote")
+			}
+			switch s[i] {
+			case 'n':
+				buf.WriteByte('\n')
+			case 'r':
+				buf.WriteByte('\r')
+			case 't':
+				buf.WriteByte('\t')
+			default:
+				buf.WriteByte(s[i])
+			}
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+	return "", fmt.Errorf("unterminated double quote")
+}
+
+// Decode writes val, which must be an Object, to w as INI.
+func Decode(w io.Writer, val *types.Value, opts ...Option) error {
+	c := newConfig(opts)
+	if val.Kind != types.Object {
+		return fmt.Errorf("top-level value must be an Object, but got %#v", val.Kind)
+	}
+	globals := map[string]*types.Value{}
+	sections := make([]string, 0)
+	for k, v := range val.Object() {
+		if v.Kind == types.Object {
+			sections = append(sections, k)
+		} else {
+			globals[k] = v
+		}
+	}
+	sort.Strings(sections)
+	bw := bufio.NewWriter(w)
+	err := writePairs(bw, types.NewObjectValue(globals), c.sep)
+	if err != nil {
+		return err
+	}
+	for i, name := range sections {
+		if i > 0 || len(globals) > 0 {
+			_, err = bw.WriteString("\n")
+			if err != nil {
+				return err
+			}
+		}
+		if strings.ContainsAny(name, "[]\r\n") {
+			return fmt.Errorf("invalid section name: %#v", name)
+		}
+		_, err = fmt.Fprintf(bw, "[%s]\n", name)
+		if err != nil {
+			return err
+		}
+		err = writePairs(bw, val.Object()[name], c.sep)
+		if err != nil {
+			return fmt.Errorf("[%s]: %w", name, err)
+		}
+	}
+	return bw.Flush()
+}
+
+func writePairs(w io.Writer, obj *types.Value, sep string) error {
+	pairs, err := flat.Flatten(obj, sep)
+	if err != nil {
+		return err
+	}
+	for _, p := range pairs {
+		if p.Key == "" || strings.ContainsAny(p.Key, "=:[\r\n") || strings.TrimSpace(p.Key) != p.Key || p.Key[0] == ';' || p.Key[0] == '#' {
+			return fmt.Errorf("invalid key: %#v", p.Key)
+		}
+		_, err = fmt.Fprintf(w, "%s = %s\n", p.Key, quote(p.Value))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func quote(s string) string {
+	if strings.TrimSpace(s) == s && !strings.ContainsAny(s, "\r\n\t\"\\") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,94 @@
+package ini
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func str(s string) *types.Value {
+	return types.NewStringValue([]byte(s))
+}
+
+func TestEncode(t *testing.T) {
+	src := `; global settings
+name = app
+
+[server]
+host = localhost
+port: 8080
+
+[server.tls]
+cert.path = "/etc/ssl/app.pem"
+`
+	got, err := Encode(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"name": str("app"),
+		"server": types.NewObjectValue(map[string]*types.Value{
+			"host": str("localhost"),
+			"port": str("8080"),
+		}),
+		"server.tls": types.NewObjectValue(map[string]*types.Value{
+			"cert.path": str("/etc/ssl/app.pem"),
+		}),
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = Encode(strings.NewReader(src), WithExpansion())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = types.NewObjectValue(map[string]*types.Value{
+		"name": str("app"),
+		"server": types.NewObjectValue(map[string]*types.Value{
+			"host": str("localhost"),
+			"port": str("8080"),
+			"tls": types.NewObjectValue(map[string]*types.Value{
+				"cert": types.NewObjectValue(map[string]*types.Value{
+					"path": str("/etc/ssl/app.pem"),
+				}),
+			}),
+		}),
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	v := types.NewObjectValue(map[string]*types.Value{
+		"name": str("app"),
+		"server": types.NewObjectValue(map[string]*types.Value{
+			"port":  types.NewIntValue(8080),
+			"motd":  str(" hi "),
+			"tls":   types.NewObjectValue(map[string]*types.Value{"enabled": types.NewBoolValue(true)}),
+			"hosts": types.NewArrayValue([]*types.Value{str("a"), str("b")}),
+		}),
+	})
+	buf := bytes.NewBuffer(nil)
+	err := Decode(buf, v, WithSeparator("/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `name = app
+
+[server]
+hosts/0 = a
+hosts/1 = b
+motd = " hi "
+port = 8080
+tls/enabled = true
+`
+	if got := buf.String(); got != want {
+		t.Errorf("expected %#v but got %#v", want, got)
+	}
+}
@@ -0,0 +1,120 @@
+// Package flat converts nested Objects into flat key/value pairs and vice versa.
+// It is shared by converters of flat formats such as dotenv, INI, and Java properties.
+package flat
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+// Pair is a key/value pair of a flat format.
+type Pair struct {
+	Key   string
+	Value string
+}
+
+// Flatten converts v, which must be an Object, into pairs sorted by their keys.
+// Keys of nested Objects are joined with sep, and elements of Arrays are keyed by their indices.
+// Empty Objects and empty Arrays are dropped since they have no pairs.
+func Flatten(v *types.Value, sep string) ([]Pair, error) {
+	if v.Kind != types.Object {
+		return nil, fmt.Errorf("top-level value must be an Object, but got %#v", v.Kind)
+	}
+	pairs := make([]Pair, 0, len(v.Object()))
+	err := flatten(&pairs, "", v, sep)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Key < pairs[j].Key
+	})
+	for i := 1; i < len(pairs); i++ {
+		if pairs[i-1].Key == pairs[i].Key {
+			return nil, fmt.Errorf("duplicate key after flattening: %s", pairs[i].Key)
+		}
+	}
+	return pairs, nil
+}
+
+func flatten(pairs *[]Pair, prefix string, v *types.Value, sep string) error {
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + sep + k
+	}
+	switch v.Kind {
+	case types.Object:
+		for k, elem := range v.Object() {
+			err := flatten(pairs, join(k), elem, sep)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case types.Array:
+		for i, elem := range v.Array() {
+			err := flatten(pairs, join(strconv.Itoa(i)), elem, sep)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		text, err := Text(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", prefix, err)
+		}
+		*pairs = append(*pairs, Pair{Key: prefix, Value: text})
+		return nil
+	}
+}
+
+// Text converts a scalar into its textual representation. Nil is converted into an empty string.
+func Text(v *types.Value) (string, error) {
+	switch v.Kind {
+	case types.Int:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case types.Uint:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case types.Float:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	case types.String:
+		return string(v.Bytes()), nil
+	case types.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case types.Nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("%#v can't be written as text", v.Kind)
+	}
+}
+
+// Set sets value to obj at key.
+// If sep is not empty, key is split by sep and the value is set to the nested Object, which is created if necessary.
+func Set(obj map[string]*types.Value, key string, value *types.Value, sep string) error {
+	path := []string{key}
+	if sep != "" {
+		path = strings.Split(key, sep)
+	}
+	for i, k := range path[:len(path)-1] {
+		child, ok := obj[k]
+		if !ok {
+			child = types.NewObjectValue(map[string]*types.Value{})
+			obj[k] = child
+		} else if child.Kind != types.Object {
+			return fmt.Errorf("%s conflicts with %s", key, strings.Join(path[:i+1], sep))
+		}
+		obj = child.Object()
+	}
+	last := path[len(path)-1]
+	if old, ok := obj[last]; ok && old.Kind == types.Object && value.Kind != types.Object {
+		return fmt.Errorf("%s conflicts with its children", key)
+	}
+	obj[last] = value
+	return nil
+}
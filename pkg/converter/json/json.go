@@ -1,25 +1,403 @@
 // Package json provides a way to convert JSON into types.Value and vice versa.
+//
+// Numbers in JSON are converted as follows:
+//   * Integral literals that fit in int64 are converted into Int.
+//   * Integral literals that are greater than math.MaxInt64 and fit in uint64 are converted into Uint.
+//   * Any other numbers are converted into Float.
+//
+// Since plain JSON can't distinguish Uint from Int and can't represent NaN, infinities, or strings that are not valid UTF-8,
+// this package also supports extended JSON (see WithExtended). In extended JSON, these values are represented as tagged objects:
+//   {"$uint": "18446744073709551615"}  Uint
+//   {"$float": "NaN"}                  NaN (also "Infinity" and "-Infinity")
+//   {"$bytes": "//79"}                 String that is not valid UTF-8 (encoded in standard base64)
+//   {"$entries": [[k, v], ...]}        Object that has a key that is not valid UTF-8, whose keys are Strings or $bytes
+//   {"$object": {...}}                 Object that would otherwise be mistaken for one of the above
 package json
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"flag"
+	"fmt"
 	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/genkami/watson/pkg/converter"
 	"github.com/genkami/watson/pkg/types"
 )
 
-func Decode(w io.Writer, val *types.Value) error {
-	obj := val.ToGoObject()
+const (
+	tagUint   = "$uint"
+	tagFloat  = "$float"
+	tagBytes   = "$bytes"
+	tagEntries = "$entries"
+	tagObject  = "$object"
+
+	nameNaN    = "NaN"
+	namePosInf = "Infinity"
+	nameNegInf = "-Infinity"
+)
+
+func init() {
+	converter.Register(&Converter{})
+}
+
+// Converter converts JSON into types.Value and vice versa.
+type Converter struct {
+	// Extended enables extended JSON.
+	Extended bool
+}
+
+func (c *Converter) Name() string {
+	return "json"
+}
+
+func (c *Converter) Extensions() []string {
+	return []string{".json"}
+}
+
+// Sniff recognizes objects and arrays.
+func (c *Converter) Sniff(head []byte) bool {
+	head = bytes.TrimLeft(head, " \t\r\n")
+	return len(head) > 0 && (head[0] == '{' || head[0] == '[')
+}
+
+func (c *Converter) RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.Extended, "json-extended", false, "use extended JSON")
+}
+
+func (c *Converter) Encode(r io.Reader) (*types.Value, error) {
+	return Encode(r, c.options()...)
+}
+
+func (c *Converter) Decode(w io.Writer, v *types.Value) error {
+	return Decode(w, v, c.options()...)
+}
+
+func (c *Converter) options() []Option {
+	if c.Extended {
+		return []Option{WithExtended()}
+	}
+	return nil
+}
+
+var _ converter.Converter = &Converter{}
+var _ converter.FlagRegisterer = &Converter{}
+
+// Option configures Encode and Decode.
+type Option interface {
+	apply(*config)
+}
+
+type option func(*config)
+
+func (opt option) apply(c *config) {
+	opt(c)
+}
+
+// WithExtended enables extended JSON, which can represent any Value losslessly.
+func WithExtended() Option {
+	return option(func(c *config) {
+		c.extended = true
+	})
+}
+
+type config struct {
+	extended bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	return c
+}
+
+// Decode writes val to w as JSON.
+// Unless extended JSON is enabled, it fails if val contains NaN or infinities.
+func Decode(w io.Writer, val *types.Value, opts ...Option) error {
+	c := newConfig(opts)
+	obj, err := c.toJSONObject(val)
+	if err != nil {
+		return err
+	}
 	enc := json.NewEncoder(w)
 	return enc.Encode(obj)
 }
 
-func Encode(r io.Reader) (*types.Value, error) {
+// Encode reads JSON from r and converts it into a Value.
+func Encode(r io.Reader, opts ...Option) (*types.Value, error) {
+	c := newConfig(opts)
 	var any interface{}
 	dec := json.NewDecoder(r)
+	dec.UseNumber()
 	err := dec.Decode(&any)
 	if err != nil {
 		return nil, err
 	}
-	return types.ToValue(any)
+	return c.toValue(any)
+}
+
+func (c *config) toValue(any interface{}) (*types.Value, error) {
+	switch any := any.(type) {
+	case nil:
+		return types.NewNilValue(), nil
+	case bool:
+		return types.NewBoolValue(any), nil
+	case string:
+		return types.NewStringValue([]byte(any)), nil
+	case json.Number:
+		return numberToValue(any)
+	case []interface{}:
+		arr := make([]*types.Value, 0, len(any))
+		for _, elem := range any {
+			v, err := c.toValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return types.NewArrayValue(arr), nil
+	case map[string]interface{}:
+		if c.extended && len(any) == 1 {
+			for k, elem := range any {
+				if isTag(k) {
+					return c.taggedToValue(k, elem)
+				}
+			}
+		}
+		return c.objectToValue(any)
+	default:
+		return nil, fmt.Errorf("unexpected JSON value: %#v", any)
+	}
+}
+
+func (c *config) objectToValue(obj map[string]interface{}) (*types.Value, error) {
+	o := make(map[string]*types.Value, len(obj))
+	for k, elem := range obj {
+		v, err := c.toValue(elem)
+		if err != nil {
+			return nil, err
+		}
+		o[k] = v
+	}
+	return types.NewObjectValue(o), nil
+}
+
+func (c *config) taggedToValue(tag string, elem interface{}) (*types.Value, error) {
+	if tag == tagObject {
+		obj, ok := elem.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s must be an object", tag)
+		}
+		return c.objectToValue(obj)
+	}
+	if tag == tagEntries {
+		return c.entriesToValue(elem)
+	}
+	s, ok := elem.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a string", tag)
+	}
+	switch tag {
+	case tagUint:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewUintValue(n), nil
+	case tagFloat:
+		switch s {
+		case nameNaN:
+			return types.NewFloatValue(math.NaN()), nil
+		case namePosInf:
+			return types.NewFloatValue(math.Inf(1)), nil
+		case nameNegInf:
+			return types.NewFloatValue(math.Inf(-1)), nil
+		}
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewFloatValue(x), nil
+	case tagBytes:
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewStringValue(b), nil
+	default:
+		panic(fmt.Errorf("unknown tag: %s", tag))
+	}
+}
+
+// entriesToValue converts `[[k, v], ...]` into an Object, where each k is either a string or a String tagged with $bytes.
+func (c *config) entriesToValue(elem interface{}) (*types.Value, error) {
+	entries, ok := elem.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array", tagEntries)
+	}
+	o := make(map[string]*types.Value, len(entries))
+	for _, entry := range entries {
+		pair, ok := entry.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("each element of %s must be an array of a key and a value", tagEntries)
+		}
+		k, err := c.toValue(pair[0])
+		if err != nil {
+			return nil, err
+		}
+		if k.Kind != types.String {
+			return nil, fmt.Errorf("keys of %s must be strings", tagEntries)
+		}
+		v, err := c.toValue(pair[1])
+		if err != nil {
+			return nil, err
+		}
+		o[string(k.Bytes())] = v
+	}
+	return types.NewObjectValue(o), nil
+}
+
+func numberToValue(n json.Number) (*types.Value, error) {
+	s := n.String()
+	if !strings.ContainsAny(s, ".eE") {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return types.NewIntValue(i), nil
+		}
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return types.NewUintValue(u), nil
+		}
+	}
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewFloatValue(x), nil
+}
+
+func (c *config) toJSONObject(val *types.Value) (interface{}, error) {
+	switch val.Kind {
+	case types.Int:
+		return json.Number(strconv.FormatInt(val.Int(), 10)), nil
+	case types.Uint:
+		s := strconv.FormatUint(val.Uint(), 10)
+		if c.extended {
+			return map[string]interface{}{tagUint: s}, nil
+		}
+		return json.Number(s), nil
+	case types.Float:
+		return c.floatToJSONObject(val.Float())
+	case types.String:
+		if c.extended && !utf8.Valid(val.Bytes()) {
+			return map[string]interface{}{tagBytes: base64.StdEncoding.EncodeToString(val.Bytes())}, nil
+		}
+		return string(val.Bytes()), nil
+	case types.Object:
+		if c.extended && hasInvalidKey(val.Object()) {
+			return c.toJSONEntries(val.Object())
+		}
+		obj := make(map[string]interface{}, len(val.Object()))
+		for k, v := range val.Object() {
+			elem, err := c.toJSONObject(v)
+			if err != nil {
+				return nil, err
+			}
+			obj[k] = elem
+		}
+		if c.extended && len(obj) == 1 {
+			for k := range obj {
+				if isTag(k) {
+					return map[string]interface{}{tagObject: obj}, nil
+				}
+			}
+		}
+		return obj, nil
+	case types.Array:
+		arr := make([]interface{}, 0, len(val.Array()))
+		for _, v := range val.Array() {
+			elem, err := c.toJSONObject(v)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, elem)
+		}
+		return arr, nil
+	case types.Bool:
+		return val.Bool(), nil
+	case types.Nil:
+		return nil, nil
+	default:
+		panic(fmt.Errorf("invalid kind: %d", val.Kind))
+	}
+}
+
+func hasInvalidKey(obj map[string]*types.Value) bool {
+	for k := range obj {
+		if !utf8.ValidString(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// toJSONEntries writes obj as `{"$entries": [[k, v], ...]}` so that keys that are not valid UTF-8 can be written as $bytes.
+// Entries are sorted by their keys so that the output is deterministic.
+func (c *config) toJSONEntries(obj map[string]*types.Value) (interface{}, error) {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	entries := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		key, err := c.toJSONObject(types.NewStringValue([]byte(k)))
+		if err != nil {
+			return nil, err
+		}
+		v, err := c.toJSONObject(obj[k])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, []interface{}{key, v})
+	}
+	return map[string]interface{}{tagEntries: entries}, nil
+}
+
+func (c *config) floatToJSONObject(x float64) (interface{}, error) {
+	var name string
+	switch {
+	case math.IsNaN(x):
+		name = nameNaN
+	case math.IsInf(x, 1):
+		name = namePosInf
+	case math.IsInf(x, -1):
+		name = nameNegInf
+	default:
+		// Make sure that the number is read as a Float again.
+		s := strconv.FormatFloat(x, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eE") {
+			s += ".0"
+		}
+		return json.Number(s), nil
+	}
+	if !c.extended {
+		return nil, fmt.Errorf("%s can't be represented in JSON (use extended JSON instead)", name)
+	}
+	return map[string]interface{}{tagFloat: name}, nil
+}
+
+func isTag(k string) bool {
+	switch k {
+	case tagUint, tagFloat, tagBytes, tagEntries, tagObject:
+		return true
+	default:
+		return false
+	}
 }
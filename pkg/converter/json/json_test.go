@@ -0,0 +1,134 @@
+package json
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func TestEncodeInfersNumberKinds(t *testing.T) {
+	test := func(src string, want *types.Value) {
+		got, err := Encode(strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", src, diff)
+		}
+	}
+	test("0", types.NewIntValue(0))
+	test("-123", types.NewIntValue(-123))
+	test("9223372036854775807", types.NewIntValue(math.MaxInt64))
+	test("-9223372036854775808", types.NewIntValue(math.MinInt64))
+	test("9223372036854775808", types.NewUintValue(math.MaxInt64+1))
+	test("18446744073709551615", types.NewUintValue(math.MaxUint64))
+	test("18446744073709551616", types.NewFloatValue(18446744073709551616))
+	test("1.0", types.NewFloatValue(1))
+	test("1e3", types.NewFloatValue(1000))
+	test("-2.5", types.NewFloatValue(-2.5))
+}
+
+func TestEncodeConvertsNestedValues(t *testing.T) {
+	got, err := Encode(strings.NewReader(`{"a": [1, 2.5, "x", true, null]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"a": types.NewArrayValue([]*types.Value{
+			types.NewIntValue(1),
+			types.NewFloatValue(2.5),
+			types.NewStringValue([]byte("x")),
+			types.NewBoolValue(true),
+			types.NewNilValue(),
+		}),
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecodeWritesFloatsDistinguishably(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := Decode(buf, types.NewArrayValue([]*types.Value{
+		types.NewIntValue(1),
+		types.NewFloatValue(1),
+		types.NewUintValue(2),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[1,1.0,2]\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %#v but got %#v", want, got)
+	}
+}
+
+func TestDecodeFailsOnNaNUnlessExtended(t *testing.T) {
+	err := Decode(bytes.NewBuffer(nil), types.NewFloatValue(math.NaN()))
+	if err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
+
+func TestExtendedJSONRoundTrips(t *testing.T) {
+	test := func(orig *types.Value) {
+		buf := bytes.NewBuffer(nil)
+		err := Decode(buf, orig, WithExtended())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Encode(buf, WithExtended())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(orig, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	}
+	test(types.NewIntValue(-1))
+	test(types.NewUintValue(1))
+	test(types.NewUintValue(math.MaxUint64))
+	test(types.NewFloatValue(3))
+	test(types.NewFloatValue(math.Inf(1)))
+	test(types.NewFloatValue(math.Inf(-1)))
+	test(types.NewStringValue([]byte("hello")))
+	test(types.NewStringValue([]byte{0xff, 0xfe, 0x00}))
+	test(types.NewObjectValue(map[string]*types.Value{
+		"$uint": types.NewStringValue([]byte("not a uint")),
+	}))
+	test(types.NewObjectValue(map[string]*types.Value{
+		"$object": types.NewObjectValue(map[string]*types.Value{}),
+	}))
+	test(types.NewObjectValue(map[string]*types.Value{
+		"\xff\xfe": types.NewStringValue([]byte{0xff}),
+		"a":        types.NewObjectValue(map[string]*types.Value{"\x80": types.NewNilValue()}),
+	}))
+	test(types.NewObjectValue(map[string]*types.Value{
+		"$entries": types.NewArrayValue([]*types.Value{}),
+	}))
+	test(types.NewArrayValue([]*types.Value{
+		types.NewUintValue(0),
+		types.NewNilValue(),
+		types.NewBoolValue(false),
+	}))
+}
+
+func TestExtendedJSONRoundTripsNaN(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := Decode(buf, types.NewFloatValue(math.NaN()), WithExtended())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Encode(buf, WithExtended())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.IsNaN() {
+		t.Errorf("expected NaN but got %#v", got)
+	}
+}
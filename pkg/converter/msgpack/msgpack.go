@@ -6,9 +6,49 @@ import (
 
 	"github.com/vmihailenco/msgpack"
 
+	"github.com/genkami/watson/pkg/converter"
 	"github.com/genkami/watson/pkg/types"
 )
 
+func init() {
+	converter.Register(&Converter{})
+}
+
+// Converter converts MessagePack into types.Value and vice versa.
+type Converter struct{}
+
+func (c *Converter) Name() string {
+	return "msgpack"
+}
+
+func (c *Converter) Extensions() []string {
+	return []string{".msgpack", ".mpk"}
+}
+
+// Sniff recognizes maps.
+func (c *Converter) Sniff(head []byte) bool {
+	if len(head) == 0 {
+		return false
+	}
+	b := head[0]
+	return (0x80 <= b && b <= 0x8f) || b == 0xde || b == 0xdf
+}
+
+// IsBinary returns true.
+func (c *Converter) IsBinary() bool {
+	return true
+}
+
+func (c *Converter) Encode(r io.Reader) (*types.Value, error) {
+	return Encode(r)
+}
+
+func (c *Converter) Decode(w io.Writer, v *types.Value) error {
+	return Decode(w, v)
+}
+
+var _ converter.BinaryConverter = &Converter{}
+
 func Decode(w io.Writer, val *types.Value) error {
 	obj := val.ToGoObject()
 	enc := msgpack.NewEncoder(w)
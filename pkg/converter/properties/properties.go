@@ -0,0 +1,291 @@
+// Package properties provides a way to convert Java properties files into types.Value and vice versa.
+//
+// A properties file is converted into an Object whose values are Strings.
+// The file is read as described in the documentation of java.util.Properties.load, that is,
+//   * Lines that start with `#` or `!` are comments.
+//   * A key is separated from its value by `=`, `:`, or whitespaces.
+//   * A line that ends with an odd number of backslashes continues to the next line.
+//   * Escape sequences such as `\t`, `\n`, and `\uXXXX` are unescaped.
+//
+// Unlike java.util.Properties.load, files are read as UTF-8 rather than ISO 8859-1.
+//
+// Keys are expanded into nested Objects if WithExpansion is given, and nested Objects are always flattened when writing.
+package properties
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/genkami/watson/pkg/converter"
+	"github.com/genkami/watson/pkg/converter/internal/flat"
+	"github.com/genkami/watson/pkg/types"
+)
+
+// DefaultSeparator is a separator that joins keys of nested Objects by default.
+const DefaultSeparator = "."
+
+func init() {
+	converter.Register(&Converter{Separator: DefaultSeparator})
+}
+
+// Converter converts Java properties into types.Value and vice versa.
+type Converter struct {
+	// Separator is passed to WithSeparator.
+	Separator string
+	// Expand enables WithExpansion.
+	Expand bool
+}
+
+func (c *Converter) Name() string {
+	return "properties"
+}
+
+func (c *Converter) Extensions() []string {
+	return []string{".properties"}
+}
+
+// Sniff always returns false since properties files have no distinctive header.
+func (c *Converter) Sniff(head []byte) bool {
+	return false
+}
+
+func (c *Converter) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Separator, "properties-separator", c.Separator, "separator that joins keys of nested objects")
+	fs.BoolVar(&c.Expand, "properties-expand", false, "expand keys that contain separators into nested objects")
+}
+
+func (c *Converter) Encode(r io.Reader) (*types.Value, error) {
+	return Encode(r, c.options()...)
+}
+
+func (c *Converter) Decode(w io.Writer, v *types.Value) error {
+	return Decode(w, v, c.options()...)
+}
+
+func (c *Converter) options() []Option {
+	opts := []Option{WithSeparator(c.Separator)}
+	if c.Expand {
+		opts = append(opts, WithExpansion())
+	}
+	return opts
+}
+
+var _ converter.Converter = &Converter{}
+var _ converter.FlagRegisterer = &Converter{}
+
+// Option configures Encode and Decode.
+type Option interface {
+	apply(*config)
+}
+
+type option func(*config)
+
+func (opt option) apply(c *config) {
+	opt(c)
+}
+
+// WithSeparator sets the separator that joins keys of nested Objects. The default is DefaultSeparator.
+func WithSeparator(sep string) Option {
+	return option(func(c *config) {
+		c.sep = sep
+	})
+}
+
+// WithExpansion makes Encode split keys by the separator and convert them into nested Objects.
+func WithExpansion() Option {
+	return option(func(c *config) {
+		c.expand = true
+	})
+}
+
+type config struct {
+	sep    string
+	expand bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{sep: DefaultSeparator}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	return c
+}
+
+// Encode reads Java properties from r and converts it into an Object.
+func Encode(r io.Reader, opts ...Option) (*types.Value, error) {
+	c := newConfig(opts)
+	sep := ""
+	if c.expand {
+		sep = c.sep
+	}
+	obj := map[string]*types.Value{}
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		start := lineNo
+		line := strings.TrimLeft(sc.Text(), " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		for continues(line) {
+			line = line[:len(line)-1]
+			if !sc.Scan() {
+				break
+			}
+			lineNo++
+			line += strings.TrimLeft(sc.Text(), " \t\f")
+		}
+		key, value := splitLine(line)
+		k, err := unescape(key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", start, err)
+		}
+		v, err := unescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", start, err)
+		}
+		err = flat.Set(obj, k, types.NewStringValue([]byte(v)), sep)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", start, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return types.NewObjectValue(obj), nil
+}
+
+// continues reports whether line ends with an odd number of backslashes.
+func continues(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitLine splits line into an escaped key and an escaped value.
+func splitLine(line string) (string, string) {
+	i := 0
+	for ; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+			break
+		}
+	}
+	if i >= len(line) {
+		return line, ""
+	}
+	key := line[:i]
+	rest := strings.TrimLeft(line[i:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	return key, rest
+}
+
+func unescape(s string) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+	buf := strings.Builder{}
+	var surrogate rune
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			buf.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i >= len(s) {
+			break
+		}
+		switch s[i] {
+		case 't':
+			buf.WriteByte('\t')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("malformed \\u escape")
+			}
+			n, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\u escape: %w", err)
+			}
+			i += 4
+			r := rune(n)
+			if utf16.IsSurrogate(r) {
+				if surrogate == 0 {
+					surrogate = r
+					continue
+				}
+				r = utf16.DecodeRune(surrogate, r)
+			}
+			surrogate = 0
+			buf.WriteRune(r)
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+	return buf.String(), nil
+}
+
+// Decode writes val, which must be an Object, to w as Java properties.
+func Decode(w io.Writer, val *types.Value, opts ...Option) error {
+	c := newConfig(opts)
+	pairs, err := flat.Flatten(val, c.sep)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	for _, p := range pairs {
+		_, err = fmt.Fprintf(bw, "%s=%s\n", escape(p.Key, true), escape(p.Value, false))
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func escape(s string, isKey bool) string {
+	buf := strings.Builder{}
+	for i, c := range s {
+		switch c {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '=', ':', '#', '!', ' ':
+			if isKey || i == 0 {
+				buf.WriteByte('\\')
+			}
+			buf.WriteRune(c)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(&buf, `\u%04x`, c)
+			} else {
+				buf.WriteRune(c)
+			}
+		}
+	}
+	return buf.String()
+}
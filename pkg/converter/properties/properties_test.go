@@ -0,0 +1,68 @@
+package properties
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func str(s string) *types.Value {
+	return types.NewStringValue([]byte(s))
+}
+
+func TestEncode(t *testing.T) {
+	src := `# comment
+! another comment
+app.name = My App
+app.port:8080
+app.greeting Hello, \
+    world
+key\ with\ spaces=\u3042\ud83d\ude00
+empty
+`
+	got, err := Encode(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"app.name":        str("My App"),
+		"app.port":        str("8080"),
+		"app.greeting":    str("Hello, world"),
+		"key with spaces": str("あ😀"),
+		"empty":           str(""),
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRoundTripWithExpansion(t *testing.T) {
+	orig := types.NewObjectValue(map[string]*types.Value{
+		"app": types.NewObjectValue(map[string]*types.Value{
+			"name":  str(" My App"),
+			"url":   str("http://example.com/#top"),
+			"title": str("a=b"),
+		}),
+		"key:with=specials": str("line1\nline2"),
+	})
+	buf := bytes.NewBuffer(nil)
+	err := Decode(buf, orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "app.name=\\ My App\napp.title=a=b\napp.url=http://example.com/#top\nkey\\:with\\=specials=line1\\nline2\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %#v but got %#v", want, got)
+	}
+	got, err := Encode(buf, WithExpansion())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(orig, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,211 @@
+// Package toml provides a way to convert TOML into types.Value and vice versa.
+//
+// TOML values are converted as follows:
+//   * Integers are converted into Int, and floats (including nan and inf) are converted into Float.
+//   * Tables (including inline tables) are converted into Object, and arrays (including arrays of tables) are converted into Array.
+//   * Datetimes are converted into Strings in their TOML representations, that is,
+//     offset datetimes are written in RFC 3339 (e.g. "1979-05-27T07:32:00Z"),
+//     and local datetimes, local dates, and local times are written as "1979-05-27T07:32:00", "1979-05-27", and "07:32:00" respectively.
+//     Note that they are written back as Strings, not as datetimes.
+//
+// Since TOML is less expressive than Watson, Decode fails if a value has any of the following shapes:
+//   * A top-level value that is not an Object.
+//   * Nil.
+//   * A Uint that is greater than math.MaxInt64.
+//   * A String that is not valid UTF-8.
+package toml
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/genkami/watson/pkg/converter"
+	"github.com/genkami/watson/pkg/types"
+)
+
+const (
+	layoutLocalDatetime = "2006-01-02T15:04:05.999999999"
+	layoutLocalDate     = "2006-01-02"
+	layoutLocalTime     = "15:04:05.999999999"
+
+	// Names of time.Location that are used by github.com/BurntSushi/toml to represent local datetimes.
+	locLocalDatetime = "datetime-local"
+	locLocalDate     = "date-local"
+	locLocalTime     = "time-local"
+)
+
+func init() {
+	converter.Register(&Converter{})
+}
+
+// Converter converts TOML into types.Value and vice versa.
+type Converter struct{}
+
+func (c *Converter) Name() string {
+	return "toml"
+}
+
+func (c *Converter) Extensions() []string {
+	return []string{".toml"}
+}
+
+// Sniff always returns false since TOML has no distinctive header.
+func (c *Converter) Sniff(head []byte) bool {
+	return false
+}
+
+func (c *Converter) Encode(r io.Reader) (*types.Value, error) {
+	return Encode(r)
+}
+
+func (c *Converter) Decode(w io.Writer, v *types.Value) error {
+	return Decode(w, v)
+}
+
+var _ converter.Converter = &Converter{}
+
+// UnsupportedValue is an error that indicates that a value can't be represented in TOML.
+type UnsupportedValue struct {
+	Path   string
+	Reason string
+}
+
+func (e *UnsupportedValue) Error() string {
+	return fmt.Sprintf("can't represent %s in TOML: %s", e.Path, e.Reason)
+}
+
+// Decode writes val to w as TOML.
+func Decode(w io.Writer, val *types.Value) error {
+	if val.Kind != types.Object {
+		return &UnsupportedValue{Path: rootPath, Reason: fmt.Sprintf("top-level value must be an Object, but got %#v", val.Kind)}
+	}
+	obj, err := toTOMLObject(val, rootPath)
+	if err != nil {
+		return err
+	}
+	enc := toml.NewEncoder(w)
+	enc.Indent = ""
+	return enc.Encode(obj)
+}
+
+// Encode reads TOML from r and converts it into a Value.
+func Encode(r io.Reader) (*types.Value, error) {
+	var doc map[string]interface{}
+	_, err := toml.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return toValue(doc)
+}
+
+func toValue(any interface{}) (*types.Value, error) {
+	switch any := any.(type) {
+	case int64:
+		return types.NewIntValue(any), nil
+	case float64:
+		return types.NewFloatValue(any), nil
+	case string:
+		return types.NewStringValue([]byte(any)), nil
+	case bool:
+		return types.NewBoolValue(any), nil
+	case time.Time:
+		return types.NewStringValue([]byte(formatDatetime(any))), nil
+	case map[string]interface{}:
+		obj := make(map[string]*types.Value, len(any))
+		for k, elem := range any {
+			v, err := toValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			obj[k] = v
+		}
+		return types.NewObjectValue(obj), nil
+	case []map[string]interface{}:
+		arr := make([]*types.Value, 0, len(any))
+		for _, elem := range any {
+			v, err := toValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return types.NewArrayValue(arr), nil
+	case []interface{}:
+		arr := make([]*types.Value, 0, len(any))
+		for _, elem := range any {
+			v, err := toValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return types.NewArrayValue(arr), nil
+	default:
+		return nil, fmt.Errorf("unexpected TOML value: %#v", any)
+	}
+}
+
+func formatDatetime(t time.Time) string {
+	switch t.Location().String() {
+	case locLocalDatetime:
+		return t.Format(layoutLocalDatetime)
+	case locLocalDate:
+		return t.Format(layoutLocalDate)
+	case locLocalTime:
+		return t.Format(layoutLocalTime)
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
+
+const rootPath = "<root>"
+
+func toTOMLObject(val *types.Value, path string) (interface{}, error) {
+	switch val.Kind {
+	case types.Int:
+		return val.Int(), nil
+	case types.Uint:
+		if val.Uint() > math.MaxInt64 {
+			return nil, &UnsupportedValue{Path: path, Reason: fmt.Sprintf("%d overflows 64-bit signed integer", val.Uint())}
+		}
+		return int64(val.Uint()), nil
+	case types.Float:
+		return val.Float(), nil
+	case types.String:
+		if !utf8.Valid(val.Bytes()) {
+			return nil, &UnsupportedValue{Path: path, Reason: "string is not valid UTF-8"}
+		}
+		return string(val.Bytes()), nil
+	case types.Object:
+		obj := make(map[string]interface{}, len(val.Object()))
+		for k, v := range val.Object() {
+			elem, err := toTOMLObject(v, fmt.Sprintf("%s.%s", path, k))
+			if err != nil {
+				return nil, err
+			}
+			obj[k] = elem
+		}
+		return obj, nil
+	case types.Array:
+		arr := make([]interface{}, 0, len(val.Array()))
+		for i, v := range val.Array() {
+			elem, err := toTOMLObject(v, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, elem)
+		}
+		return arr, nil
+	case types.Bool:
+		return val.Bool(), nil
+	case types.Nil:
+		return nil, &UnsupportedValue{Path: path, Reason: "TOML has no null"}
+	default:
+		panic(fmt.Errorf("invalid kind: %d", val.Kind))
+	}
+}
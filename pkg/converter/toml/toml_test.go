@@ -0,0 +1,122 @@
+package toml
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func TestEncode(t *testing.T) {
+	src := `
+title = "example"
+port = 8080
+ratio = 0.5
+enabled = true
+offset = 1979-05-27T07:32:00Z
+local = 1979-05-27T07:32:00
+date = 1979-05-27
+time = 07:32:00
+
+[owner]
+name = "Tom"
+
+[[servers]]
+ip = "10.0.0.1"
+
+[[servers]]
+ip = "10.0.0.2"
+`
+	got, err := Encode(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	str := func(s string) *types.Value {
+		return types.NewStringValue([]byte(s))
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"title":   str("example"),
+		"port":    types.NewIntValue(8080),
+		"ratio":   types.NewFloatValue(0.5),
+		"enabled": types.NewBoolValue(true),
+		"offset":  str("1979-05-27T07:32:00Z"),
+		"local":   str("1979-05-27T07:32:00"),
+		"date":    str("1979-05-27"),
+		"time":    str("07:32:00"),
+		"owner": types.NewObjectValue(map[string]*types.Value{
+			"name": str("Tom"),
+		}),
+		"servers": types.NewArrayValue([]*types.Value{
+			types.NewObjectValue(map[string]*types.Value{"ip": str("10.0.0.1")}),
+			types.NewObjectValue(map[string]*types.Value{"ip": str("10.0.0.2")}),
+		}),
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	orig := types.NewObjectValue(map[string]*types.Value{
+		"a":   types.NewIntValue(-1),
+		"b":   types.NewFloatValue(1),
+		"c":   types.NewStringValue([]byte("hello")),
+		"d":   types.NewArrayValue([]*types.Value{types.NewIntValue(1), types.NewStringValue([]byte("x"))}),
+		"e":   types.NewObjectValue(map[string]*types.Value{"f": types.NewBoolValue(false)}),
+		"inf": types.NewFloatValue(math.Inf(-1)),
+	})
+	buf := bytes.NewBuffer(nil)
+	err := Decode(buf, orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Encode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(orig, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecodeConvertsSmallUintsIntoIntegers(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := Decode(buf, types.NewObjectValue(map[string]*types.Value{
+		"n": types.NewUintValue(123),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "n = 123\n" {
+		t.Errorf("unexpected output: %#v", got)
+	}
+}
+
+func TestDecodeRejectsUnsupportedValues(t *testing.T) {
+	test := func(v *types.Value, path string) {
+		err := Decode(bytes.NewBuffer(nil), v)
+		var unsupported *UnsupportedValue
+		if !errors.As(err, &unsupported) {
+			t.Fatalf("expected UnsupportedValue but got %#v", err)
+		}
+		if unsupported.Path != path {
+			t.Errorf("expected %#v but got %#v", path, unsupported.Path)
+		}
+	}
+	test(types.NewArrayValue([]*types.Value{}), "<root>")
+	test(types.NewNilValue(), "<root>")
+	test(types.NewObjectValue(map[string]*types.Value{
+		"a": types.NewArrayValue([]*types.Value{types.NewNilValue()}),
+	}), "<root>.a[0]")
+	test(types.NewObjectValue(map[string]*types.Value{
+		"big": types.NewUintValue(math.MaxUint64),
+	}), "<root>.big")
+	test(types.NewObjectValue(map[string]*types.Value{
+		"bin": types.NewStringValue([]byte{0xff}),
+	}), "<root>.bin")
+}
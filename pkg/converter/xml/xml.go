@@ -0,0 +1,472 @@
+// Package xml provides a way to convert XML into types.Value and vice versa.
+//
+// An XML document is converted into an Object that has exactly one key, the name of the root element.
+// Each element is converted by the following rules:
+//   * An element that has neither attributes nor child elements is converted into a String that contains its text,
+//     or into Nil if it is empty (e.g. `<a/>`).
+//   * Any other elements are converted into Objects:
+//     * Each attribute is converted into a key `"@name"`.
+//     * Text is converted into a key `"#text"`. Text that consists only of whitespaces is ignored.
+//     * Each child element is converted into a key that equals to its name.
+//       If an element has two or more children of the same name, they are converted into an Array.
+//     * If an element has children of two or more different names, the names of children in document order are stored in `"#order"`.
+//     * If an element has both text and child elements (mixed content), each run of text between them is stored verbatim
+//       in `"#text"`, which is an Array if there are two or more runs,
+//       and `"#order"` has `"#text"` at the position of each run so that text and children are written back in the same order.
+//       Mixed content is written without indentation so that its text is kept as it is.
+//
+// Names are kept as they are written, including their namespace prefixes (e.g. `"@xmlns:x"`, `"x:item"`),
+// so namespace declarations and prefixes are preserved on round-trips.
+// Attributes are written in the order of their names except that namespace declarations come first.
+// Comments, processing instructions, and directives are dropped.
+//
+// When writing XML, Ints, Uints, Floats, and Bools are written as text.
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/genkami/watson/pkg/converter"
+	"github.com/genkami/watson/pkg/types"
+)
+
+const (
+	attrPrefix = "@"
+	keyText    = "#text"
+	keyOrder   = "#order"
+)
+
+func init() {
+	converter.Register(&Converter{})
+}
+
+// Converter converts XML into types.Value and vice versa.
+type Converter struct{}
+
+func (c *Converter) Name() string {
+	return "xml"
+}
+
+func (c *Converter) Extensions() []string {
+	return []string{".xml"}
+}
+
+// Sniff recognizes documents that start with an XML declaration or an element.
+func (c *Converter) Sniff(head []byte) bool {
+	head = bytes.TrimLeft(head, " \t\r\n")
+	return bytes.HasPrefix(head, []byte("<?xml")) || (len(head) > 1 && head[0] == '<' && isNameStart(head[1]))
+}
+
+func (c *Converter) Encode(r io.Reader) (*types.Value, error) {
+	return Encode(r)
+}
+
+func (c *Converter) Decode(w io.Writer, v *types.Value) error {
+	return Decode(w, v)
+}
+
+var _ converter.Converter = &Converter{}
+
+func isNameStart(b byte) bool {
+	return b == '_' || b == ':' || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || b >= 0x80
+}
+
+// Encode reads XML from r and converts it into a Value.
+func Encode(r io.Reader) (*types.Value, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			return nil, errors.New("no root element")
+		} else if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			v, err := elementToValue(dec, tok)
+			if err != nil {
+				return nil, err
+			}
+			return types.NewObjectValue(map[string]*types.Value{
+				nameOf(tok.Name): v,
+			}), nil
+		case xml.CharData:
+			if len(bytes.TrimSpace(tok)) > 0 {
+				return nil, errors.New("text outside of the root element")
+			}
+		}
+	}
+}
+
+type element struct {
+	attrs map[string]*types.Value
+	text  []byte
+	// runs are the non-empty runs of text between child elements, which are kept verbatim.
+	runs     [][]byte
+	run      []byte
+	children map[string][]*types.Value
+	order    []string
+}
+
+// endRun ends the run of text that precedes a child element or the end tag.
+func (e *element) endRun() {
+	if len(e.run) > 0 {
+		e.runs = append(e.runs, e.run)
+		e.order = append(e.order, keyText)
+	}
+	e.run = nil
+}
+
+func elementToValue(dec *xml.Decoder, start xml.StartElement) (*types.Value, error) {
+	e := &element{
+		attrs:    map[string]*types.Value{},
+		children: map[string][]*types.Value{},
+	}
+	for _, attr := range start.Attr {
+		e.attrs[attrPrefix+nameOf(attr.Name)] = types.NewStringValue([]byte(attr.Value))
+	}
+	for {
+		tok, err := dec.RawToken()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			e.endRun()
+			child, err := elementToValue(dec, tok)
+			if err != nil {
+				return nil, err
+			}
+			name := nameOf(tok.Name)
+			e.children[name] = append(e.children[name], child)
+			e.order = append(e.order, name)
+		case xml.EndElement:
+			if tok.Name != start.Name {
+				return nil, fmt.Errorf("element <%s> closed by </%s>", nameOf(start.Name), nameOf(tok.Name))
+			}
+			e.endRun()
+			return e.toValue(), nil
+		case xml.CharData:
+			e.text = append(e.text, tok...)
+			e.run = append(e.run, tok...)
+		}
+	}
+}
+
+func (e *element) toValue() *types.Value {
+	text := e.text
+	if len(bytes.TrimSpace(text)) == 0 {
+		text = nil
+	}
+	if len(e.attrs) == 0 && len(e.children) == 0 {
+		if text == nil {
+			return types.NewNilValue()
+		}
+		return types.NewStringValue(text)
+	}
+	obj := e.attrs
+	// Whitespaces between child elements are just indentation unless the element also has text.
+	mixed := len(e.children) > 0 && text != nil
+	if mixed {
+		if len(e.runs) == 1 {
+			obj[keyText] = types.NewStringValue(e.runs[0])
+		} else {
+			runs := make([]*types.Value, 0, len(e.runs))
+			for _, run := range e.runs {
+				runs = append(runs, types.NewStringValue(run))
+			}
+			obj[keyText] = types.NewArrayValue(runs)
+		}
+	} else if text != nil {
+		obj[keyText] = types.NewStringValue(text)
+	}
+	for name, children := range e.children {
+		if len(children) == 1 {
+			obj[name] = children[0]
+		} else {
+			obj[name] = types.NewArrayValue(children)
+		}
+	}
+	if len(e.children) > 1 || mixed {
+		order := make([]*types.Value, 0, len(e.order))
+		for _, name := range e.order {
+			if name == keyText && !mixed {
+				continue
+			}
+			order = append(order, types.NewStringValue([]byte(name)))
+		}
+		obj[keyOrder] = types.NewArrayValue(order)
+	}
+	return types.NewObjectValue(obj)
+}
+
+func nameOf(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// Decode writes val to w as XML.
+func Decode(w io.Writer, val *types.Value) error {
+	if val.Kind != types.Object || len(val.Object()) != 1 {
+		return errors.New("top-level value must be an Object that has exactly one key")
+	}
+	enc := xml.NewEncoder(w)
+	for name, v := range val.Object() {
+		if v.Kind == types.Array {
+			return errors.New("root element must not be an Array")
+		}
+		err := writeElement(enc, name, v, 0)
+		if err != nil {
+			return err
+		}
+	}
+	err := enc.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte("\n"))
+	return err
+}
+
+// writeElement writes v as elements named name that are indented by depth levels.
+// Elements are not indented if depth is negative, e.g. inside mixed content, where indentation would change the text.
+func writeElement(enc *xml.Encoder, name string, v *types.Value, depth int) error {
+	if v.Kind == types.Array {
+		for _, elem := range v.Array() {
+			err := writeElement(enc, name, elem, depth)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if depth > 0 {
+		err := indent(enc, depth)
+		if err != nil {
+			return err
+		}
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if v.Kind != types.Object {
+		text, err := textOf(v)
+		if err != nil {
+			return fmt.Errorf("<%s>: %w", name, err)
+		}
+		return writeSimpleElement(enc, start, text)
+	}
+	keys := make([]string, 0, len(v.Object()))
+	for k := range v.Object() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	children := make([]string, 0, len(keys))
+	for _, k := range keys {
+		elem := v.Object()[k]
+		switch {
+		case strings.HasPrefix(k, attrPrefix):
+			text, err := textOf(elem)
+			if err != nil {
+				return fmt.Errorf("<%s %s>: %w", name, k, err)
+			}
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: k[len(attrPrefix):]}, Value: text})
+		case k == keyText || k == keyOrder:
+			// These are written later.
+		default:
+			children = append(children, k)
+		}
+	}
+	// Namespace declarations come first so that they look natural.
+	sort.SliceStable(start.Attr, func(i, j int) bool {
+		return isNamespaceDecl(start.Attr[i].Name.Local) && !isNamespaceDecl(start.Attr[j].Name.Local)
+	})
+	err := enc.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+	order := v.Object()[keyOrder]
+	t, hasText := v.Object()[keyText]
+	childDepth := depth + 1
+	if depth < 0 || (hasText && len(children) > 0) {
+		childDepth = -1
+	}
+	if hasText && !ordered(order, keyText) {
+		// Text that is not in `"#order"` comes first.
+		err = writeText(enc, name, t)
+		if err != nil {
+			return err
+		}
+	}
+	err = writeChildren(enc, name, v.Object(), children, order, childDepth)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 && childDepth > 0 {
+		err = indent(enc, depth)
+		if err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// indent starts a new line that is indented by depth levels.
+func indent(enc *xml.Encoder, depth int) error {
+	return enc.EncodeToken(xml.CharData("\n" + strings.Repeat("  ", depth)))
+}
+
+// ordered reports whether order contains name.
+func ordered(order *types.Value, name string) bool {
+	if order == nil || order.Kind != types.Array {
+		return false
+	}
+	for _, o := range order.Array() {
+		if o.Kind == types.String && string(o.Bytes()) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// writeText writes t, which is either a scalar or an Array of runs of text.
+func writeText(enc *xml.Encoder, name string, t *types.Value) error {
+	runs := []*types.Value{t}
+	if t.Kind == types.Array {
+		runs = t.Array()
+	}
+	for _, run := range runs {
+		text, err := textOf(run)
+		if err != nil {
+			return fmt.Errorf("<%s>: %w", name, err)
+		}
+		err = enc.EncodeToken(xml.CharData(text))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSimpleElement(enc *xml.Encoder, start xml.StartElement, text string) error {
+	err := enc.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+	if text != "" {
+		err = enc.EncodeToken(xml.CharData(text))
+		if err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// writeChildren writes children and runs of text in the order specified by `"#order"`,
+// then writes the rest of the text and the rest of children in the order of their names.
+func writeChildren(enc *xml.Encoder, parent string, obj map[string]*types.Value, names []string, order *types.Value, depth int) error {
+	written := map[string]int{}
+	if order != nil && order.Kind == types.Array {
+		for _, o := range order.Array() {
+			if o.Kind != types.String {
+				continue
+			}
+			name := string(o.Bytes())
+			child, ok := obj[name]
+			if !ok || name == keyOrder || strings.HasPrefix(name, attrPrefix) {
+				continue
+			}
+			i := written[name]
+			if child.Kind == types.Array {
+				if i >= len(child.Array()) {
+					continue
+				}
+				child = child.Array()[i]
+			} else if i > 0 {
+				continue
+			}
+			var err error
+			if name == keyText {
+				err = writeText(enc, parent, child)
+			} else {
+				err = writeElement(enc, name, child, depth)
+			}
+			if err != nil {
+				return err
+			}
+			written[name] = i + 1
+		}
+	}
+	if t, ok := obj[keyText]; ok && ordered(order, keyText) {
+		i := written[keyText]
+		if t.Kind == types.Array {
+			err := writeText(enc, parent, types.NewArrayValue(t.Array()[minInt(i, len(t.Array())):]))
+			if err != nil {
+				return err
+			}
+		} else if i == 0 {
+			err := writeText(enc, parent, t)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range names {
+		child := obj[name]
+		i := written[name]
+		if child.Kind == types.Array {
+			child = types.NewArrayValue(child.Array()[minInt(i, len(child.Array())):])
+		} else if i > 0 {
+			continue
+		}
+		err := writeElement(enc, name, child, depth)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isNamespaceDecl(name string) bool {
+	return name == "xmlns" || strings.HasPrefix(name, "xmlns:")
+}
+
+func textOf(v *types.Value) (string, error) {
+	switch v.Kind {
+	case types.Int:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case types.Uint:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case types.Float:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	case types.String:
+		if !utf8.Valid(v.Bytes()) {
+			return "", errors.New("string is not valid UTF-8")
+		}
+		return string(v.Bytes()), nil
+	case types.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case types.Nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("%#v can't be written as text", v.Kind)
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
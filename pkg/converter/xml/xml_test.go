@@ -0,0 +1,168 @@
+package xml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func TestEncode(t *testing.T) {
+	src := `<?xml version="1.0"?>
+<config version="2">
+  <name>app</name>
+  <server port="80"/>
+  <server port="443">secure</server>
+  <empty/>
+</config>
+`
+	got, err := Encode(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	str := func(s string) *types.Value {
+		return types.NewStringValue([]byte(s))
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"config": types.NewObjectValue(map[string]*types.Value{
+			"@version": str("2"),
+			"name":     str("app"),
+			"server": types.NewArrayValue([]*types.Value{
+				types.NewObjectValue(map[string]*types.Value{"@port": str("80")}),
+				types.NewObjectValue(map[string]*types.Value{"@port": str("443"), "#text": str("secure")}),
+			}),
+			"empty": types.NewNilValue(),
+			"#order": types.NewArrayValue([]*types.Value{
+				str("name"), str("server"), str("server"), str("empty"),
+			}),
+		}),
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRoundTripKeepsOrderAndNamespaces(t *testing.T) {
+	src := `<x:root xmlns="urn:default" xmlns:x="urn:x" x:id="1">
+  <b>1</b>
+  <a>2</a>
+  <x:b>3</x:b>
+  <b>4</b>
+  <c attr="value"></c>
+</x:root>
+`
+	v, err := Encode(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = Decode(buf, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != src {
+		t.Errorf("expected:\n%s\nbut got:\n%s", src, got)
+	}
+}
+
+func TestMixedContentKeepsOrder(t *testing.T) {
+	v, err := Encode(strings.NewReader(`<p>Hello <b>world</b>, and <i>you</i>!</p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	str := func(s string) *types.Value {
+		return types.NewStringValue([]byte(s))
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"p": types.NewObjectValue(map[string]*types.Value{
+			"#text": types.NewArrayValue([]*types.Value{str("Hello "), str(", and "), str("!")}),
+			"b":     str("world"),
+			"i":     str("you"),
+			"#order": types.NewArrayValue([]*types.Value{
+				str("#text"), str("b"), str("#text"), str("i"), str("#text"),
+			}),
+		}),
+	})
+	if diff := cmp.Diff(want, v); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = Decode(buf, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Encode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMixedContentRoundTrips(t *testing.T) {
+	src := `<doc>
+  <p>Hello <b>world</b>, and <i>you <em>all</em></i>!</p>
+  <p>
+    <b>bold</b> first
+  </p>
+  <note>plain</note>
+</doc>
+`
+	v, err := Encode(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = Decode(buf, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != src {
+		t.Errorf("expected:\n%s\nbut got:\n%s", src, got)
+	}
+}
+
+func TestDecodeWritesScalarsAsText(t *testing.T) {
+	v := types.NewObjectValue(map[string]*types.Value{
+		"root": types.NewObjectValue(map[string]*types.Value{
+			"@enabled": types.NewBoolValue(true),
+			"count":    types.NewIntValue(-3),
+			"ratio":    types.NewFloatValue(0.5),
+		}),
+	})
+	buf := bytes.NewBuffer(nil)
+	err := Decode(buf, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<root enabled="true">
+  <count>-3</count>
+  <ratio>0.5</ratio>
+</root>
+`
+	if got := buf.String(); got != want {
+		t.Errorf("expected:\n%s\nbut got:\n%s", want, got)
+	}
+}
+
+func TestDecodeRejectsInvalidRoots(t *testing.T) {
+	test := func(v *types.Value) {
+		err := Decode(bytes.NewBuffer(nil), v)
+		if err == nil {
+			t.Errorf("expected error but got nil")
+		}
+	}
+	test(types.NewIntValue(1))
+	test(types.NewObjectValue(map[string]*types.Value{}))
+	test(types.NewObjectValue(map[string]*types.Value{
+		"a": types.NewIntValue(1),
+		"b": types.NewIntValue(2),
+	}))
+	test(types.NewObjectValue(map[string]*types.Value{
+		"a": types.NewArrayValue([]*types.Value{}),
+	}))
+}
@@ -1,53 +1,523 @@
 // Package yaml provides a way to convert YAML into types.Value and vice versa.
+//
+// The conversion is done on YAML nodes rather than on Go objects, so the following features are preserved:
+//   * Anchors and aliases are converted into subtrees that share the same *types.Value, and vice versa.
+//   * Merge keys (`<<`) are expanded.
+//   * `!!binary` scalars are converted into Strings. Strings that are not valid UTF-8 are written as `!!binary`.
+//   * Nodes with custom tags (e.g. `!Ref foo`) are converted into `{"$tag": "!Ref", "$value": "foo"}`, and vice versa.
+//   * Comments are optionally kept in `"$comments"` (see WithComments).
+//
+// A stream that consists of exactly one document is converted into the value of the document.
+// Any other streams are converted into `{"$documents": [...]}` so that they can be told apart from a document that contains a sequence.
 package yaml
 
 import (
+	"bytes"
+	"encoding/base64"
 	"errors"
+	"flag"
+	"fmt"
 	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 
+	"github.com/genkami/watson/pkg/converter"
 	"github.com/genkami/watson/pkg/types"
 )
 
-func Decode(w io.Writer, val *types.Value) error {
-	obj := val.ToGoObject()
+const (
+	keyDocuments = "$documents"
+	keyTag       = "$tag"
+	keyValue     = "$value"
+	keyComments  = "$comments"
+
+	tagStr       = "!!str"
+	tagInt       = "!!int"
+	tagFloat     = "!!float"
+	tagBool      = "!!bool"
+	tagNull      = "!!null"
+	tagMap       = "!!map"
+	tagSeq       = "!!seq"
+	tagBinary    = "!!binary"
+	tagTimestamp = "!!timestamp"
+	tagMerge     = "!!merge"
+)
+
+func init() {
+	converter.Register(&Converter{})
+}
+
+// Converter converts YAML into types.Value and vice versa.
+type Converter struct {
+	// Comments enables WithComments.
+	Comments bool
+}
+
+func (c *Converter) Name() string {
+	return "yaml"
+}
+
+func (c *Converter) Extensions() []string {
+	return []string{".yaml", ".yml"}
+}
+
+// Sniff recognizes documents that start with a directive or a document marker.
+func (c *Converter) Sniff(head []byte) bool {
+	return bytes.HasPrefix(head, []byte("%YAML")) || bytes.HasPrefix(head, []byte("---"))
+}
+
+func (c *Converter) RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.Comments, "yaml-comments", false, "keep YAML comments in \"$comments\"")
+}
+
+func (c *Converter) Encode(r io.Reader) (*types.Value, error) {
+	return Encode(r, c.options()...)
+}
+
+func (c *Converter) Decode(w io.Writer, v *types.Value) error {
+	return Decode(w, v, c.options()...)
+}
+
+func (c *Converter) options() []Option {
+	if c.Comments {
+		return []Option{WithComments()}
+	}
+	return nil
+}
+
+var _ converter.Converter = &Converter{}
+var _ converter.FlagRegisterer = &Converter{}
+
+// Option configures Encode and Decode.
+type Option interface {
+	apply(*config)
+}
+
+type option func(*config)
+
+func (opt option) apply(c *config) {
+	opt(c)
+}
+
+// WithComments keeps comments of mappings.
+//
+// When reading YAML, comments attached to each key of a mapping are stored in `"$comments"` of the corresponding Object,
+// e.g. `{"$comments": {"replicas": "# number of pods"}, "replicas": 3}`.
+// When writing YAML, `"$comments"` is written back as comments of the keys.
+func WithComments() Option {
+	return option(func(c *config) {
+		c.comments = true
+	})
+}
+
+type config struct {
+	comments bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	return c
+}
+
+// Decode writes val to w as YAML.
+func Decode(w io.Writer, val *types.Value, opts ...Option) error {
+	c := newConfig(opts)
 	enc := yaml.NewEncoder(w)
-	defer enc.Close()
-	arr, ok := obj.([]interface{})
-	if !ok {
-		return enc.Encode(obj)
+	enc.SetIndent(2)
+	docs := []*types.Value{val}
+	if arr, ok := documents(val); ok {
+		docs = arr
 	}
-	for _, v := range arr {
-		err := enc.Encode(v)
+	e := newNodeEncoder(c, docs)
+	for _, doc := range docs {
+		node, err := e.toNode(doc)
+		if err != nil {
+			return err
+		}
+		err = enc.Encode(node)
 		if err != nil {
 			return err
 		}
 	}
-	return nil
+	return enc.Close()
 }
 
-func Encode(r io.Reader) (*types.Value, error) {
-	var any interface{}
+// Encode reads a YAML stream from r and converts it into a Value.
+func Encode(r io.Reader, opts ...Option) (*types.Value, error) {
+	c := newConfig(opts)
 	dec := yaml.NewDecoder(r)
-	results := make([]*types.Value, 0)
+	d := &nodeDecoder{c: c, values: map[*yaml.Node]*types.Value{}, visiting: map[*yaml.Node]bool{}}
+	docs := make([]*types.Value, 0, 1)
 	for {
-		err := dec.Decode(&any)
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		v, err := d.toValue(&node)
 		if err != nil {
-			if errors.Is(err, io.EOF) && len(results) > 0 {
-				break
+			return nil, err
+		}
+		docs = append(docs, v)
+	}
+	if len(docs) == 1 {
+		if _, ok := documents(docs[0]); !ok {
+			return docs[0], nil
+		}
+	}
+	return types.NewObjectValue(map[string]*types.Value{
+		keyDocuments: types.NewArrayValue(docs),
+	}), nil
+}
+
+func documents(v *types.Value) ([]*types.Value, bool) {
+	if v.Kind != types.Object || len(v.Object()) != 1 {
+		return nil, false
+	}
+	docs, ok := v.Object()[keyDocuments]
+	if !ok || docs.Kind != types.Array {
+		return nil, false
+	}
+	return docs.Array(), true
+}
+
+type nodeDecoder struct {
+	c      *config
+	values map[*yaml.Node]*types.Value
+	// visiting holds the nodes that are being converted, so that an alias that refers to its ancestor can be detected.
+	visiting map[*yaml.Node]bool
+}
+
+func (d *nodeDecoder) toValue(n *yaml.Node) (*types.Value, error) {
+	if v, ok := d.values[n]; ok {
+		return v, nil
+	}
+	if d.visiting[n] {
+		return nil, fmt.Errorf("line %d: anchor %q refers to itself", n.Line, n.Anchor)
+	}
+	d.visiting[n] = true
+	defer delete(d.visiting, n)
+	var v *types.Value
+	var err error
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return types.NewNilValue(), nil
+		}
+		return d.toValue(n.Content[0])
+	case yaml.AliasNode:
+		return d.toValue(n.Alias)
+	case yaml.ScalarNode:
+		v, err = d.scalarToValue(n)
+	case yaml.MappingNode:
+		v, err = d.mappingToValue(n)
+	case yaml.SequenceNode:
+		v, err = d.sequenceToValue(n)
+	default:
+		return nil, fmt.Errorf("line %d: unknown node kind: %d", n.Line, n.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if isCustomTag(n.Tag) {
+		v = types.NewObjectValue(map[string]*types.Value{
+			keyTag:   types.NewStringValue([]byte(n.Tag)),
+			keyValue: v,
+		})
+	}
+	d.values[n] = v
+	return v, nil
+}
+
+func (d *nodeDecoder) scalarToValue(n *yaml.Node) (*types.Value, error) {
+	switch n.ShortTag() {
+	case tagNull:
+		return types.NewNilValue(), nil
+	case tagBool, tagInt, tagFloat:
+		var any interface{}
+		err := n.Decode(&any)
+		if err != nil {
+			return nil, err
+		}
+		return types.ToValue(any)
+	case tagBinary:
+		b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(n.Value), ""))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n.Line, err)
+		}
+		return types.NewStringValue(b), nil
+	default:
+		// Strings, timestamps, and scalars with custom tags are kept as they are written.
+		return types.NewStringValue([]byte(n.Value)), nil
+	}
+}
+
+func (d *nodeDecoder) mappingToValue(n *yaml.Node) (*types.Value, error) {
+	obj := map[string]*types.Value{}
+	comments := map[string]*types.Value{}
+	merged := make([]*yaml.Node, 0)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.Kind == yaml.ScalarNode && k.ShortTag() == tagMerge {
+			merged = append(merged, v)
+			continue
+		}
+		key, err := d.key(k)
+		if err != nil {
+			return nil, err
+		}
+		val, err := d.toValue(v)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = val
+		if comment := joinComments(k.HeadComment, k.LineComment, v.LineComment); comment != "" {
+			comments[key] = types.NewStringValue([]byte(comment))
+		}
+	}
+	for _, m := range merged {
+		err := d.merge(obj, m)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if d.c.comments && len(comments) > 0 {
+		obj[keyComments] = types.NewObjectValue(comments)
+	}
+	return types.NewObjectValue(obj), nil
+}
+
+// merge adds entries of mappings referred by the merge key to obj unless obj already has the same keys.
+func (d *nodeDecoder) merge(obj map[string]*types.Value, n *yaml.Node) error {
+	sources := []*yaml.Node{n}
+	if n.Kind == yaml.SequenceNode {
+		sources = n.Content
+	}
+	for _, src := range sources {
+		v, err := d.toValue(src)
+		if err != nil {
+			return err
+		}
+		if v.Kind != types.Object {
+			return fmt.Errorf("line %d: merge key must refer to mappings", src.Line)
+		}
+		for k, elem := range v.Object() {
+			if k == keyComments {
+				continue
 			}
+			if _, ok := obj[k]; !ok {
+				obj[k] = elem
+			}
+		}
+	}
+	return nil
+}
+
+func (d *nodeDecoder) key(n *yaml.Node) (string, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind != yaml.ScalarNode {
+		return "", fmt.Errorf("line %d: keys must be scalars", n.Line)
+	}
+	return n.Value, nil
+}
+
+func (d *nodeDecoder) sequenceToValue(n *yaml.Node) (*types.Value, error) {
+	arr := make([]*types.Value, 0, len(n.Content))
+	for _, elem := range n.Content {
+		v, err := d.toValue(elem)
+		if err != nil {
 			return nil, err
 		}
-		v, err := types.ToValue(any)
+		arr = append(arr, v)
+	}
+	return types.NewArrayValue(arr), nil
+}
+
+type nodeEncoder struct {
+	c       *config
+	counts  map[*types.Value]int
+	anchors map[*types.Value]*yaml.Node
+}
+
+func newNodeEncoder(c *config, docs []*types.Value) *nodeEncoder {
+	e := &nodeEncoder{
+		c:       c,
+		counts:  map[*types.Value]int{},
+		anchors: map[*types.Value]*yaml.Node{},
+	}
+	for _, doc := range docs {
+		e.count(doc)
+	}
+	return e
+}
+
+// count counts how many times each Object and Array is referred to find shared subtrees.
+func (e *nodeEncoder) count(v *types.Value) {
+	if v.Kind != types.Object && v.Kind != types.Array {
+		return
+	}
+	e.counts[v]++
+	if e.counts[v] > 1 {
+		return
+	}
+	for _, elem := range v.Object() {
+		e.count(elem)
+	}
+	for _, elem := range v.Array() {
+		e.count(elem)
+	}
+}
+
+func (e *nodeEncoder) toNode(v *types.Value) (*yaml.Node, error) {
+	if n, ok := e.anchors[v]; ok {
+		return &yaml.Node{Kind: yaml.AliasNode, Alias: n, Value: n.Anchor}, nil
+	}
+	n, err := e.newNode(v)
+	if err != nil {
+		return nil, err
+	}
+	if e.counts[v] > 1 {
+		n.Anchor = fmt.Sprintf("anchor%d", len(e.anchors)+1)
+		e.anchors[v] = n
+	}
+	return n, nil
+}
+
+func (e *nodeEncoder) newNode(v *types.Value) (*yaml.Node, error) {
+	switch v.Kind {
+	case types.Int:
+		return scalar(tagInt, strconv.FormatInt(v.Int(), 10)), nil
+	case types.Uint:
+		return scalar(tagInt, strconv.FormatUint(v.Uint(), 10)), nil
+	case types.Float:
+		return scalar(tagFloat, formatFloat(v.Float())), nil
+	case types.String:
+		if !utf8.Valid(v.Bytes()) {
+			return scalar(tagBinary, base64.StdEncoding.EncodeToString(v.Bytes())), nil
+		}
+		return scalar(tagStr, string(v.Bytes())), nil
+	case types.Object:
+		if tag, val, ok := tagged(v); ok {
+			n, err := e.toNode(val)
+			if err != nil {
+				return nil, err
+			}
+			n.Tag = tag
+			return n, nil
+		}
+		return e.mappingNode(v.Object())
+	case types.Array:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: tagSeq}
+		for _, elem := range v.Array() {
+			en, err := e.toNode(elem)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, en)
+		}
+		return n, nil
+	case types.Bool:
+		return scalar(tagBool, strconv.FormatBool(v.Bool())), nil
+	case types.Nil:
+		return scalar(tagNull, "null"), nil
+	default:
+		panic(fmt.Errorf("invalid kind: %d", v.Kind))
+	}
+}
+
+func (e *nodeEncoder) mappingNode(obj map[string]*types.Value) (*yaml.Node, error) {
+	var comments map[string]*types.Value
+	if c, ok := obj[keyComments]; e.c.comments && ok && c.Kind == types.Object {
+		comments = c.Object()
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		if comments != nil && k == keyComments {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	n := &yaml.Node{Kind: yaml.MappingNode, Tag: tagMap}
+	for _, k := range keys {
+		kn := scalar(tagStr, k)
+		if c, ok := comments[k]; ok && c.Kind == types.String {
+			kn.HeadComment = string(c.Bytes())
+		}
+		vn, err := e.toNode(obj[k])
 		if err != nil {
 			return nil, err
 		}
-		results = append(results, v)
+		n.Content = append(n.Content, kn, vn)
 	}
-	if len(results) == 1 {
-		return results[0], nil
-	} else {
-		return types.NewArrayValue(results), nil
+	return n, nil
+}
+
+func tagged(v *types.Value) (string, *types.Value, bool) {
+	if len(v.Object()) != 2 {
+		return "", nil, false
+	}
+	tag, ok := v.Object()[keyTag]
+	if !ok || tag.Kind != types.String {
+		return "", nil, false
+	}
+	val, ok := v.Object()[keyValue]
+	if !ok {
+		return "", nil, false
+	}
+	return string(tag.Bytes()), val, true
+}
+
+func scalar(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+func formatFloat(x float64) string {
+	switch {
+	case math.IsNaN(x):
+		return ".nan"
+	case math.IsInf(x, 1):
+		return ".inf"
+	case math.IsInf(x, -1):
+		return "-.inf"
+	}
+	s := strconv.FormatFloat(x, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return s
+}
+
+func isCustomTag(tag string) bool {
+	if tag == "" || tag == "!" {
+		return false
+	}
+	switch tag {
+	case tagStr, tagInt, tagFloat, tagBool, tagNull, tagMap, tagSeq, tagBinary, tagTimestamp, tagMerge:
+		return false
+	default:
+		return true
+	}
+}
+
+func joinComments(comments ...string) string {
+	nonEmpty := make([]string, 0, len(comments))
+	for _, c := range comments {
+		if c != "" {
+			nonEmpty = append(nonEmpty, c)
+		}
 	}
+	return strings.Join(nonEmpty, "\n")
 }
@@ -0,0 +1,211 @@
+package yaml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func TestEncodeSingleDocument(t *testing.T) {
+	got, err := Encode(strings.NewReader("- 1\n- 2.5\n- hello\n- true\n- null\n- 18446744073709551615\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewArrayValue([]*types.Value{
+		types.NewIntValue(1),
+		types.NewFloatValue(2.5),
+		types.NewStringValue([]byte("hello")),
+		types.NewBoolValue(true),
+		types.NewNilValue(),
+		types.NewUintValue(18446744073709551615),
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncodeMultipleDocuments(t *testing.T) {
+	got, err := Encode(strings.NewReader("a: 1\n---\nb: 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"$documents": types.NewArrayValue([]*types.Value{
+			types.NewObjectValue(map[string]*types.Value{"a": types.NewIntValue(1)}),
+			types.NewObjectValue(map[string]*types.Value{"b": types.NewIntValue(2)}),
+		}),
+	})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSingleArrayAndMultipleDocumentsRoundTrip(t *testing.T) {
+	test := func(src string) {
+		v, err := Encode(strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := bytes.NewBuffer(nil)
+		err = Decode(buf, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != src {
+			t.Errorf("expected %#v but got %#v", src, got)
+		}
+	}
+	test("- a: 1\n- b: 2\n")
+	test("a: 1\n---\nb: 2\n")
+	test("kind: Deployment\nspec:\n  replicas: 3\n---\nkind: Service\nspec:\n  ports:\n    - port: 80\n")
+}
+
+func TestEncodeSharesAnchoredSubtrees(t *testing.T) {
+	got, err := Encode(strings.NewReader("base: &base\n  x: 1\ncopy: *base\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Object()["base"] != got.Object()["copy"] {
+		t.Errorf("expected aliases to share the same value")
+	}
+}
+
+func TestEncodeRejectsRecursiveAliases(t *testing.T) {
+	for _, src := range []string{"a: &x\n  b: *x\n", "- &x [*x]\n", "a: &x\n  <<: *x\n"} {
+		if _, err := Encode(strings.NewReader(src)); err == nil {
+			t.Errorf("%q: expected an error but got nil", src)
+		}
+	}
+}
+
+func TestDecodeWritesSharedSubtreesAsAliases(t *testing.T) {
+	shared := types.NewObjectValue(map[string]*types.Value{"x": types.NewIntValue(1)})
+	v := types.NewObjectValue(map[string]*types.Value{
+		"a": shared,
+		"b": shared,
+	})
+	buf := bytes.NewBuffer(nil)
+	err := Decode(buf, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a: &anchor1\n  x: 1\nb: *anchor1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %#v but got %#v", want, got)
+	}
+}
+
+func TestEncodeExpandsMergeKeys(t *testing.T) {
+	got, err := Encode(strings.NewReader("base: &base\n  x: 1\n  y: 2\nderived:\n  <<: *base\n  y: 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"x": types.NewIntValue(1),
+		"y": types.NewIntValue(3),
+	})
+	if diff := cmp.Diff(want, got.Object()["derived"]); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBinaryRoundTrips(t *testing.T) {
+	got, err := Encode(strings.NewReader("!!binary //79\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewStringValue([]byte{0xff, 0xfe, 0xfd})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = Decode(buf, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); s != "!!binary //79\n" {
+		t.Errorf("unexpected output: %#v", s)
+	}
+}
+
+func TestCustomTagsRoundTrip(t *testing.T) {
+	src := "bucket: !Ref MyBucket\nitems: !Sorted\n  - 1\n"
+	got, err := Encode(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"$tag":   types.NewStringValue([]byte("!Ref")),
+		"$value": types.NewStringValue([]byte("MyBucket")),
+	})
+	if diff := cmp.Diff(want, got.Object()["bucket"]); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = Decode(buf, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); s != src {
+		t.Errorf("expected %#v but got %#v", src, s)
+	}
+}
+
+func TestDecodeQuotesAmbiguousStrings(t *testing.T) {
+	v := types.NewArrayValue([]*types.Value{
+		types.NewStringValue([]byte("true")),
+		types.NewStringValue([]byte("123")),
+		types.NewFloatValue(1),
+	})
+	buf := bytes.NewBuffer(nil)
+	err := Decode(buf, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Encode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(v, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCommentsRoundTrip(t *testing.T) {
+	src := "# number of pods\nreplicas: 3\n"
+	v, err := Encode(strings.NewReader(src), WithComments())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"replicas": types.NewIntValue(3),
+		"$comments": types.NewObjectValue(map[string]*types.Value{
+			"replicas": types.NewStringValue([]byte("# number of pods")),
+		}),
+	})
+	if diff := cmp.Diff(want, v); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = Decode(buf, v, WithComments())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != src {
+		t.Errorf("expected %#v but got %#v", src, got)
+	}
+}
+
+func TestCommentsAreDroppedByDefault(t *testing.T) {
+	v, err := Encode(strings.NewReader("# comment\na: 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.Object()["$comments"]; ok {
+		t.Errorf("expected comments to be dropped")
+	}
+}
@@ -25,19 +25,19 @@ func NewDumper(w lexer.OpWriter) *Dumper {
 func (d *Dumper) Dump(v *types.Value) error {
 	switch v.Kind {
 	case types.Int:
-		return d.dumpInt(uint64(v.Int))
+		return d.dumpInt(uint64(v.Int()))
 	case types.Uint:
-		return d.dumpUint(v.Uint)
+		return d.dumpUint(v.Uint())
 	case types.Float:
-		return d.dumpFloat(v.Float)
+		return d.dumpFloat(v.Float())
 	case types.String:
-		return d.dumpString(v.String)
+		return d.dumpString(v.Bytes())
 	case types.Object:
-		return d.dumpObject(v.Object)
+		return d.dumpObject(v.Object())
 	case types.Array:
-		return d.dumpArray(v.Array)
+		return d.dumpArray(v.Array())
 	case types.Bool:
-		return d.dumpBool(v.Bool)
+		return d.dumpBool(v.Bool())
 	case types.Nil:
 		return d.dumpNil()
 	default:
@@ -0,0 +1,289 @@
+// Package edit modifies values in Watson Representation without re-encoding the whole input.
+//
+// Set and Delete only rewrite the bytes that build the target value (or the entry that adds it to its parent),
+// and leave all the other bytes, including decorations written by the prettifier, untouched.
+// The new Ops are written in the mode that is active at the position of the edit,
+// so that the rest of the input is lexed in the same way as before.
+package edit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/genkami/watson/pkg/dumper"
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/sourcemap"
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+// ErrNotFound is returned when the value at the given path doesn't exist.
+var ErrNotFound = errors.New("no such value")
+
+// ErrNotEditable is returned when the value at the given path is not built by a contiguous range of Ops,
+// e.g. when it is shared with other values via Gdup, so that it can't be edited in place.
+var ErrNotEditable = errors.New("the value can't be edited in place")
+
+// Option configures Set and Delete.
+type Option interface {
+	apply(*config)
+}
+
+type config struct {
+	mode      lexer.Mode
+	stackSize int
+}
+
+type option func(*config)
+
+func (opt option) apply(c *config) {
+	opt(c)
+}
+
+// WithInitialMode sets the initial mode of the lexer that reads the input. The default is lexer.A.
+func WithInitialMode(mode lexer.Mode) Option {
+	return option(func(c *config) {
+		c.mode = mode
+	})
+}
+
+// WithStackSize sets the stack size of the VM that executes the input. The default is vm.DefaultStackSize.
+func WithStackSize(size int) Option {
+	return option(func(c *config) {
+		c.stackSize = size
+	})
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{mode: lexer.A, stackSize: vm.DefaultStackSize}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	return c
+}
+
+// Set replaces the value at path in src with v, and returns the modified Watson Representation.
+// path is written in the same form as the ones in error messages, e.g. "<root>.servers[1].port"; the leading "<root>" can be omitted.
+//
+// If the parent of path is an Object that doesn't have the key, the key is added to the Object.
+// Otherwise it returns ErrNotFound if the value doesn't exist.
+func Set(src []byte, path string, v *types.Value, opts ...Option) ([]byte, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	c := newConfig(opts)
+	doc, err := parse(src, c)
+	if err != nil {
+		return nil, err
+	}
+	if len(segs) == 0 {
+		out, err := doc.replace(doc.sm.Lookup, "<root>", v)
+		if err != nil {
+			return nil, err
+		}
+		if err := doc.verify(out, v, c); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+	want := doc.value.DeepCopy()
+	parent, err := lookup(want, segs[:len(segs)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := segs[len(segs)-1]
+	var out []byte
+	if _, exists := child(parent, last); exists {
+		out, err = doc.replace(doc.sm.Lookup, formatPath(segs), v)
+	} else if parent.Kind == types.Object && !last.isIndex {
+		out, err = doc.insert(formatPath(segs[:len(segs)-1]), last.key, v)
+	} else {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, formatPath(segs))
+	}
+	if err != nil {
+		return nil, err
+	}
+	setChild(parent, last, v)
+	if err := doc.verify(out, want, c); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Delete removes the value at path from its parent in src, and returns the modified Watson Representation.
+// The elements of an Array that follow the deleted one are shifted.
+// It returns ErrNotFound if the value doesn't exist. The root can't be deleted.
+func Delete(src []byte, path string, opts ...Option) ([]byte, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(segs) == 0 {
+		return nil, errors.New("can't delete the root")
+	}
+	c := newConfig(opts)
+	doc, err := parse(src, c)
+	if err != nil {
+		return nil, err
+	}
+	want := doc.value.DeepCopy()
+	parent, err := lookup(want, segs[:len(segs)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := segs[len(segs)-1]
+	if _, exists := child(parent, last); !exists {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, formatPath(segs))
+	}
+	out, err := doc.replace(doc.sm.LookupEntry, formatPath(segs), nil)
+	if err != nil {
+		return nil, err
+	}
+	deleteChild(parent, last)
+	if err := doc.verify(out, want, c); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// document is a parsed Watson Representation.
+type document struct {
+	src   []byte
+	value *types.Value
+	sm    *sourcemap.SourceMap
+	// lineStarts[i] is the offset of the i-th line in src.
+	lineStarts []int
+	// before and after are the modes of the lexer right before and after the token at each position.
+	before map[sourcemap.Position]lexer.Mode
+	after  map[sourcemap.Position]lexer.Mode
+}
+
+func parse(src []byte, c *config) (*document, error) {
+	doc := &document{
+		src:        src,
+		lineStarts: []int{0},
+		before:     map[sourcemap.Position]lexer.Mode{},
+		after:      map[sourcemap.Position]lexer.Mode{},
+	}
+	for i, b := range src {
+		if b == '\n' {
+			doc.lineStarts = append(doc.lineStarts, i+1)
+		}
+	}
+	rec := sourcemap.NewRecorder()
+	m := vm.NewVM(vm.WithStackSize(c.stackSize), vm.WithTracer(rec))
+	lex := lexer.NewLexer(bytes.NewReader(src), lexer.WithInitialLexerMode(c.mode))
+	mode := c.mode
+	for {
+		tok, err := lex.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		p := sourcemap.Position{Line: tok.Line, Column: tok.Column}
+		doc.before[p] = mode
+		mode = lexer.NextMode(mode, tok.Op)
+		doc.after[p] = mode
+		if err := rec.Feed(m, tok); err != nil {
+			return nil, err
+		}
+	}
+	v, err := m.Top()
+	if err != nil {
+		return nil, err
+	}
+	sm, err := rec.SourceMap()
+	if err != nil {
+		return nil, err
+	}
+	doc.value = v
+	doc.sm = sm
+	return doc, nil
+}
+
+func (doc *document) offset(p sourcemap.Position) int {
+	return doc.lineStarts[p.Line] + p.Column
+}
+
+// replace replaces the Span of path, which is found by lookup, with the Ops of v. The Span is just removed if v is nil.
+func (doc *document) replace(lookup func(string) (sourcemap.Span, bool), path string, v *types.Value) ([]byte, error) {
+	span, ok := lookup(path)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	return doc.splice(doc.offset(span.Start), doc.offset(span.End)+1, doc.before[span.Start], doc.after[span.End], func(u *lexer.Unlexer) error {
+		if v == nil {
+			return nil
+		}
+		return dumper.NewDumper(u).Dump(v)
+	})
+}
+
+// insert adds key and v to the Object at path right after the last Op that builds it.
+func (doc *document) insert(path, key string, v *types.Value) ([]byte, error) {
+	span, ok := doc.sm.Lookup(path)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	at := doc.offset(span.End) + 1
+	mode := doc.after[span.End]
+	return doc.splice(at, at, mode, mode, func(u *lexer.Unlexer) error {
+		d := dumper.NewDumper(u)
+		if err := d.Dump(types.NewStringValue([]byte(key))); err != nil {
+			return err
+		}
+		if err := d.Dump(v); err != nil {
+			return err
+		}
+		return u.Write(vm.Oadd)
+	})
+}
+
+// splice replaces src[from:to] with the Ops written by write, which start in mode start.
+// If the mode after them differs from end, which is the mode of the lexer at src[to], it appends Ops that do nothing but flip the mode.
+func (doc *document) splice(from, to int, start, end lexer.Mode, write func(u *lexer.Unlexer) error) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.Write(doc.src[:from])
+	u := lexer.NewUnlexer(buf, lexer.WithInitialUnlexerMode(start))
+	if err := write(u); err != nil {
+		return nil, err
+	}
+	if u.Mode() != end {
+		// Snew flips the mode, and the empty string is discarded right after that.
+		if err := u.Write(vm.Snew); err != nil {
+			return nil, err
+		}
+		if err := u.Write(vm.Gpop); err != nil {
+			return nil, err
+		}
+	}
+	buf.Write(doc.src[to:])
+	return buf.Bytes(), nil
+}
+
+// verify checks that out is decoded into want, which ensures that the edit didn't affect other values.
+func (doc *document) verify(out []byte, want *types.Value, c *config) error {
+	m := vm.NewVM(vm.WithStackSize(c.stackSize))
+	lex := lexer.NewLexer(bytes.NewReader(out), lexer.WithInitialLexerMode(c.mode))
+	ops := make([]vm.Op, 1024)
+	for {
+		n, lexErr := lex.ReadOps(ops)
+		if err := m.Run(ops[:n]); err != nil {
+			return ErrNotEditable
+		}
+		if lexErr == io.EOF {
+			break
+		} else if lexErr != nil {
+			return lexErr
+		}
+	}
+	got, err := m.Top()
+	if err != nil || !got.Equal(want) {
+		return ErrNotEditable
+	}
+	return nil
+}
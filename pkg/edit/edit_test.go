@@ -0,0 +1,238 @@
+package edit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/dumper"
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/prettifier"
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+func str(s string) *types.Value {
+	return types.NewStringValue([]byte(s))
+}
+
+func dumpOps(t *testing.T, v *types.Value) []vm.Op {
+	t.Helper()
+	w := lexer.NewSliceWriter()
+	if err := dumper.NewDumper(w).Dump(v); err != nil {
+		t.Fatal(err)
+	}
+	return w.Ops()
+}
+
+// watsonLines encodes each element of lines into a separate line.
+func watsonLines(t *testing.T, lines ...[]vm.Op) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	u := lexer.NewUnlexer(buf)
+	for _, line := range lines {
+		for _, op := range line {
+			if err := u.Write(op); err != nil {
+				t.Fatal(err)
+			}
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+// entry returns the Ops that add key and v to an Object.
+func entry(t *testing.T, key string, v *types.Value) []vm.Op {
+	t.Helper()
+	ops := append(dumpOps(t, str(key)), dumpOps(t, v)...)
+	return append(ops, vm.Oadd)
+}
+
+func decode(t *testing.T, src []byte) *types.Value {
+	t.Helper()
+	m := vm.NewVM()
+	lex := lexer.NewLexer(bytes.NewReader(src))
+	for {
+		tok, err := lex.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Feed(tok.Op); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v, err := m.Top()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func lines(src []byte) [][]byte {
+	return bytes.Split(src, []byte("\n"))
+}
+
+func TestSetReplacesOnlyTheValue(t *testing.T) {
+	src := watsonLines(t,
+		[]vm.Op{vm.Onew},
+		entry(t, "a", types.NewIntValue(1)),
+		entry(t, "b", str("x")),
+	)
+	// A string flips the mode, so the following line is broken unless Set restores it.
+	out, err := Set(src, "<root>.a", str("long string"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"a": str("long string"),
+		"b": str("x"),
+	})
+	if diff := cmp.Diff(want, decode(t, out)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	srcLines, outLines := lines(src), lines(out)
+	if !bytes.Equal(srcLines[0], outLines[0]) || !bytes.Equal(srcLines[2], outLines[2]) {
+		t.Errorf("other lines are modified:\n%s\n%s", src, out)
+	}
+	key := len(dumpOps(t, str("a")))
+	if !bytes.Equal(srcLines[1][:key], outLines[1][:key]) {
+		t.Errorf("the key is modified:\n%s\n%s", src, out)
+	}
+}
+
+func TestSetKeepsDecorations(t *testing.T) {
+	v := types.NewObjectValue(map[string]*types.Value{
+		"name": str("app"),
+		"port": types.NewIntValue(80),
+		"tags": types.NewArrayValue([]*types.Value{str("a"), str("b")}),
+		"spec": types.NewObjectValue(map[string]*types.Value{"replicas": types.NewIntValue(1), "debug": types.NewBoolValue(true)}),
+	})
+	buf := &bytes.Buffer{}
+	if err := dumper.NewDumper(prettifier.NewPrettifier(lexer.NewUnlexer(buf))).Dump(v); err != nil {
+		t.Fatal(err)
+	}
+	src := buf.Bytes()
+
+	test := func(path string, x *types.Value, want *types.Value) {
+		t.Helper()
+		out, err := Set(src, path, x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, decode(t, out)); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	}
+	want := v.DeepCopy()
+	want.Object()["spec"].Object()["replicas"] = types.NewIntValue(3)
+	test("<root>.spec.replicas", types.NewIntValue(3), want)
+
+	want = v.DeepCopy()
+	want.Object()["tags"].Array()[1] = types.NewNilValue()
+	test("tags[1]", types.NewNilValue(), want)
+
+	want = v.DeepCopy()
+	want.Object()["spec"].Object()["image"] = str("nginx")
+	test("spec.image", str("nginx"), want)
+
+	test("<root>", types.NewIntValue(1), types.NewIntValue(1))
+}
+
+func TestDeleteRemovesOnlyTheEntry(t *testing.T) {
+	src := watsonLines(t,
+		[]vm.Op{vm.Onew},
+		entry(t, "a", str("x")),
+		entry(t, "b", types.NewArrayValue([]*types.Value{types.NewIntValue(1), str("y"), types.NewIntValue(3)})),
+	)
+	out, err := Delete(src, "<root>.a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"b": types.NewArrayValue([]*types.Value{types.NewIntValue(1), str("y"), types.NewIntValue(3)}),
+	})
+	if diff := cmp.Diff(want, decode(t, out)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	srcLines, outLines := lines(src), lines(out)
+	if !bytes.Equal(srcLines[0], outLines[0]) || !bytes.Equal(srcLines[2], outLines[2]) {
+		t.Errorf("other lines are modified:\n%s\n%s", src, out)
+	}
+
+	out, err = Delete(src, "b[1]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = types.NewObjectValue(map[string]*types.Value{
+		"a": str("x"),
+		"b": types.NewArrayValue([]*types.Value{types.NewIntValue(1), types.NewIntValue(3)}),
+	})
+	if diff := cmp.Diff(want, decode(t, out)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetAndDeleteReturnErrNotFound(t *testing.T) {
+	src := watsonLines(t,
+		[]vm.Op{vm.Onew},
+		entry(t, "a", types.NewArrayValue([]*types.Value{types.NewIntValue(1)})),
+	)
+	for _, path := range []string{"a[1]", "b.c", "a.c", "a[0][0]"} {
+		if _, err := Set(src, path, types.NewNilValue()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Set(%q): expected ErrNotFound but got %v", path, err)
+		}
+		if _, err := Delete(src, path); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Delete(%q): expected ErrNotFound but got %v", path, err)
+		}
+	}
+	if _, err := Delete(src, "<root>"); err == nil {
+		t.Errorf("expected an error but got nil")
+	}
+}
+
+func TestSetReturnsErrNotEditableWhenValueIsShared(t *testing.T) {
+	// [1, 1], whose elements are the same literal duplicated with Gdup.
+	src := watsonLines(t,
+		append(dumpOps(t, types.NewIntValue(1)), vm.Gdup),
+		[]vm.Op{vm.Anew, vm.Gswp, vm.Aadd, vm.Gswp, vm.Aadd},
+	)
+	if _, err := Set(src, "[0]", types.NewIntValue(2)); !errors.Is(err, ErrNotEditable) {
+		t.Errorf("expected ErrNotEditable but got %v", err)
+	}
+	if _, err := Set(src, "[0]", types.NewIntValue(1)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	test := func(path string, want []segment, formatted string) {
+		t.Helper()
+		got, err := parsePath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got, cmp.AllowUnexported(segment{})); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+		if f := formatPath(got); f != formatted {
+			t.Errorf("expected %s but got %s", formatted, f)
+		}
+	}
+	test("<root>", nil, "<root>")
+	test("", nil, "<root>")
+	test("<root>.a[1].b", []segment{{key: "a"}, {idx: 1, isIndex: true}, {key: "b"}}, "<root>.a[1].b")
+	test("a[1][2]", []segment{{key: "a"}, {idx: 1, isIndex: true}, {idx: 2, isIndex: true}}, "<root>.a[1][2]")
+	test(".a", []segment{{key: "a"}}, "<root>.a")
+	test("[0].a", []segment{{idx: 0, isIndex: true}, {key: "a"}}, "<root>[0].a")
+
+	for _, path := range []string{"a[", "a[x]", "a..b", "a[-1]", "a[0]b"} {
+		if _, err := parsePath(path); err == nil {
+			t.Errorf("%q: expected an error but got nil", path)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package edit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+// segment is either a key of an Object or an index of an Array.
+type segment struct {
+	key     string
+	idx     int
+	isIndex bool
+}
+
+// parsePath parses a path like "<root>.servers[1].port" into segments. The leading "<root>" can be omitted.
+func parsePath(path string) ([]segment, error) {
+	rest := strings.TrimPrefix(path, "<root>")
+	var segs []segment
+	for i := 0; i < len(rest); {
+		switch {
+		case rest[i] == '[':
+			end := strings.IndexByte(rest[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: missing ']'", path)
+			}
+			idx, err := strconv.Atoi(rest[i+1 : i+end])
+			if err != nil || idx < 0 {
+				return nil, fmt.Errorf("invalid path %q: invalid index %q", path, rest[i+1:i+end])
+			}
+			segs = append(segs, segment{idx: idx, isIndex: true})
+			i += end + 1
+		case rest[i] == '.' || i == 0:
+			if rest[i] == '.' {
+				i++
+			}
+			end := strings.IndexAny(rest[i:], ".[")
+			if end < 0 {
+				end = len(rest) - i
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid path %q: empty key", path)
+			}
+			segs = append(segs, segment{key: rest[i : i+end]})
+			i += end
+		default:
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+	}
+	return segs, nil
+}
+
+// formatPath formats segs in the same form as the paths of a sourcemap.SourceMap.
+func formatPath(segs []segment) string {
+	var b strings.Builder
+	b.WriteString("<root>")
+	for _, s := range segs {
+		if s.isIndex {
+			fmt.Fprintf(&b, "[%d]", s.idx)
+		} else {
+			b.WriteString(".")
+			b.WriteString(s.key)
+		}
+	}
+	return b.String()
+}
+
+// lookup returns the value at segs in v.
+func lookup(v *types.Value, segs []segment) (*types.Value, error) {
+	for i, s := range segs {
+		c, ok := child(v, s)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, formatPath(segs[:i+1]))
+		}
+		v = c
+	}
+	return v, nil
+}
+
+func child(v *types.Value, s segment) (*types.Value, bool) {
+	if s.isIndex {
+		arr := v.Array()
+		if v.Kind != types.Array || s.idx >= len(arr) {
+			return nil, false
+		}
+		return arr[s.idx], true
+	}
+	if v.Kind != types.Object {
+		return nil, false
+	}
+	c, ok := v.Object()[s.key]
+	return c, ok
+}
+
+func setChild(v *types.Value, s segment, c *types.Value) {
+	if s.isIndex {
+		v.Array()[s.idx] = c
+	} else {
+		v.Object()[s.key] = c
+	}
+}
+
+func deleteChild(v *types.Value, s segment) {
+	if !s.isIndex {
+		delete(v.Object(), s.key)
+		return
+	}
+	arr := v.Array()
+	elems := make([]*types.Value, 0, len(arr)-1)
+	elems = append(elems, arr[:s.idx]...)
+	elems = append(elems, arr[s.idx+1:]...)
+	*v = *types.NewArrayValue(elems)
+}
@@ -0,0 +1,315 @@
+// Package highlight colors Watson Representations.
+//
+// The same character represents different Ops in different modes, so each byte is colored by the category of the Op
+// that it actually represents. In addition:
+//   * Bytes that flip the mode of the lexer (i.e. Snew) are highlighted in reverse video.
+//   * Bytes that are read in mode S are underlined, so the current mode can be seen at a glance.
+//   * Bytes that are ignored by the lexer are dimmed.
+package highlight
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+// Category is a category of Ops.
+type Category int
+
+const (
+	Int     Category = iota // Inew, Iinc, Ishl, Iadd, Ineg, Isht, Itou
+	Float                   // Itof, Finf, Fnan, Fneg
+	String                  // Snew, Sadd
+	Object                  // Onew, Oadd
+	Array                   // Anew, Aadd
+	Bool                    // Bnew, Bneg
+	Nil                     // Nnew
+	Generic                 // Gdup, Gpop, Gswp
+	Ignored                 // bytes that don't represent any Op
+)
+
+// CategoryOf returns the category of op.
+func CategoryOf(op vm.Op) Category {
+	switch op {
+	case vm.Inew, vm.Iinc, vm.Ishl, vm.Iadd, vm.Ineg, vm.Isht, vm.Itou:
+		return Int
+	case vm.Itof, vm.Finf, vm.Fnan, vm.Fneg:
+		return Float
+	case vm.Snew, vm.Sadd:
+		return String
+	case vm.Onew, vm.Oadd:
+		return Object
+	case vm.Anew, vm.Aadd:
+		return Array
+	case vm.Bnew, vm.Bneg:
+		return Bool
+	case vm.Nnew:
+		return Nil
+	case vm.Gdup, vm.Gpop, vm.Gswp:
+		return Generic
+	default:
+		panic(fmt.Errorf("invalid opcode: %d", op))
+	}
+}
+
+func (c Category) name() string {
+	switch c {
+	case Int:
+		return "int"
+	case Float:
+		return "float"
+	case String:
+		return "string"
+	case Object:
+		return "object"
+	case Array:
+		return "array"
+	case Bool:
+		return "bool"
+	case Nil:
+		return "nil"
+	case Generic:
+		return "generic"
+	case Ignored:
+		return "ignored"
+	default:
+		panic(fmt.Errorf("invalid category: %d", c))
+	}
+}
+
+// Char is a classified byte of a Watson Representation.
+type Char struct {
+	Byte     byte
+	Category Category
+	// Mode is the mode of the lexer when it reads Byte.
+	Mode lexer.Mode
+	// Flip is true if Byte flips the mode of the lexer.
+	Flip bool
+}
+
+// Classify classifies each byte of src as a lexer whose initial mode is mode reads it.
+func Classify(src []byte, mode lexer.Mode) []Char {
+	chars := make([]Char, 0, len(src))
+	for _, b := range src {
+		op, ok := lexer.Lookup(mode, b)
+		if !ok {
+			chars = append(chars, Char{Byte: b, Category: Ignored, Mode: mode})
+			continue
+		}
+		next := lexer.NextMode(mode, op)
+		chars = append(chars, Char{Byte: b, Category: CategoryOf(op), Mode: mode, Flip: next != mode})
+		mode = next
+	}
+	return chars
+}
+
+// Format is a format of the output of Highlight.
+type Format int
+
+const (
+	// ANSI colors bytes with ANSI escape sequences.
+	ANSI Format = iota
+	// HTML wraps bytes with <span>s in a <pre>. See HTMLStyle for their classes.
+	HTML
+)
+
+func (f *Format) String() string {
+	switch *f {
+	case ANSI:
+		return "ansi"
+	case HTML:
+		return "html"
+	default:
+		panic("unknown format")
+	}
+}
+
+func (f *Format) Set(name string) error {
+	switch name {
+	case "ansi":
+		*f = ANSI
+	case "html":
+		*f = HTML
+	default:
+		return fmt.Errorf("unknown format: %s", name)
+	}
+	return nil
+}
+
+// HTMLStyle is a style sheet for the output of Highlight in HTML.
+const HTMLStyle = `pre.watson .watson-int { color: #1f6feb; }
+pre.watson .watson-float { color: #0a8f8f; }
+pre.watson .watson-string { color: #1a7f37; }
+pre.watson .watson-object { color: #8250df; }
+pre.watson .watson-array { color: #9a6700; }
+pre.watson .watson-bool { color: #cf222e; }
+pre.watson .watson-nil { color: #bc4c00; }
+pre.watson .watson-generic { font-weight: bold; }
+pre.watson .watson-ignored { opacity: 0.4; }
+pre.watson .watson-mode-s { text-decoration: underline; }
+pre.watson .watson-flip { color: #ffffff; background-color: #1a7f37; }
+`
+
+// Option configures Highlight.
+type Option interface {
+	apply(*config)
+}
+
+type option func(*config)
+
+func (opt option) apply(c *config) {
+	opt(c)
+}
+
+// WithFormat sets the format of the output. The default is ANSI.
+func WithFormat(f Format) Option {
+	return option(func(c *config) {
+		c.format = f
+	})
+}
+
+// WithInitialMode sets the initial mode of the lexer. The default is lexer.A.
+func WithInitialMode(mode lexer.Mode) Option {
+	return option(func(c *config) {
+		c.mode = mode
+	})
+}
+
+type config struct {
+	format Format
+	mode   lexer.Mode
+}
+
+// Highlight writes src to w with colors.
+func Highlight(w io.Writer, src []byte, opts ...Option) error {
+	c := &config{format: ANSI, mode: lexer.A}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	chars := Classify(src, c.mode)
+	switch c.format {
+	case ANSI:
+		return writeANSI(w, chars)
+	case HTML:
+		return writeHTML(w, chars)
+	default:
+		return fmt.Errorf("unknown format: %d", c.format)
+	}
+}
+
+var ansiColors = map[Category]string{
+	Int:     "34",
+	Float:   "36",
+	String:  "32",
+	Object:  "35",
+	Array:   "33",
+	Bool:    "31",
+	Nil:     "91",
+	Generic: "1",
+	Ignored: "2",
+}
+
+const ansiReset = "\x1b[0m"
+
+func ansiStyle(ch Char) string {
+	if isBlank(ch.Byte) {
+		return ""
+	}
+	style := ansiColors[ch.Category]
+	if ch.Mode == lexer.S {
+		style += ";4"
+	}
+	if ch.Flip {
+		style += ";7"
+	}
+	return "\x1b[" + style + "m"
+}
+
+// writeANSI writes chars with escape sequences. Sequences are only written when the style changes,
+// and the style is reset before each newline so that it doesn't leak to other lines.
+func writeANSI(w io.Writer, chars []Char) error {
+	out := make([]byte, 0, len(chars)*2)
+	current := ""
+	for _, ch := range chars {
+		style := ansiStyle(ch)
+		if style != current {
+			if current != "" {
+				out = append(out, ansiReset...)
+			}
+			out = append(out, style...)
+			current = style
+		}
+		out = append(out, ch.Byte)
+	}
+	if current != "" {
+		out = append(out, ansiReset...)
+	}
+	_, err := w.Write(out)
+	return err
+}
+
+func htmlClass(ch Char) string {
+	if isBlank(ch.Byte) {
+		return ""
+	}
+	class := "watson-" + ch.Category.name()
+	if ch.Mode == lexer.S {
+		class += " watson-mode-s"
+	}
+	if ch.Flip {
+		class += " watson-flip"
+	}
+	return class
+}
+
+// writeHTML writes chars in a <pre> whose class is "watson". Consecutive bytes that have the same style share one <span>.
+func writeHTML(w io.Writer, chars []Char) error {
+	out := make([]byte, 0, len(chars)*4)
+	out = append(out, `<pre class="watson">`...)
+	current := ""
+	for _, ch := range chars {
+		class := htmlClass(ch)
+		if class != current {
+			if current != "" {
+				out = append(out, "</span>"...)
+			}
+			if class != "" {
+				out = append(out, `<span class="`+class+`">`...)
+			}
+			current = class
+		}
+		out = appendEscaped(out, ch.Byte)
+	}
+	if current != "" {
+		out = append(out, "</span>"...)
+	}
+	out = append(out, "</pre>\n"...)
+	_, err := w.Write(out)
+	return err
+}
+
+// appendEscaped appends b to out, escaping it if it is special in HTML.
+// Other bytes are written as is so that multi-byte characters in UTF-8 are kept.
+func appendEscaped(out []byte, b byte) []byte {
+	switch b {
+	case '<':
+		return append(out, "&lt;"...)
+	case '>':
+		return append(out, "&gt;"...)
+	case '&':
+		return append(out, "&amp;"...)
+	case '"':
+		return append(out, "&#34;"...)
+	case '\'':
+		return append(out, "&#39;"...)
+	default:
+		return append(out, b)
+	}
+}
+
+// isBlank reports whether b is written without any style. Newlines are included so that styles don't span lines.
+func isBlank(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
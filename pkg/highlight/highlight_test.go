@@ -0,0 +1,78 @@
+package highlight
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+func TestCategoryOfIsDefinedForAllOps(t *testing.T) {
+	for _, op := range vm.AllOps() {
+		CategoryOf(op)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	// Ishl (A), Snew (A -> S), Fnan (S), ' ' (ignored in S), Snew (S -> A), Finf (A)
+	got := Classify([]byte("b?b $q"), lexer.A)
+	want := []Char{
+		{Byte: 'b', Category: Int, Mode: lexer.A},
+		{Byte: '?', Category: String, Mode: lexer.A, Flip: true},
+		{Byte: 'b', Category: Float, Mode: lexer.S},
+		{Byte: ' ', Category: Ignored, Mode: lexer.S},
+		{Byte: '$', Category: String, Mode: lexer.S, Flip: true},
+		{Byte: 'q', Category: Float, Mode: lexer.A},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestClassifyWithInitialModeS(t *testing.T) {
+	got := Classify([]byte("?"), lexer.S)
+	want := []Char{{Byte: '?', Category: Array, Mode: lexer.S}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestHighlightANSI(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := Highlight(buf, []byte("Bu?S x\n$."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1b[34mBu\x1b[0m\x1b[32;7m?\x1b[0m\x1b[34;4mS\x1b[0m \x1b[2;4mx\x1b[0m\n" +
+		"\x1b[32;4;7m$\x1b[0m\x1b[91m.\x1b[0m"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestHighlightHTML(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := Highlight(buf, []byte("~?<\n$あ"), WithFormat(HTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<pre class="watson"><span class="watson-object">~</span><span class="watson-string watson-flip">?</span>` +
+		`<span class="watson-ignored watson-mode-s">&lt;</span>` + "\n" +
+		`<span class="watson-string watson-mode-s watson-flip">$</span><span class="watson-ignored">あ</span></pre>` + "\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestFormatFlag(t *testing.T) {
+	var f Format
+	if err := f.Set("html"); err != nil || f != HTML {
+		t.Fatalf("expected HTML, got %s (err = %v)", f.String(), err)
+	}
+	if err := f.Set("svg"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
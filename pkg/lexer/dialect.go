@@ -0,0 +1,260 @@
+package lexer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+// ErrInvalidDialect is returned when a Dialect is not well-formed.
+var ErrInvalidDialect = errors.New("invalid dialect")
+
+// Alphabet is a correspondence between Ops and bytes in a mode.
+// A valid Alphabet maps every Op to a distinct byte, so that it is a bijection between Ops and the bytes used.
+type Alphabet map[vm.Op]byte
+
+// Dialect is a set of Alphabets of modes A and S, together with the Ops that switch the mode.
+// Whenever a lexer reads (or an unlexer writes) one of these Ops, its mode is flipped from A to S or vice versa.
+//
+// Watson itself is the DefaultDialect, whose Alphabets are described in the package overview and whose mode is switched by Snew.
+// Lexers and unlexers that use the same Dialect convert the same sequence of Ops into the same bytes and vice versa,
+// so files written in a Dialect other than the DefaultDialect can only be read by lexers that use the Dialect.
+type Dialect struct {
+	// ops and known are indexed by modes and bytes, so that lexers can look Ops up without hashing.
+	ops   [2][256]vm.Op
+	known [2][256]bool
+	bytes [2]Alphabet
+	// flips is indexed by Ops.
+	flips []bool
+}
+
+// DefaultDialect is the dialect defined in the specification of Watson.
+var DefaultDialect = mustNewDialect(reverse(opTableA), reverse(opTableS), []vm.Op{vm.Snew})
+
+// NewDialect creates a new Dialect whose mode is switched by each of flips.
+// It returns an error that wraps ErrInvalidDialect if a or s is not a valid Alphabet.
+func NewDialect(a, s Alphabet, flips []vm.Op) (*Dialect, error) {
+	d := &Dialect{flips: make([]bool, len(vm.AllOps()))}
+	for i, alphabet := range []Alphabet{a, s} {
+		mode := Mode(i)
+		if err := validateAlphabet(alphabet); err != nil {
+			return nil, fmt.Errorf("%w: mode %s: %s", ErrInvalidDialect, modeName(mode), err.Error())
+		}
+		d.bytes[mode] = Alphabet{}
+		for op, b := range alphabet {
+			d.bytes[mode][op] = b
+			d.ops[mode][b] = op
+			d.known[mode][b] = true
+		}
+	}
+	for _, op := range flips {
+		if !isValidOp(op) {
+			return nil, fmt.Errorf("%w: invalid opcode to flip the mode: %d", ErrInvalidDialect, op)
+		}
+		d.flips[op] = true
+	}
+	return d, nil
+}
+
+func mustNewDialect(a, s Alphabet, flips []vm.Op) *Dialect {
+	d, err := NewDialect(a, s, flips)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func validateAlphabet(alphabet Alphabet) error {
+	used := map[byte]vm.Op{}
+	for op, b := range alphabet {
+		if !isValidOp(op) {
+			return fmt.Errorf("invalid opcode: %d", op)
+		}
+		if other, ok := used[b]; ok {
+			first, second := op, other
+			if second < first {
+				first, second = second, first
+			}
+			return fmt.Errorf("%#v and %#v are represented by the same byte %q", first, second, b)
+		}
+		used[b] = op
+	}
+	for _, op := range vm.AllOps() {
+		if _, ok := alphabet[op]; !ok {
+			return fmt.Errorf("%#v is missing", op)
+		}
+	}
+	return nil
+}
+
+func isValidOp(op vm.Op) bool {
+	ops := vm.AllOps()
+	return 0 <= op && op <= ops[len(ops)-1]
+}
+
+func reverse(table map[byte]vm.Op) Alphabet {
+	alphabet := Alphabet{}
+	for b, op := range table {
+		alphabet[op] = b
+	}
+	return alphabet
+}
+
+// Lookup returns the Op that b represents in mode m.
+// ok is false if b doesn't represent any Op in m, that is, if lexers ignore b.
+func (d *Dialect) Lookup(m Mode, b byte) (op vm.Op, ok bool) {
+	m = checkMode(m)
+	return d.ops[m][b], d.known[m][b]
+}
+
+// Show returns the byte that represents op in mode m.
+func (d *Dialect) Show(m Mode, op vm.Op) byte {
+	if b, ok := d.bytes[checkMode(m)][op]; ok {
+		return b
+	}
+	panic(fmt.Errorf("unknown Op: %#v\n", op))
+}
+
+// NextMode returns the mode of a lexer right after it yields op in mode.
+func (d *Dialect) NextMode(mode Mode, op vm.Op) Mode {
+	checkMode(mode)
+	if !d.flips[op] {
+		return mode
+	}
+	return flip(mode)
+}
+
+func flip(mode Mode) Mode {
+	if mode == A {
+		return S
+	}
+	return A
+}
+
+// Alphabet returns a copy of the Alphabet of mode m.
+func (d *Dialect) Alphabet(m Mode) Alphabet {
+	alphabet := Alphabet{}
+	for op, b := range d.bytes[checkMode(m)] {
+		alphabet[op] = b
+	}
+	return alphabet
+}
+
+// Flips returns the Ops that switch the mode in the order of their opcodes.
+func (d *Dialect) Flips() []vm.Op {
+	flips := make([]vm.Op, 0)
+	for op, ok := range d.flips {
+		if ok {
+			flips = append(flips, vm.Op(op))
+		}
+	}
+	return flips
+}
+
+func checkMode(m Mode) Mode {
+	if m != A && m != S {
+		panic(fmt.Errorf("unknown mode: %d", m))
+	}
+	return m
+}
+
+func modeName(m Mode) string {
+	switch m {
+	case A:
+		return "A"
+	case S:
+		return "S"
+	default:
+		panic(fmt.Errorf("unknown mode: %d", m))
+	}
+}
+
+// DialectFromValue creates a Dialect from v, which is an Object that looks like the following YAML:
+//   A:                # the Alphabet of mode A; names of all Ops and the characters that represent them
+//     Inew: "B"
+//     Iinc: "u"
+//     ...
+//   S:                # the Alphabet of mode S
+//     Inew: "S"
+//     ...
+//   flips: ["Snew"]   # optional; Ops that switch the mode. The default is ["Snew"].
+//
+// It returns an error that wraps ErrInvalidDialect if v is malformed or doesn't describe a valid Dialect.
+func DialectFromValue(v *types.Value) (*Dialect, error) {
+	if v.Kind != types.Object {
+		return nil, fmt.Errorf("%w: dialect must be an object", ErrInvalidDialect)
+	}
+	for k := range v.Object() {
+		if k != "A" && k != "S" && k != "flips" {
+			return nil, fmt.Errorf("%w: unknown key: %s", ErrInvalidDialect, k)
+		}
+	}
+	var alphabets [2]Alphabet
+	for i, name := range []string{"A", "S"} {
+		table, ok := v.Object()[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: alphabet of mode %s is missing", ErrInvalidDialect, name)
+		}
+		if table.Kind != types.Object {
+			return nil, fmt.Errorf("%w: alphabet of mode %s must be an object", ErrInvalidDialect, name)
+		}
+		alphabets[i] = Alphabet{}
+		for opName, b := range table.Object() {
+			op, ok := opByName(opName)
+			if !ok {
+				return nil, fmt.Errorf("%w: mode %s: unknown Op: %s", ErrInvalidDialect, name, opName)
+			}
+			if b.Kind != types.String || len(b.Bytes()) != 1 {
+				return nil, fmt.Errorf("%w: mode %s: %s must be represented by a string of exactly one byte", ErrInvalidDialect, name, opName)
+			}
+			alphabets[i][op] = b.Bytes()[0]
+		}
+	}
+	flips := []vm.Op{vm.Snew}
+	if f, ok := v.Object()["flips"]; ok {
+		if f.Kind != types.Array {
+			return nil, fmt.Errorf("%w: flips must be an array", ErrInvalidDialect)
+		}
+		flips = make([]vm.Op, 0, len(f.Array()))
+		for _, name := range f.Array() {
+			if name.Kind != types.String {
+				return nil, fmt.Errorf("%w: flips must be an array of strings", ErrInvalidDialect)
+			}
+			op, ok := opByName(string(name.Bytes()))
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown Op: %s", ErrInvalidDialect, name.Bytes())
+			}
+			flips = append(flips, op)
+		}
+	}
+	return NewDialect(alphabets[A], alphabets[S], flips)
+}
+
+// ToValue converts d into the form that DialectFromValue accepts.
+func (d *Dialect) ToValue() *types.Value {
+	obj := map[string]*types.Value{}
+	for i, name := range []string{"A", "S"} {
+		table := map[string]*types.Value{}
+		for op, b := range d.bytes[i] {
+			table[op.GoString()] = types.NewStringValue([]byte{b})
+		}
+		obj[name] = types.NewObjectValue(table)
+	}
+	flips := make([]*types.Value, 0)
+	for _, op := range d.Flips() {
+		flips = append(flips, types.NewStringValue([]byte(op.GoString())))
+	}
+	obj["flips"] = types.NewArrayValue(flips)
+	return types.NewObjectValue(obj)
+}
+
+func opByName(name string) (vm.Op, bool) {
+	for _, op := range vm.AllOps() {
+		if op.GoString() == name {
+			return op, true
+		}
+	}
+	return 0, false
+}
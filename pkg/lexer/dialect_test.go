@@ -0,0 +1,177 @@
+package lexer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+// alphabet builds an Alphabet from a string whose i-th byte represents vm.Op(i).
+func alphabet(s string) Alphabet {
+	a := Alphabet{}
+	for i, op := range vm.AllOps() {
+		a[op] = s[i]
+	}
+	return a
+}
+
+// A dialect that only uses alphanumeric characters, so that it doesn't contain shell metacharacters.
+var alnumA = "BubaAeiIqtpQXOMVszoNEPW"
+var alnumS = "ShakrAzimbuDncgvxjfydew"
+
+func TestDefaultDialectIsTheConversionTable(t *testing.T) {
+	for b, op := range opTableA {
+		if got, ok := DefaultDialect.Lookup(A, b); !ok || got != op {
+			t.Errorf("expected %#v but got %#v (ok = %t)", op, got, ok)
+		}
+		if got := DefaultDialect.Show(A, op); got != b {
+			t.Errorf("expected %q but got %q", b, got)
+		}
+	}
+	for b, op := range opTableS {
+		if got, ok := DefaultDialect.Lookup(S, b); !ok || got != op {
+			t.Errorf("expected %#v but got %#v (ok = %t)", op, got, ok)
+		}
+	}
+	if diff := cmp.Diff([]vm.Op{vm.Snew}, DefaultDialect.Flips()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewDialectRejectsInvalidAlphabets(t *testing.T) {
+	missing := alphabet(alnumA)
+	delete(missing, vm.Gswp)
+	duplicated := alphabet(alnumA)
+	duplicated[vm.Gswp] = 'B'
+	extra := alphabet(alnumA)
+	extra[vm.Op(100)] = '0'
+	cases := []struct {
+		a, s  Alphabet
+		flips []vm.Op
+		want  string
+	}{
+		{missing, alphabet(alnumS), nil, "invalid dialect: mode A: Gswp is missing"},
+		{alphabet(alnumA), duplicated, nil, "invalid dialect: mode S: Inew and Gswp are represented by the same byte 'B'"},
+		{extra, alphabet(alnumS), nil, "invalid dialect: mode A: invalid opcode: 100"},
+		{alphabet(alnumA), alphabet(alnumS), []vm.Op{vm.Op(-1)}, "invalid dialect: invalid opcode to flip the mode: -1"},
+	}
+	for _, c := range cases {
+		_, err := NewDialect(c.a, c.s, c.flips)
+		if !errors.Is(err, ErrInvalidDialect) {
+			t.Errorf("expected ErrInvalidDialect, got %v", err)
+			continue
+		}
+		if err.Error() != c.want {
+			t.Errorf("expected %q, got %q", c.want, err.Error())
+		}
+	}
+}
+
+func TestLexerAndUnlexerWithDialect(t *testing.T) {
+	d, err := NewDialect(alphabet(alnumA), alphabet(alnumS), []vm.Op{vm.Snew})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := []vm.Op{vm.Onew, vm.Snew, vm.Inew, vm.Iinc, vm.Sadd, vm.Snew, vm.Gpop, vm.Nnew, vm.Oadd}
+	buf := bytes.NewBuffer(nil)
+	u := NewUnlexer(buf, WithUnlexerDialect(d))
+	for _, op := range ops {
+		if err := u.Write(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if diff := cmp.Diff("OQShnDPNM", buf.String()); diff != "" {
+		t.Error(diff)
+	}
+
+	l := NewLexer(bytes.NewReader(buf.Bytes()), WithLexerDialect(d))
+	got := make([]vm.Op, 0)
+	for {
+		tok, err := l.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, tok.Op)
+	}
+	if diff := cmp.Diff(ops, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestDialectWithCustomFlips(t *testing.T) {
+	d, err := NewDialect(alphabet(alnumA), alphabet(alnumS), []vm.Op{vm.Gdup, vm.Gswp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.NextMode(A, vm.Snew); got != A {
+		t.Errorf("expected A but got %d", got)
+	}
+	if got := d.NextMode(A, vm.Gdup); got != S {
+		t.Errorf("expected S but got %d", got)
+	}
+	if got := d.NextMode(S, vm.Gswp); got != A {
+		t.Errorf("expected A but got %d", got)
+	}
+}
+
+func TestDialectFromValue(t *testing.T) {
+	d, err := DialectFromValue(DefaultDialect.ToValue())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []Mode{A, S} {
+		if diff := cmp.Diff(DefaultDialect.Alphabet(m), d.Alphabet(m)); diff != "" {
+			t.Error(diff)
+		}
+	}
+	if diff := cmp.Diff(DefaultDialect.Flips(), d.Flips()); diff != "" {
+		t.Error(diff)
+	}
+
+	// flips defaults to Snew.
+	v := DefaultDialect.ToValue()
+	delete(v.Object(), "flips")
+	d, err = DialectFromValue(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]vm.Op{vm.Snew}, d.Flips()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestDialectFromValueRejectsMalformedValues(t *testing.T) {
+	withA := func(k string, v *types.Value) *types.Value {
+		d := DefaultDialect.ToValue()
+		d.Object()["A"].Object()[k] = v
+		return d
+	}
+	cases := []struct {
+		v    *types.Value
+		want string
+	}{
+		{types.NewNilValue(), "invalid dialect: dialect must be an object"},
+		{types.NewObjectValue(map[string]*types.Value{"A": types.NewObjectValue(nil)}), "invalid dialect: alphabet of mode S is missing"},
+		{withA("Ixxx", types.NewStringValue([]byte("0"))), "invalid dialect: mode A: unknown Op: Ixxx"},
+		{withA("Inew", types.NewStringValue([]byte("BB"))), "invalid dialect: mode A: Inew must be represented by a string of exactly one byte"},
+		{withA("Inew", types.NewStringValue([]byte("u"))), "invalid dialect: mode A: Inew and Iinc are represented by the same byte 'u'"},
+	}
+	for _, c := range cases {
+		_, err := DialectFromValue(c.v)
+		if !errors.Is(err, ErrInvalidDialect) {
+			t.Errorf("expected ErrInvalidDialect, got %v", err)
+			continue
+		}
+		if err.Error() != c.want {
+			t.Errorf("expected %q, got %q", c.want, err.Error())
+		}
+	}
+}
@@ -103,6 +103,24 @@ func WithInitialLexerMode(mode Mode) LexerOption {
 	})
 }
 
+// WithLexerDialect sets a Dialect of a lexer. The default is DefaultDialect.
+func WithLexerDialect(d *Dialect) LexerOption {
+	return lexerOption(func(l *Lexer) {
+		l.dialect = d
+	})
+}
+
+// WithStrict makes a lexer strict. A strict lexer returns an *UnknownByteError when it reads a byte that doesn't represent any Op in its current mode,
+// unless the byte is in allowed, e.g. whitespaces or characters used in comments.
+func WithStrict(allowed ...byte) LexerOption {
+	return lexerOption(func(l *Lexer) {
+		l.strict = true
+		for _, b := range allowed {
+			l.allowed[b] = true
+		}
+	})
+}
+
 // WithFileName sets a file name of a lexer.
 // File name is only used to generate error messages.
 func WithFileName(name string) LexerOption {
@@ -122,18 +140,34 @@ func WithFileName(name string) LexerOption {
 // After that, it hits 'b' again, but in this time the 'b' is interpreted differently from the previous lexing step. Since the current mode of the lexer is S, it regards 'b' as `Fnan` instead of `Ishl`.
 // Then it hits '?', which is now interpreted as `Snew`, yields `Snew`, and changes its current mode to A.
 // In the end, it hits 'q' and yields `Finf`, and it stops its lexing procedure.
+//
+// A lexer reads its input in chunks, so it may read more bytes from the underlying Reader than the ones it has yielded as Ops.
 type Lexer struct {
-	r        io.Reader
-	mode     Mode
-	buf      [1]byte
+	r       io.Reader
+	mode    Mode
+	dialect *Dialect
+	strict  bool
+	allowed [256]bool
+	// buf[pos:end] is the part of the input that is read from r but not lexed yet.
+	buf []byte
+	pos int
+	end int
+	// err is an error returned by r, which is deferred until buf is consumed.
+	err      error
 	fileName string
 	line     int
 	column   int
 }
 
+// bufferSize is the size of the buffer of a Lexer.
+const bufferSize = 4096
+
+// maxEmptyReads is the number of successive reads that return neither bytes nor an error before a Lexer gives up with io.ErrNoProgress.
+const maxEmptyReads = 100
+
 // Creates a new Lexer that reads Watson Representation from r.
 func NewLexer(r io.Reader, opts ...LexerOption) *Lexer {
-	l := &Lexer{r: r, mode: A}
+	l := &Lexer{r: r, mode: A, dialect: DefaultDialect, buf: make([]byte, bufferSize)}
 	for _, opt := range opts {
 		opt.apply(l)
 	}
@@ -147,31 +181,118 @@ func (l *Lexer) Mode() Mode {
 
 // Returns the next Op.
 // This returns io.EOF if it hits on the end of the input.
+// A strict lexer returns an *UnknownByteError if it hits on a byte that is neither an Op nor allowed. It can continue lexing after that.
+//
+// Next allocates a Token for each Op. Use NextN or ReadOps to lex large inputs.
 func (l *Lexer) Next() (*Token, error) {
-	for {
-		_, err := l.r.Read(l.buf[:])
+	var toks [1]Token
+	if _, err := l.lex(nil, toks[:]); err != nil {
+		return nil, err
+	}
+	return &toks[0], nil
+}
+
+// NextN reads up to len(toks) Tokens into toks and returns the number of Tokens read.
+// It returns a non-nil error if and only if it stops before filling toks, in which case the first n Tokens are still valid.
+// The errors are the same as the ones of Next; in particular, it returns (0, io.EOF) at the end of the input.
+func (l *Lexer) NextN(toks []Token) (n int, err error) {
+	return l.lex(nil, toks)
+}
+
+// ReadOps is the same as NextN except that it only reads Ops, which is sufficient to execute them on a VM.
+func (l *Lexer) ReadOps(ops []vm.Op) (n int, err error) {
+	return l.lex(ops, nil)
+}
+
+// lex reads Ops into either ops or toks, whichever is non-nil.
+// The state of the lexer is kept in local variables while lexing a chunk of the input since this is the hot path of decoding.
+func (l *Lexer) lex(ops []vm.Op, toks []Token) (n int, err error) {
+	size := len(ops)
+	if toks != nil {
+		size = len(toks)
+	}
+	d := l.dialect
+	mode := l.mode
+	line, col := l.line, l.column
+	for n < size {
+		if l.pos >= l.end {
+			if err = l.fill(); err != nil {
+				// Note that it returns io.EOF if the underlying Reader returns io.EOF.
+				break
+			}
+		}
+		buf := l.buf[l.pos:l.end]
+		i := 0
+		for i < len(buf) && n < size {
+			b := buf[i]
+			i++
+			tokLine, tokCol := line, col
+			if b == newline {
+				line++
+				col = 0
+			} else {
+				col++
+			}
+			if d.known[mode][b] {
+				op := d.ops[mode][b]
+				if ops != nil {
+					ops[n] = op
+				} else {
+					toks[n] = Token{Op: op, FileName: l.fileName, Line: tokLine, Column: tokCol}
+				}
+				n++
+				if d.flips[op] {
+					mode = flip(mode)
+				}
+			} else if l.strict && !l.allowed[b] {
+				err = &UnknownByteError{Byte: b, Mode: mode, FileName: l.fileName, Line: tokLine, Column: tokCol}
+				break
+			}
+		}
+		l.pos += i
+		if err != nil {
+			break
+		}
+	}
+	l.mode = mode
+	l.line, l.column = line, col
+	return
+}
+
+// fill reads the next chunk of the input into buf. It returns an error if there's nothing to lex.
+func (l *Lexer) fill() error {
+	if l.err != nil {
+		return l.err
+	}
+	for i := 0; i < maxEmptyReads; i++ {
+		n, err := l.r.Read(l.buf)
+		l.pos = 0
+		l.end = n
 		if err != nil {
-			// Note that it returns io.EOF if the underlying Reader returns io.EOF.
-			return nil, err
+			l.err = err
 		}
-		line := l.line
-		col := l.column
-		if l.buf[0] == newline {
-			l.line++
-			l.column = 0
-		} else {
-			l.column++
+		if n > 0 {
+			return nil
 		}
-		if op, ok := readOp(l.mode, l.buf[0]); ok {
-			l.mode = nextMode(l.mode, op)
-			return &Token{
-				Op:       op,
-				FileName: l.fileName,
-				Line:     line,
-				Column:   col,
-			}, nil
+		if err != nil {
+			return err
 		}
 	}
+	return io.ErrNoProgress
+}
+
+// UnknownByteError is returned by a strict Lexer when it reads a byte that doesn't represent any Op.
+// Line and Column are zero-based like the ones of Token.
+type UnknownByteError struct {
+	Byte     byte
+	Mode     Mode
+	FileName string
+	Line     int
+	Column   int
+}
+
+func (e *UnknownByteError) Error() string {
+	return fmt.Sprintf("unknown byte %q in mode %s at %#v line %d, column %d", e.Byte, modeName(e.Mode), e.FileName, e.Line+1, e.Column+1)
 }
 
 // OpWriter is an abstract interface that defines what the Unlexer does.
@@ -207,7 +328,7 @@ func (s *SliceWriter) Ops() []vm.Op {
 
 func (s *SliceWriter) Write(op vm.Op) error {
 	s.ops = append(s.ops, op)
-	s.mode = nextMode(s.mode, op)
+	s.mode = DefaultDialect.NextMode(s.mode, op)
 	return nil
 }
 
@@ -229,17 +350,26 @@ func WithInitialUnlexerMode(m Mode) UnlexerOption {
 	})
 }
 
+// WithUnlexerDialect sets a Dialect of an Unlexer. The default is DefaultDialect.
+func WithUnlexerDialect(d *Dialect) UnlexerOption {
+	return unlexerOption(func(u *Unlexer) {
+		u.dialect = d
+	})
+}
+
 // Unlexer converts a sequence of `vm.Op`s into a sequence of characters.
 type Unlexer struct {
-	w    io.Writer
-	mode Mode
+	w       io.Writer
+	mode    Mode
+	dialect *Dialect
 }
 
 // NewUnlexer returns a new Unlexer that writes to w.
 func NewUnlexer(w io.Writer, opts ...UnlexerOption) *Unlexer {
 	u := &Unlexer{
-		w:    w,
-		mode: A,
+		w:       w,
+		mode:    A,
+		dialect: DefaultDialect,
 	}
 	for _, opt := range opts {
 		opt.apply(u)
@@ -250,8 +380,8 @@ func NewUnlexer(w io.Writer, opts ...UnlexerOption) *Unlexer {
 // Write writes an Op to the underlying io.Writer.
 func (u *Unlexer) Write(op vm.Op) error {
 	b := make([]byte, 1)
-	b[0] = showOp(u.mode, op)
-	u.mode = nextMode(u.mode, op)
+	b[0] = u.dialect.Show(u.mode, op)
+	u.mode = u.dialect.NextMode(u.mode, op)
 	_, err := u.w.Write(b)
 	return err
 }
@@ -261,25 +391,15 @@ func (u *Unlexer) Mode() Mode {
 	return u.mode
 }
 
-func nextMode(mode Mode, op vm.Op) Mode {
-	var next Mode
-	switch mode {
-	case A:
-		if op == vm.Snew {
-			next = S
-		} else {
-			next = A
-		}
-	case S:
-		if op == vm.Snew {
-			next = A
-		} else {
-			next = S
-		}
-	default:
-		panic(fmt.Errorf("unknown mode: %d", mode))
-	}
-	return next
+// NextMode returns the mode of a lexer that uses DefaultDialect right after it yields op in mode.
+func NextMode(mode Mode, op vm.Op) Mode {
+	return DefaultDialect.NextMode(mode, op)
+}
+
+// Lookup returns the Op that b represents in mode m of DefaultDialect.
+// ok is false if b doesn't represent any Op in m, that is, if lexers ignore b.
+func Lookup(m Mode, b byte) (op vm.Op, ok bool) {
+	return DefaultDialect.Lookup(m, b)
 }
 
 var opTableA = map[byte]vm.Op{
@@ -308,8 +428,6 @@ var opTableA = map[byte]vm.Op{
 	char("%"): vm.Gswp,
 }
 
-var reversedTableA map[vm.Op]byte
-
 var opTableS = map[byte]vm.Op{
 	char("S"): vm.Inew,
 	char("h"): vm.Iinc,
@@ -336,49 +454,6 @@ var opTableS = map[byte]vm.Op{
 	char(":"): vm.Gswp,
 }
 
-var reversedTableS map[vm.Op]byte
-
-func init() {
-	reversedTableA = make(map[vm.Op]byte)
-	for k, v := range opTableA {
-		reversedTableA[v] = k
-	}
-	reversedTableS = make(map[vm.Op]byte)
-	for k, v := range opTableS {
-		reversedTableS[v] = k
-	}
-}
-
-func readOp(m Mode, b byte) (op vm.Op, ok bool) {
-	var table map[byte]vm.Op
-	switch m {
-	case A:
-		table = opTableA
-	case S:
-		table = opTableS
-	default:
-		panic(fmt.Errorf("unknown mode: %d", m))
-	}
-	op, ok = table[b]
-	return
-}
-
-func showOp(m Mode, op vm.Op) byte {
-	var table map[vm.Op]byte
-	switch m {
-	case A:
-		table = reversedTableA
-	case S:
-		table = reversedTableS
-	default:
-		panic(fmt.Errorf("unknown mode: %d", m))
-	}
-	if b, ok := table[op]; ok {
-		return b
-	}
-	panic(fmt.Errorf("unknown Op: %#v\n", op))
-}
-
 func char(s string) byte {
 	return []byte(s)[0]
 }
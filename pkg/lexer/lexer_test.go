@@ -2,8 +2,11 @@ package lexer
 
 import (
 	"bytes"
+	"errors"
 	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/genkami/watson/pkg/vm"
 
@@ -240,6 +243,30 @@ func TestWriteChangesItsModeWhenHittingSnew(t *testing.T) {
 	}
 }
 
+func TestLookupReturnsOpsDependingOnMode(t *testing.T) {
+	if op, ok := Lookup(A, char("b")); !ok || op != vm.Ishl {
+		t.Fatalf("expected Ishl but got %#v (ok = %t)", op, ok)
+	}
+	if op, ok := Lookup(S, char("b")); !ok || op != vm.Fnan {
+		t.Fatalf("expected Fnan but got %#v (ok = %t)", op, ok)
+	}
+	if _, ok := Lookup(A, char(" ")); ok {
+		t.Fatalf("expected ' ' to be ignored")
+	}
+}
+
+func TestNextModeFlipsOnlyOnSnew(t *testing.T) {
+	if got := NextMode(A, vm.Snew); got != S {
+		t.Fatalf("expected S but got %d", got)
+	}
+	if got := NextMode(S, vm.Snew); got != A {
+		t.Fatalf("expected A but got %d", got)
+	}
+	if got := NextMode(S, vm.Inew); got != S {
+		t.Fatalf("expected S but got %d", got)
+	}
+}
+
 func readOne(s string) (vm.Op, error) {
 	buf := bytes.NewReader([]byte(s))
 	l := NewLexer(buf)
@@ -266,3 +293,208 @@ func readAll(s string) ([]vm.Op, error) {
 		out = append(out, tok.Op)
 	}
 }
+
+func TestNextSkipsUnknownBytesByDefault(t *testing.T) {
+	ops, err := readAll("B x\nu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]vm.Op{vm.Inew, vm.Iinc}, ops); diff != "" {
+		t.Fatalf("expected %#v but got %#v", []vm.Op{vm.Inew, vm.Iinc}, ops)
+	}
+}
+
+func TestStrictLexerRejectsUnknownBytes(t *testing.T) {
+	// 'x' is unknown in mode A, and 'B' is unknown in mode S.
+	l := NewLexer(bytes.NewReader([]byte("B x\n?B")), WithStrict(' ', '\n'), WithFileName("a.watson"))
+	tok, err := l.Next()
+	if err != nil || tok.Op != vm.Inew {
+		t.Fatalf("expected Inew but got %#v (err = %v)", tok, err)
+	}
+	_, err = l.Next()
+	want := &UnknownByteError{Byte: 'x', Mode: A, FileName: "a.watson", Line: 0, Column: 2}
+	var got *UnknownByteError
+	if !errors.As(err, &got) {
+		t.Fatalf("expected UnknownByteError but got %#v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("error mismatch (-want +got):\n%s", diff)
+	}
+	if got.Error() != `unknown byte 'x' in mode A at "a.watson" line 1, column 3` {
+		t.Fatalf("unexpected message: %s", got.Error())
+	}
+
+	// The lexer continues after the error.
+	tok, err = l.Next()
+	if err != nil || tok.Op != vm.Snew {
+		t.Fatalf("expected Snew but got %#v (err = %v)", tok, err)
+	}
+	_, err = l.Next()
+	want = &UnknownByteError{Byte: 'B', Mode: S, FileName: "a.watson", Line: 1, Column: 1}
+	if !errors.As(err, &got) {
+		t.Fatalf("expected UnknownByteError but got %#v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("error mismatch (-want +got):\n%s", diff)
+	}
+	if _, err = l.Next(); err != io.EOF {
+		t.Fatalf("expected EOF but got %v", err)
+	}
+}
+
+func TestNextNReadsTokensInBatches(t *testing.T) {
+	l := NewLexer(bytes.NewReader([]byte("Bu\nb?S")), WithFileName("a.watson"))
+	toks := make([]Token, 3)
+	n, err := l.NextN(toks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Token{
+		{Op: vm.Inew, FileName: "a.watson", Line: 0, Column: 0},
+		{Op: vm.Iinc, FileName: "a.watson", Line: 0, Column: 1},
+		{Op: vm.Ishl, FileName: "a.watson", Line: 1, Column: 0},
+	}
+	if diff := cmp.Diff(want, toks[:n]); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	n, err = l.NextN(toks)
+	if err != io.EOF {
+		t.Fatalf("expected EOF but got %v", err)
+	}
+	want = []Token{
+		{Op: vm.Snew, FileName: "a.watson", Line: 1, Column: 1},
+		{Op: vm.Inew, FileName: "a.watson", Line: 1, Column: 2},
+	}
+	if diff := cmp.Diff(want, toks[:n]); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if n, err = l.NextN(toks); n != 0 || err != io.EOF {
+		t.Fatalf("expected (0, EOF) but got (%d, %v)", n, err)
+	}
+}
+
+func TestReadOpsStopsAtUnknownBytesInStrictMode(t *testing.T) {
+	l := NewLexer(bytes.NewReader([]byte("Bux#")), WithStrict())
+	ops := make([]vm.Op, 10)
+	n, err := l.ReadOps(ops)
+	var ube *UnknownByteError
+	if !errors.As(err, &ube) || ube.Column != 2 {
+		t.Fatalf("expected UnknownByteError at column 2 but got %v", err)
+	}
+	if diff := cmp.Diff([]vm.Op{vm.Inew, vm.Iinc}, ops[:n]); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	n, err = l.ReadOps(ops)
+	if err != io.EOF {
+		t.Fatalf("expected EOF but got %v", err)
+	}
+	if diff := cmp.Diff([]vm.Op{vm.Gpop}, ops[:n]); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLexerTracksPositionsAcrossBufferBoundaries(t *testing.T) {
+	// Each line has a single Inew at its end, and lines are long enough to span several buffers.
+	line := string(bytes.Repeat([]byte(" "), bufferSize/3)) + "B\n"
+	src := strings.Repeat(line, 10)
+	for _, r := range []io.Reader{strings.NewReader(src), iotest.OneByteReader(strings.NewReader(src)), iotest.DataErrReader(strings.NewReader(src))} {
+		l := NewLexer(r)
+		toks := make([]Token, 4)
+		var got []Token
+		for {
+			n, err := l.NextN(toks)
+			got = append(got, toks[:n]...)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(got) != 10 {
+			t.Fatalf("expected 10 tokens but got %d", len(got))
+		}
+		for i, tok := range got {
+			if tok.Line != i || tok.Column != bufferSize/3 {
+				t.Errorf("token %d: expected line %d, column %d but got line %d, column %d", i, i, bufferSize/3, tok.Line, tok.Column)
+			}
+		}
+	}
+}
+
+func TestLexerReturnsErrorsOfReaderAfterConsumingData(t *testing.T) {
+	want := errors.New("broken")
+	l := NewLexer(io.MultiReader(strings.NewReader("Bu"), errReader{want}))
+	ops := make([]vm.Op, 10)
+	n, err := l.ReadOps(ops)
+	if err != want {
+		t.Fatalf("expected %v but got %v", want, err)
+	}
+	if diff := cmp.Diff([]vm.Op{vm.Inew, vm.Iinc}, ops[:n]); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := l.Next(); err != want {
+		t.Fatalf("expected %v but got %v", want, err)
+	}
+}
+
+func TestLexerGivesUpOnReadersThatMakeNoProgress(t *testing.T) {
+	l := NewLexer(emptyReader{})
+	if _, err := l.Next(); err != io.ErrNoProgress {
+		t.Fatalf("expected ErrNoProgress but got %v", err)
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+type emptyReader struct{}
+
+func (emptyReader) Read([]byte) (int, error) {
+	return 0, nil
+}
+
+// benchmarkInput is about 4 MiB of Watson Representation that contains Ops in both modes.
+var benchmarkInput = bytes.Repeat([]byte("BBuaBubaBubbbaBubbbbaBubbbbbaBubbbbbbaBubbbbbbbaBubbbbbbbba\n?SShkShaaakShaaaakShaaaaak-\n$BBuak-@"), 1<<15)
+
+func BenchmarkNext(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkInput)))
+	for i := 0; i < b.N; i++ {
+		l := NewLexer(bytes.NewReader(benchmarkInput))
+		for {
+			if _, err := l.Next(); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkNextN(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkInput)))
+	toks := make([]Token, 1024)
+	for i := 0; i < b.N; i++ {
+		l := NewLexer(bytes.NewReader(benchmarkInput))
+		for {
+			if _, err := l.NextN(toks); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkReadOps(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkInput)))
+	ops := make([]vm.Op, 1024)
+	for i := 0; i < b.N; i++ {
+		l := NewLexer(bytes.NewReader(benchmarkInput))
+		for {
+			if _, err := l.ReadOps(ops); err != nil {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,423 @@
+// Package lint finds common mistakes in Watson Representation.
+//
+// Lint executes its input on a VM, and reports the following mistakes as Diagnostics. Each of them can be disabled.
+//   * MultipleValues: more than one value is left on the stack at the end of the input. Only the top of them is decoded.
+//   * PushThenPop: a value is pushed and then discarded by Gpop right after that.
+//     Snew Gpop is not reported since it is the way to flip the mode.
+//   * NoOpNegation: a pair of Ineg, Fneg or Bneg that does nothing.
+//   * MaskedCharacter: Sadd appends an integer outside 0-255, which is silently masked.
+//   * DuplicateKey: Oadd overwrites a key that has already been added to the Object.
+//   * EndsInModeS: the input ends in mode S, so anything appended to it is read in mode S.
+//     Note that the dumper leaves mode S if it writes an odd number of Strings.
+//   * VMError: the VM fails to execute an Op. Nothing after that is executed.
+//
+// Decorations written by the prettifier, and the ones that are common in hand-written Watson such as "Sharrk" and "Samee",
+// are valid Watson even though they consist of Ops that do nothing, so they are not reported.
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/sourcemap"
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+// Rule is a name of a kind of mistakes.
+type Rule string
+
+const (
+	MultipleValues  Rule = "multiple-values"
+	PushThenPop     Rule = "push-then-pop"
+	NoOpNegation    Rule = "no-op-negation"
+	MaskedCharacter Rule = "masked-character"
+	DuplicateKey    Rule = "duplicate-key"
+	EndsInModeS     Rule = "ends-in-mode-s"
+	VMError         Rule = "vm-error"
+)
+
+var descriptions = map[Rule]string{
+	MultipleValues:  "more than one value is left on the stack at the end of the input",
+	PushThenPop:     "a value is pushed and immediately discarded by Gpop",
+	NoOpNegation:    "a pair of Ineg, Fneg or Bneg that does nothing",
+	MaskedCharacter: "Sadd appends an integer outside 0-255, which is masked",
+	DuplicateKey:    "Oadd overwrites a key that has already been added",
+	EndsInModeS:     "the input ends in mode S",
+	VMError:         "the VM fails to execute an Op",
+}
+
+// Rules returns all Rules in the sorted order.
+func Rules() []Rule {
+	rules := make([]Rule, 0, len(descriptions))
+	for r := range descriptions {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i] < rules[j]
+	})
+	return rules
+}
+
+// Description returns a short description of r, or an empty string if r is unknown.
+func (r Rule) Description() string {
+	return descriptions[r]
+}
+
+// Diagnostic is a mistake found at Pos.
+type Diagnostic struct {
+	Rule    Rule
+	Pos     sourcemap.Position
+	Message string
+}
+
+// String returns the Diagnostic in the form of "file:line:column: message (rule)".
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s (%s)", d.Pos.String(), d.Message, d.Rule)
+}
+
+// MarshalJSON encodes the Diagnostic into an Object that has "file", "line", "column", "rule" and "message".
+// Like String, its line and column are one-based.
+func (d *Diagnostic) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		File    string `json:"file"`
+		Line    int    `json:"line"`
+		Column  int    `json:"column"`
+		Rule    Rule   `json:"rule"`
+		Message string `json:"message"`
+	}{
+		File:    d.Pos.FileName,
+		Line:    d.Pos.Line + 1,
+		Column:  d.Pos.Column + 1,
+		Rule:    d.Rule,
+		Message: d.Message,
+	})
+}
+
+// Option configures Lint.
+type Option interface {
+	apply(*config)
+}
+
+type config struct {
+	mode      lexer.Mode
+	stackSize int
+	fileName  string
+	only      []Rule
+	disabled  []Rule
+}
+
+type option func(*config)
+
+func (opt option) apply(c *config) {
+	opt(c)
+}
+
+// WithInitialMode sets the initial mode of the lexer. The default is lexer.A.
+func WithInitialMode(mode lexer.Mode) Option {
+	return option(func(c *config) {
+		c.mode = mode
+	})
+}
+
+// WithStackSize sets the stack size of the VM. The default is vm.DefaultStackSize.
+func WithStackSize(size int) Option {
+	return option(func(c *config) {
+		c.stackSize = size
+	})
+}
+
+// WithFileName sets the file name of Diagnostics.
+func WithFileName(name string) Option {
+	return option(func(c *config) {
+		c.fileName = name
+	})
+}
+
+// Only disables all Rules but rules.
+func Only(rules ...Rule) Option {
+	return option(func(c *config) {
+		c.only = append(c.only, rules...)
+	})
+}
+
+// Disable disables rules.
+func Disable(rules ...Rule) Option {
+	return option(func(c *config) {
+		c.disabled = append(c.disabled, rules...)
+	})
+}
+
+// enabledRules returns a set of Rules that are enabled by c, or an error if c has an unknown Rule.
+func (c *config) enabledRules() (map[Rule]bool, error) {
+	enabled := map[Rule]bool{}
+	for r := range descriptions {
+		enabled[r] = c.only == nil
+	}
+	for _, r := range c.only {
+		if _, ok := descriptions[r]; !ok {
+			return nil, fmt.Errorf("unknown rule: %s", r)
+		}
+		enabled[r] = true
+	}
+	for _, r := range c.disabled {
+		if _, ok := descriptions[r]; !ok {
+			return nil, fmt.Errorf("unknown rule: %s", r)
+		}
+		enabled[r] = false
+	}
+	return enabled, nil
+}
+
+// Lint reads Watson Representation from r, and returns Diagnostics in the order of their positions.
+// It returns an error if it fails to read r; mistakes in the input, including the ones that make the VM fail, are reported as Diagnostics.
+func Lint(r io.Reader, opts ...Option) ([]*Diagnostic, error) {
+	c := &config{mode: lexer.A, stackSize: vm.DefaultStackSize}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	enabled, err := c.enabledRules()
+	if err != nil {
+		return nil, err
+	}
+	l := &linter{enabled: enabled, fileName: c.fileName}
+	if err := l.lex(r, c); err != nil {
+		return nil, err
+	}
+	l.execute(c)
+	l.checkOps()
+	l.checkMode()
+	sort.SliceStable(l.diags, func(i, j int) bool {
+		p, q := l.diags[i].Pos, l.diags[j].Pos
+		return p.Line < q.Line || (p.Line == q.Line && p.Column < q.Column)
+	})
+	return l.diags, nil
+}
+
+// LintBytes is the same as Lint except that it reads Watson Representation from src.
+func LintBytes(src []byte, opts ...Option) ([]*Diagnostic, error) {
+	return Lint(bytes.NewReader(src), opts...)
+}
+
+type linter struct {
+	enabled  map[Rule]bool
+	fileName string
+	toks     []lexer.Token
+	// modes[i] is the mode of the lexer right before it yields toks[i].
+	modes []lexer.Mode
+	// mode is the mode of the lexer at the end of the input.
+	mode lexer.Mode
+	// cur is the index of the token that is being executed.
+	cur int
+	// starts are the positions of the first tokens of the values on the stack.
+	starts []sourcemap.Position
+	diags  []*Diagnostic
+}
+
+func (l *linter) report(rule Rule, pos sourcemap.Position, format string, args ...interface{}) {
+	if !l.enabled[rule] {
+		return
+	}
+	l.diags = append(l.diags, &Diagnostic{Rule: rule, Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) pos(i int) sourcemap.Position {
+	tok := &l.toks[i]
+	return sourcemap.Position{FileName: tok.FileName, Line: tok.Line, Column: tok.Column}
+}
+
+func (l *linter) lex(r io.Reader, c *config) error {
+	lex := lexer.NewLexer(r, lexer.WithInitialLexerMode(c.mode), lexer.WithFileName(c.fileName))
+	mode := c.mode
+	for {
+		tok, err := lex.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		l.toks = append(l.toks, *tok)
+		l.modes = append(l.modes, mode)
+		mode = lexer.NextMode(mode, tok.Op)
+	}
+	l.mode = mode
+	return nil
+}
+
+func (l *linter) execute(c *config) {
+	m := vm.NewVM(vm.WithStackSize(c.stackSize), vm.WithTracer(l))
+	for i := range l.toks {
+		l.cur = i
+		if err := m.Feed(l.toks[i].Op); err != nil {
+			l.report(VMError, l.pos(i), "%s: %s", l.toks[i].Op.GoString(), err.Error())
+			return
+		}
+	}
+	for _, p := range l.starts[:max(len(l.starts)-1, 0)] {
+		l.report(MultipleValues, p, "this value is left on the stack and discarded since only the last value is decoded")
+	}
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func (l *linter) BeforeOp(op vm.Op, stack []*types.Value) {
+	switch op {
+	case vm.Sadd:
+		if len(stack) < 1 {
+			return
+		}
+		top := stack[len(stack)-1]
+		if n := top.Int(); top.Kind == types.Int && (n < 0 || n > 255) {
+			l.report(MaskedCharacter, l.pos(l.cur), "character %d is out of range 0-255 and is masked to %d", n, byte(n))
+		}
+	case vm.Oadd:
+		if len(stack) < 3 {
+			return
+		}
+		o, k := stack[len(stack)-3], stack[len(stack)-2]
+		if o.Kind != types.Object || k.Kind != types.String {
+			return
+		}
+		if _, ok := o.Object()[string(k.Bytes())]; ok {
+			l.report(DuplicateKey, l.pos(l.cur), "key %q overwrites the one that has already been added", k.Bytes())
+		}
+	}
+}
+
+func (l *linter) AfterOp(op vm.Op, stack []*types.Value, err error) {
+	if err != nil {
+		return
+	}
+	switch op {
+	case vm.Inew, vm.Finf, vm.Fnan, vm.Snew, vm.Onew, vm.Anew, vm.Bnew, vm.Nnew:
+		l.starts = append(l.starts, l.pos(l.cur))
+	case vm.Gdup:
+		l.starts = append(l.starts, l.starts[len(l.starts)-1])
+	case vm.Iadd, vm.Isht, vm.Sadd, vm.Aadd, vm.Gpop:
+		l.starts = l.starts[:len(l.starts)-1]
+	case vm.Oadd:
+		l.starts = l.starts[:len(l.starts)-2]
+	case vm.Gswp:
+		n := len(l.starts)
+		l.starts[n-1], l.starts[n-2] = l.starts[n-2], l.starts[n-1]
+	}
+}
+
+var _ vm.Tracer = &linter{}
+
+func isPush(op vm.Op) bool {
+	switch op {
+	case vm.Inew, vm.Finf, vm.Fnan, vm.Snew, vm.Onew, vm.Anew, vm.Bnew, vm.Nnew, vm.Gdup:
+		return true
+	}
+	return false
+}
+
+// checkOps reports the sequences of Ops that do nothing.
+func (l *linter) checkOps() {
+	for i := 0; i < len(l.toks); i++ {
+		op := l.toks[i].Op
+		switch {
+		case op == vm.Gpop && i > 0 && isPush(l.toks[i-1].Op) && l.toks[i-1].Op != vm.Snew && !l.prettified(i-1):
+			l.report(PushThenPop, l.pos(i-1), "the value pushed by %s is discarded by Gpop right after that", l.toks[i-1].Op.GoString())
+		case (op == vm.Ineg || op == vm.Fneg || op == vm.Bneg) && l.matches(i, l.modes[i], op, op):
+			if l.prettified(i) {
+				i++
+			} else if !l.prettified(i + 1) {
+				// The pair that starts at toks[i+1] may be a decoration that follows a meaningful negation.
+				l.report(NoOpNegation, l.pos(i), "%s %s does nothing", op.GoString(), op.GoString())
+				i++
+			}
+		}
+	}
+}
+
+// matches reports whether the tokens from toks[i] are ops, and the mode before toks[i] is mode.
+func (l *linter) matches(i int, mode lexer.Mode, ops ...vm.Op) bool {
+	if i < 0 || i+len(ops) > len(l.toks) || l.modes[i] != mode {
+		return false
+	}
+	for j, op := range ops {
+		if l.toks[i+j].Op != op {
+			return false
+		}
+	}
+	return true
+}
+
+// prettified reports whether toks[i] is a part of decorations, that is:
+//   * Bnew [Bneg Bneg] Oadd in mode A, which is written by the prettifier.
+//     Any number of pairs of Bneg between Bnew and Oadd, such as "^!!!!g", are also decorations in both modes.
+//   * (an Op that leaves an Int) [Ineg Ineg] Oadd [Gdup Gpop] in mode A, which is written by the prettifier.
+//   * Iinc [Ineg Ineg], Ishl [Ineg Ineg] and Isht [Ineg Ineg] followed by Iadd or Inew in mode S,
+//     which are written by the prettifier as "Sharrk".
+//   * [Inew Ishl Finf Gpop Gpop] in mode S, which is written by the prettifier as "Samee".
+// where the Ops in brackets are decorations.
+func (l *linter) prettified(i int) bool {
+	op := l.toks[i].Op
+	prev := vm.Op(-1)
+	if i > 0 {
+		prev = l.toks[i-1].Op
+	}
+	switch op {
+	case vm.Bneg:
+		return l.inExclamations(i)
+	case vm.Ineg:
+		if topIsInt(prev) && l.matches(i, lexer.A, vm.Ineg, vm.Ineg, vm.Oadd, vm.Gdup, vm.Gpop) {
+			return true
+		}
+		if prev != vm.Iinc && prev != vm.Ishl && prev != vm.Isht {
+			return false
+		}
+		return l.matches(i, lexer.S, vm.Ineg, vm.Ineg, vm.Iadd) || l.matches(i, lexer.S, vm.Ineg, vm.Ineg, vm.Inew)
+	case vm.Gdup:
+		return l.matches(i-3, lexer.A, vm.Ineg, vm.Ineg, vm.Oadd, vm.Gdup, vm.Gpop)
+	case vm.Finf:
+		return l.matches(i-2, lexer.S, vm.Inew, vm.Ishl, vm.Finf, vm.Gpop, vm.Gpop)
+	}
+	return false
+}
+
+// inExclamations reports whether toks[i] is in a run of Bneg that starts right after Bnew and ends right before Oadd.
+func (l *linter) inExclamations(i int) bool {
+	start := i
+	for start > 0 && l.toks[start-1].Op == vm.Bneg {
+		start--
+	}
+	end := i
+	for end < len(l.toks) && l.toks[end].Op == vm.Bneg {
+		end++
+	}
+	return start > 0 && l.toks[start-1].Op == vm.Bnew && end < len(l.toks) && l.toks[end].Op == vm.Oadd
+}
+
+func topIsInt(op vm.Op) bool {
+	switch op {
+	case vm.Inew, vm.Iinc, vm.Ishl, vm.Iadd, vm.Ineg, vm.Isht:
+		return true
+	}
+	return false
+}
+
+// checkMode reports the input that ends in mode S.
+func (l *linter) checkMode() {
+	if l.mode != lexer.S {
+		return
+	}
+	for i := len(l.toks) - 1; i >= 0; i-- {
+		if l.modes[i] != l.mode {
+			l.report(EndsInModeS, l.pos(i), "the input ends in mode S since this %s", l.toks[i].Op.GoString())
+			return
+		}
+	}
+	l.report(EndsInModeS, sourcemap.Position{FileName: l.fileName}, "the input ends in mode S")
+}
@@ -0,0 +1,222 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/dumper"
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/prettifier"
+	"github.com/genkami/watson/pkg/sourcemap"
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+func dumpOps(t *testing.T, v *types.Value) []vm.Op {
+	t.Helper()
+	w := lexer.NewSliceWriter()
+	if err := dumper.NewDumper(w).Dump(v); err != nil {
+		t.Fatal(err)
+	}
+	return w.Ops()
+}
+
+// watsonLines encodes each element of lines into a separate line.
+func watsonLines(t *testing.T, lines ...[]vm.Op) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	u := lexer.NewUnlexer(buf)
+	for _, line := range lines {
+		for _, op := range line {
+			if err := u.Write(op); err != nil {
+				t.Fatal(err)
+			}
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+func concat(groups ...[]vm.Op) []vm.Op {
+	var ops []vm.Op
+	for _, g := range groups {
+		ops = append(ops, g...)
+	}
+	return ops
+}
+
+type found struct {
+	Rule   Rule
+	Line   int
+	Column int
+}
+
+func lint(t *testing.T, src []byte, opts ...Option) []found {
+	t.Helper()
+	diags, err := LintBytes(src, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fs []found
+	for _, d := range diags {
+		fs = append(fs, found{Rule: d.Rule, Line: d.Pos.Line, Column: d.Pos.Column})
+	}
+	return fs
+}
+
+func TestLintReportsNothingInPrettifiedOutput(t *testing.T) {
+	v := types.NewObjectValue(map[string]*types.Value{
+		"name":  types.NewStringValue([]byte("app")),
+		"port":  types.NewIntValue(8080),
+		"neg":   types.NewIntValue(-42),
+		"big":   types.NewUintValue(math.MaxUint64),
+		"ratio": types.NewFloatValue(-1.5),
+		"on":    types.NewBoolValue(true),
+		"off":   types.NewBoolValue(false),
+		"none":  types.NewNilValue(),
+		"tags":  types.NewArrayValue([]*types.Value{types.NewStringValue([]byte("a")), types.NewIntValue(-1)}),
+		"spec":  types.NewObjectValue(map[string]*types.Value{"replicas": types.NewIntValue(3)}),
+	})
+	buf := &bytes.Buffer{}
+	if err := dumper.NewDumper(prettifier.NewPrettifier(lexer.NewUnlexer(buf))).Dump(v); err != nil {
+		t.Fatal(err)
+	}
+	// The output of the dumper ends in mode S if it has an odd number of strings.
+	if fs := lint(t, buf.Bytes(), Disable(EndsInModeS)); len(fs) != 0 {
+		t.Errorf("expected no diagnostics but got %#v in %s", fs, buf.String())
+	}
+}
+
+func TestLintReportsMistakes(t *testing.T) {
+	test := func(rule Rule, src []byte, want ...found) {
+		t.Helper()
+		if diff := cmp.Diff(want, lint(t, src, Only(rule))); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", rule, diff)
+		}
+	}
+	one := dumpOps(t, types.NewIntValue(1))
+	test(MultipleValues,
+		watsonLines(t, one, one, one),
+		found{MultipleValues, 0, 0}, found{MultipleValues, 1, 0})
+	test(PushThenPop,
+		watsonLines(t, []vm.Op{vm.Bnew, vm.Nnew, vm.Gpop}, []vm.Op{vm.Gdup, vm.Gpop, vm.Gdup, vm.Gswp, vm.Gpop}),
+		found{PushThenPop, 0, 1}, found{PushThenPop, 1, 0})
+	test(NoOpNegation,
+		watsonLines(t, concat(one, []vm.Op{vm.Ineg, vm.Ineg, vm.Ineg, vm.Gpop}), []vm.Op{vm.Bnew, vm.Bneg, vm.Bneg}),
+		found{NoOpNegation, 0, len(one)}, found{NoOpNegation, 1, 1})
+	test(MaskedCharacter,
+		watsonLines(t, concat([]vm.Op{vm.Snew}, dumpOps(t, types.NewIntValue(256)), []vm.Op{vm.Sadd}, dumpOps(t, types.NewIntValue(255)), []vm.Op{vm.Sadd})),
+		found{MaskedCharacter, 0, 1 + len(dumpOps(t, types.NewIntValue(256)))})
+	key := dumpOps(t, types.NewStringValue([]byte("a")))
+	test(DuplicateKey,
+		watsonLines(t, []vm.Op{vm.Onew}, concat(key, one, []vm.Op{vm.Oadd}), concat(key, one, []vm.Op{vm.Oadd})),
+		found{DuplicateKey, 2, len(key) + len(one)})
+	test(EndsInModeS,
+		watsonLines(t, []vm.Op{vm.Snew, vm.Gpop, vm.Snew}, []vm.Op{vm.Gpop, vm.Snew, vm.Nnew}),
+		found{EndsInModeS, 1, 1})
+	test(VMError,
+		watsonLines(t, one, []vm.Op{vm.Sadd, vm.Gpop}),
+		found{VMError, 1, 0})
+}
+
+func TestLintKeepsNegationsBeforeDecorations(t *testing.T) {
+	// A negative Int followed by the decorations of the prettifier: Ineg [Ineg Ineg] Oadd [Gdup Gpop].
+	// It starts in mode S so that the value is written in mode A after the key.
+	buf := &bytes.Buffer{}
+	u := lexer.NewUnlexer(buf, lexer.WithInitialUnlexerMode(lexer.S))
+	ops := concat([]vm.Op{vm.Onew}, dumpOps(t, types.NewStringValue([]byte("a"))),
+		[]vm.Op{vm.Inew, vm.Iinc, vm.Ineg, vm.Ineg, vm.Ineg, vm.Oadd, vm.Gdup, vm.Gpop})
+	for _, op := range ops {
+		if err := u.Write(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fs := lint(t, buf.Bytes(), WithInitialMode(lexer.S)); len(fs) != 0 {
+		t.Errorf("expected no diagnostics but got %#v", fs)
+	}
+	if fs := lint(t, buf.Bytes()[:buf.Len()-2], WithInitialMode(lexer.S)); len(fs) != 1 || fs[0].Rule != NoOpNegation {
+		t.Errorf("expected a pair of Ineg without Gdup Gpop to be reported but got %#v", fs)
+	}
+}
+
+func TestLintReportsNothingInHandWrittenDecorations(t *testing.T) {
+	// "Sharrk", "Samee" and "^!!!g" in mode S. Note that "^!!!g" has a meaningful Bneg as well as decorations.
+	src := []byte("~?SameeShaaaaaarrShaaaaarrkShrrk-^!!!g")
+	if fs := lint(t, src, Disable(EndsInModeS)); len(fs) != 0 {
+		t.Errorf("expected no diagnostics but got %#v", fs)
+	}
+	// "rr" that is not followed by a character is not a decoration.
+	src = []byte("~?Shaarr-")
+	if diff := cmp.Diff([]found{{NoOpNegation, 0, 6}}, lint(t, src, Only(NoOpNegation))); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLintEnablesAndDisablesRules(t *testing.T) {
+	src := watsonLines(t, []vm.Op{vm.Nnew, vm.Gpop, vm.Bnew, vm.Bneg, vm.Bneg})
+	if diff := cmp.Diff([]found{{PushThenPop, 0, 0}, {NoOpNegation, 0, 3}}, lint(t, src)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]found{{NoOpNegation, 0, 3}}, lint(t, src, Disable(PushThenPop))); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]found{{PushThenPop, 0, 0}}, lint(t, src, Only(PushThenPop, NoOpNegation), Disable(NoOpNegation))); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := LintBytes(src, Disable("no-such-rule")); err == nil {
+		t.Errorf("expected an error but got nil")
+	}
+}
+
+func TestLintReportsNothingInExamples(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "..", "examples", "*.watson"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no examples found")
+	}
+	for _, path := range paths {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The examples end in mode S, as the output of the dumper does.
+		if fs := lint(t, src, Disable(EndsInModeS)); len(fs) != 0 {
+			t.Errorf("%s: expected no diagnostics but got %#v", path, fs)
+		}
+	}
+}
+
+func TestDiagnosticFormats(t *testing.T) {
+	d := &Diagnostic{
+		Rule:    DuplicateKey,
+		Pos:     sourcemap.Position{FileName: "a.watson", Line: 1, Column: 2},
+		Message: "key \"a\" overwrites the one that has already been added",
+	}
+	if s := d.String(); s != `a.watson:2:3: key "a" overwrites the one that has already been added (duplicate-key)` {
+		t.Errorf("unexpected string: %s", s)
+	}
+	got, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"file":"a.watson","line":2,"column":3,"rule":"duplicate-key","message":"key \"a\" overwrites the one that has already been added"}`
+	if string(got) != want {
+		t.Errorf("expected %s but got %s", want, got)
+	}
+}
+
+func TestRulesHaveDescriptions(t *testing.T) {
+	for _, r := range Rules() {
+		if r.Description() == "" {
+			t.Errorf("%s has no description", r)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package lsp
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+const (
+	// maxStackEntries is the maximum number of values in the stack that a hover shows.
+	maxStackEntries = 16
+	// maxValueLength is the maximum length of a value shown in hovers and inlay hints.
+	maxValueLength = 80
+	// checkpointInterval is the number of tokens between two checkpoints of the VM.
+	checkpointInterval = 1024
+)
+
+// token is a lexer.Token with the mode that it is read in.
+type token struct {
+	*lexer.Token
+	mode lexer.Mode
+}
+
+// document is an analyzed Watson file.
+type document struct {
+	lines  []string
+	tokens []*token
+	// errIndex is the index of the token that the VM failed to execute, or -1 if the VM succeeded.
+	errIndex int
+	err      error
+	hints    []InlayHint
+	// checkpoints[k] is the snapshot of the VM right before executing the (k * checkpointInterval)-th token.
+	checkpoints []*vm.Snapshot
+}
+
+// analyze lexes the whole text and executes it up to the first error.
+func analyze(text string, c *config) *document {
+	d := &document{
+		lines:    strings.Split(text, "\n"),
+		errIndex: -1,
+	}
+	lex := lexer.NewLexer(strings.NewReader(text), lexer.WithInitialLexerMode(c.mode))
+	for {
+		mode := lex.Mode()
+		tok, err := lex.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			// strings.Reader never fails.
+			panic(err)
+		}
+		d.tokens = append(d.tokens, &token{Token: tok, mode: mode})
+	}
+	m := vm.NewVM(vm.WithStackSize(c.stackSize))
+	run := &literalRun{}
+	for i, tok := range d.tokens {
+		if run.active() && !(run.accepts(tok.Op) && (tok.Op != vm.Inew || d.buildsCharacter(i))) {
+			d.endRun(m, run, i-1)
+		}
+		if !run.active() {
+			run.start(tok.Op)
+		}
+		if i%checkpointInterval == 0 {
+			d.checkpoints = append(d.checkpoints, m.Snapshot())
+		}
+		err := m.Feed(tok.Op)
+		if err != nil {
+			// Tokens after the error are not executed, but they are kept so that they can be hovered.
+			d.errIndex = i
+			d.err = err
+			run.kind = noRun
+			break
+		}
+		if run.kind == intRun && (tok.Op == vm.Itou || tok.Op == vm.Itof) {
+			d.endRun(m, run, i)
+		}
+	}
+	if run.active() {
+		d.endRun(m, run, len(d.tokens)-1)
+	}
+	return d
+}
+
+type runKind int
+
+const (
+	noRun runKind = iota
+	intRun
+	stringRun
+)
+
+// literalRun is a sequence of consecutive tokens that builds an integer or a string literal, such as `Bubua` or `?Bu!`.
+// An integer run may end with Itou or Itof.
+type literalRun struct {
+	kind runKind
+}
+
+func (r *literalRun) active() bool {
+	return r.kind != noRun
+}
+
+func (r *literalRun) start(op vm.Op) {
+	switch op {
+	case vm.Inew:
+		r.kind = intRun
+	case vm.Snew:
+		r.kind = stringRun
+	}
+}
+
+func (r *literalRun) accepts(op vm.Op) bool {
+	switch op {
+	case vm.Inew:
+		// Inew starts another integer unless it starts a character of a string, which is checked by buildsCharacter.
+		return r.kind == stringRun
+	case vm.Iinc, vm.Ishl, vm.Iadd, vm.Ineg, vm.Isht:
+		return true
+	case vm.Itou, vm.Itof:
+		return r.kind == intRun
+	case vm.Sadd:
+		return r.kind == stringRun
+	default:
+		return false
+	}
+}
+
+// buildsCharacter reports whether the integer that starts at the i-th token is appended to a string by Sadd right after it is built.
+// Otherwise the integer is a value of its own, e.g. the value that follows a key.
+func (d *document) buildsCharacter(i int) bool {
+	for _, tok := range d.tokens[i+1:] {
+		switch tok.Op {
+		case vm.Iinc, vm.Ishl, vm.Iadd, vm.Ineg, vm.Isht:
+		case vm.Sadd:
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// endRun adds an inlay hint that shows the value built by run, which ends at the token last.
+func (d *document) endRun(m *vm.VM, run *literalRun, last int) {
+	kind := run.kind
+	run.kind = noRun
+	top, err := m.Top()
+	if err != nil {
+		return
+	}
+	switch {
+	case kind == intRun && (top.Kind == types.Int || top.Kind == types.Uint || top.Kind == types.Float):
+	case kind == stringRun && top.Kind == types.String:
+	default:
+		return
+	}
+	tok := d.tokens[last]
+	d.hints = append(d.hints, InlayHint{
+		Position:    d.position(tok.Line, tok.Column+1),
+		Label:       showValue(top),
+		PaddingLeft: true,
+	})
+}
+
+// diagnostics returns errors in d.
+func (d *document) diagnostics() []Diagnostic {
+	diags := make([]Diagnostic, 0)
+	if d.errIndex < 0 {
+		return diags
+	}
+	tok := d.tokens[d.errIndex]
+	return append(diags, Diagnostic{
+		Range:    d.tokenRange(tok),
+		Severity: SeverityError,
+		Source:   "watson",
+		Message:  fmt.Sprintf("%#v: %s", tok.Op, d.err.Error()),
+	})
+}
+
+// inlayHints returns inlay hints in r.
+func (d *document) inlayHints(r Range) []InlayHint {
+	hints := make([]InlayHint, 0)
+	for _, h := range d.hints {
+		if r.contains(h.Position) {
+			hints = append(hints, h)
+		}
+	}
+	return hints
+}
+
+// hover describes the token at p and the stack right after executing it. It returns nil if there's no token at p.
+func (d *document) hover(p Position, c *config) *Hover {
+	i := d.tokenAt(p)
+	if i < 0 {
+		return nil
+	}
+	tok := d.tokens[i]
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "**%#v** (mode %s)\n\n", tok.Op, modeName(tok.mode))
+	switch {
+	case d.errIndex >= 0 && i > d.errIndex:
+		errTok := d.tokens[d.errIndex]
+		fmt.Fprintf(b, "not executed: the VM stopped at line %d, column %d", errTok.Line+1, errTok.Column+1)
+	case i == d.errIndex:
+		fmt.Fprintf(b, "error: %s", d.err.Error())
+	default:
+		writeStack(b, d.stackAfter(i, c))
+	}
+	r := d.tokenRange(tok)
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: b.String()},
+		Range:    &r,
+	}
+}
+
+// stackAfter returns the stack right after executing the i-th token by re-executing the document from the last checkpoint before it.
+func (d *document) stackAfter(i int, c *config) []*types.Value {
+	m := vm.NewVM(vm.WithStackSize(c.stackSize))
+	k := i / checkpointInterval
+	if err := m.Restore(d.checkpoints[k]); err != nil {
+		// This doesn't happen since the checkpoint is taken from a VM of the same size.
+		panic(err)
+	}
+	for _, tok := range d.tokens[k*checkpointInterval : i+1] {
+		if err := m.Feed(tok.Op); err != nil {
+			// This doesn't happen since the document is known to be executed successfully up to i.
+			panic(err)
+		}
+	}
+	return m.Stack()
+}
+
+func writeStack(b *strings.Builder, stack []*types.Value) {
+	if len(stack) == 0 {
+		b.WriteString("stack is empty")
+		return
+	}
+	b.WriteString("stack (top first):\n\n```\n")
+	for n := 0; n < len(stack) && n < maxStackEntries; n++ {
+		v := stack[len(stack)-1-n]
+		fmt.Fprintf(b, "%#v: %s\n", v.Kind, showValue(v))
+	}
+	if len(stack) > maxStackEntries {
+		fmt.Fprintf(b, "... and %d more\n", len(stack)-maxStackEntries)
+	}
+	b.WriteString("```")
+}
+
+// tokenAt returns the index of the token at p, or -1 if there's no such token.
+func (d *document) tokenAt(p Position) int {
+	col := d.byteColumn(p)
+	i := sort.Search(len(d.tokens), func(i int) bool {
+		tok := d.tokens[i]
+		return tok.Line > p.Line || (tok.Line == p.Line && tok.Column >= col)
+	})
+	if i < len(d.tokens) && d.tokens[i].Line == p.Line && d.tokens[i].Column == col {
+		return i
+	}
+	return -1
+}
+
+func (d *document) tokenRange(tok *token) Range {
+	return Range{
+		Start: d.position(tok.Line, tok.Column),
+		End:   d.position(tok.Line, tok.Column+1),
+	}
+}
+
+// position converts a column in bytes into a Position, whose column is in UTF-16 code units.
+func (d *document) position(line, col int) Position {
+	if line >= len(d.lines) {
+		return Position{Line: line, Character: col}
+	}
+	s := d.lines[line]
+	if col > len(s) {
+		col = len(s)
+	}
+	n := 0
+	for _, r := range s[:col] {
+		n += utf16.RuneLen(r)
+	}
+	return Position{Line: line, Character: n}
+}
+
+// byteColumn converts the column of p into a column in bytes.
+func (d *document) byteColumn(p Position) int {
+	if p.Line < 0 || p.Line >= len(d.lines) {
+		return -1
+	}
+	s := d.lines[p.Line]
+	n := 0
+	for i, r := range s {
+		if n >= p.Character {
+			return i
+		}
+		n += utf16.RuneLen(r)
+	}
+	return len(s)
+}
+
+func modeName(m lexer.Mode) string {
+	switch m {
+	case lexer.A:
+		return "A"
+	case lexer.S:
+		return "S"
+	default:
+		panic(fmt.Errorf("unknown mode: %d", m))
+	}
+}
+
+// showValue returns a short representation of v, e.g. `{"a": [1, 2u, 3.5]}`.
+func showValue(v *types.Value) string {
+	b := &strings.Builder{}
+	writeValue(b, v)
+	s := b.String()
+	if len(s) > maxValueLength {
+		s = s[:maxValueLength] + "..."
+	}
+	return s
+}
+
+func writeValue(b *strings.Builder, v *types.Value) {
+	if b.Len() > maxValueLength {
+		return
+	}
+	switch v.Kind {
+	case types.Int:
+		b.WriteString(strconv.FormatInt(v.Int(), 10))
+	case types.Uint:
+		b.WriteString(strconv.FormatUint(v.Uint(), 10) + "u")
+	case types.Float:
+		b.WriteString(showFloat(v.Float()))
+	case types.String:
+		b.WriteString(strconv.Quote(string(v.Bytes())))
+	case types.Object:
+		keys := make([]string, 0, len(v.Object()))
+		for k := range v.Object() {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString("{")
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(strconv.Quote(k) + ": ")
+			writeValue(b, v.Object()[k])
+			if b.Len() > maxValueLength {
+				return
+			}
+		}
+		b.WriteString("}")
+	case types.Array:
+		b.WriteString("[")
+		for i, elem := range v.Array() {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeValue(b, elem)
+			if b.Len() > maxValueLength {
+				return
+			}
+		}
+		b.WriteString("]")
+	case types.Bool:
+		b.WriteString(strconv.FormatBool(v.Bool()))
+	case types.Nil:
+		b.WriteString("nil")
+	default:
+		panic(fmt.Errorf("invalid kind: %d", v.Kind))
+	}
+}
+
+// showFloat formats f so that it can be distinguished from integers.
+func showFloat(f float64) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
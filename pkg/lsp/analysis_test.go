@@ -0,0 +1,50 @@
+package lsp
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func TestShowValue(t *testing.T) {
+	cases := []struct {
+		v    *types.Value
+		want string
+	}{
+		{types.NewIntValue(-3), "-3"},
+		{types.NewUintValue(3), "3u"},
+		{types.NewFloatValue(3), "3.0"},
+		{types.NewFloatValue(math.Inf(-1)), "-Inf"},
+		{types.NewStringValue([]byte("a\n")), `"a\n"`},
+		{types.NewObjectValue(map[string]*types.Value{
+			"b": types.NewArrayValue([]*types.Value{types.NewBoolValue(true), types.NewNilValue()}),
+			"a": types.NewObjectValue(map[string]*types.Value{}),
+		}), `{"a": {}, "b": [true, nil]}`},
+		{types.NewStringValue([]byte(strings.Repeat("x", 100))), `"` + strings.Repeat("x", maxValueLength-1) + "..."},
+	}
+	for _, c := range cases {
+		if got := showValue(c.v); got != c.want {
+			t.Errorf("expected %s, got %s", c.want, got)
+		}
+	}
+}
+
+func TestStackAfterUsesCheckpoints(t *testing.T) {
+	// An array of integers that spans several checkpoints.
+	text := "@" + strings.Repeat("Bus", checkpointInterval)
+	d := analyze(text, &config{stackSize: 16})
+	if len(d.checkpoints) != 4 {
+		t.Fatalf("expected 4 checkpoints, got %d", len(d.checkpoints))
+	}
+	for _, i := range []int{0, checkpointInterval - 1, checkpointInterval, 2*checkpointInterval + 1, len(d.tokens) - 1} {
+		stack := d.stackAfter(i, &config{stackSize: 16})
+		// Tokens other than the first one are (Inew, Iinc, Aadd)s, so the array has an element for each Aadd up to i.
+		n := i / 3
+		arr := stack[0].Array()
+		if len(arr) != n {
+			t.Errorf("after the token %d: expected %d elements, got %d", i, n, len(arr))
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Package lsp provides a language server of Watson that speaks the Language Server Protocol.
+//
+// The server executes each open document on the Watson VM and provides the following features:
+//   * Diagnostics: an error of the VM, such as a type mismatch or an empty stack, at the token that causes it.
+//   * Hover: the Op of the token at the cursor, the mode of the lexer that reads it, and the stack right after executing it.
+//   * Inlay hints: the value of each run of tokens that builds an integer or a string literal.
+//
+// Documents are always synchronized in full.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+// ErrExitWithoutShutdown is returned by Serve if the client sends the exit notification without the shutdown request.
+var ErrExitWithoutShutdown = errors.New("exit notification received before shutdown")
+
+// Option configures a Server.
+type Option interface {
+	apply(*config)
+}
+
+type option func(*config)
+
+func (opt option) apply(c *config) {
+	opt(c)
+}
+
+// WithInitialMode sets the initial mode of the lexer that reads documents.
+func WithInitialMode(mode lexer.Mode) Option {
+	return option(func(c *config) {
+		c.mode = mode
+	})
+}
+
+// WithStackSize sets the stack size of the VM that executes documents.
+func WithStackSize(size int) Option {
+	return option(func(c *config) {
+		c.stackSize = size
+	})
+}
+
+type config struct {
+	mode      lexer.Mode
+	stackSize int
+}
+
+// Server is a language server that communicates with a client over a pair of streams, e.g. the standard input and output.
+type Server struct {
+	conn     *conn
+	config   *config
+	docs     map[string]*document
+	shutdown bool
+}
+
+// NewServer creates a new Server that reads messages from r and writes messages to w.
+func NewServer(r io.Reader, w io.Writer, opts ...Option) *Server {
+	c := &config{mode: lexer.A, stackSize: vm.DefaultStackSize}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	return &Server{
+		conn:   newConn(r, w),
+		config: c,
+		docs:   map[string]*document{},
+	}
+}
+
+// Serve handles messages until the client sends the exit notification.
+// It returns nil if the client has sent the shutdown request before that, and an error otherwise.
+func (s *Server) Serve() error {
+	for {
+		body, err := s.conn.read()
+		if err == io.EOF {
+			if s.shutdown {
+				return nil
+			}
+			return io.ErrUnexpectedEOF
+		} else if err != nil {
+			return err
+		}
+		msg := &message{}
+		err = json.Unmarshal(body, msg)
+		if err != nil {
+			err = s.conn.replyError(nil, codeParseError, err.Error())
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.Method == "exit" {
+			if s.shutdown {
+				return nil
+			}
+			return ErrExitWithoutShutdown
+		}
+		if msg.isNotification() {
+			err = s.handleNotification(msg)
+		} else {
+			err = s.handleRequest(msg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handleRequest(msg *message) error {
+	switch msg.Method {
+	case "initialize":
+		return s.conn.reply(msg.ID, &initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:  1,
+				HoverProvider:     true,
+				InlayHintProvider: true,
+			},
+			ServerInfo: serverInfo{Name: "watson"},
+		})
+	case "shutdown":
+		s.shutdown = true
+		return s.conn.reply(msg.ID, nil)
+	case "textDocument/hover":
+		params := &hoverParams{}
+		if err := json.Unmarshal(msg.Params, params); err != nil {
+			return s.conn.replyError(msg.ID, codeInvalidParams, err.Error())
+		}
+		d, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return s.conn.reply(msg.ID, nil)
+		}
+		return s.conn.reply(msg.ID, d.hover(params.Position, s.config))
+	case "textDocument/inlayHint":
+		params := &inlayHintParams{}
+		if err := json.Unmarshal(msg.Params, params); err != nil {
+			return s.conn.replyError(msg.ID, codeInvalidParams, err.Error())
+		}
+		d, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return s.conn.reply(msg.ID, []InlayHint{})
+		}
+		return s.conn.reply(msg.ID, d.inlayHints(params.Range))
+	default:
+		return s.conn.replyError(msg.ID, codeMethodNotFound, "method not found: "+msg.Method)
+	}
+}
+
+// handleNotification handles a notification. Malformed or unknown notifications are ignored since they can't be replied.
+func (s *Server) handleNotification(msg *message) error {
+	switch msg.Method {
+	case "textDocument/didOpen":
+		params := &didOpenParams{}
+		if err := json.Unmarshal(msg.Params, params); err != nil {
+			return nil
+		}
+		return s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		params := &didChangeParams{}
+		if err := json.Unmarshal(msg.Params, params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		// The last change has the whole document since the server only supports full synchronization.
+		last := params.ContentChanges[len(params.ContentChanges)-1]
+		return s.update(params.TextDocument.URI, last.Text)
+	case "textDocument/didClose":
+		params := &didCloseParams{}
+		if err := json.Unmarshal(msg.Params, params); err != nil {
+			return nil
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+	default:
+		return nil
+	}
+}
+
+func (s *Server) update(uri, text string) error {
+	d := analyze(text, s.config)
+	s.docs[uri] = d
+	return s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: d.diagnostics(),
+	})
+}
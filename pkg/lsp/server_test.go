@@ -0,0 +1,359 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/lexer"
+)
+
+// client is a scripted LSP client that talks to a Server running in another goroutine.
+type client struct {
+	t      *testing.T
+	conn   *conn
+	nextID int
+	// pending holds notifications that are received while waiting for responses.
+	pending []*incoming
+	done    chan error
+}
+
+type incoming struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+func newClient(t *testing.T, opts ...Option) *client {
+	t.Helper()
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &client{
+		t:    t,
+		conn: newConn(clientIn, clientOut),
+		done: make(chan error, 1),
+	}
+	go func() {
+		err := NewServer(serverIn, serverOut, opts...).Serve()
+		serverOut.Close()
+		c.done <- err
+	}()
+	var result initializeResult
+	c.request("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}}, &result)
+	if !result.Capabilities.HoverProvider || !result.Capabilities.InlayHintProvider {
+		t.Fatalf("unexpected capabilities: %#v", result.Capabilities)
+	}
+	c.notify("initialized", map[string]interface{}{})
+	return c
+}
+
+func (c *client) read() *incoming {
+	c.t.Helper()
+	body, err := c.conn.read()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	msg := &incoming{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	if err := c.conn.notify(method, params); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// call sends a request and waits for its response.
+func (c *client) call(method string, params interface{}) *incoming {
+	c.t.Helper()
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	err := c.conn.write(&message{JSONRPC: "2.0", ID: id, Method: method, Params: mustMarshal(c.t, params)})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		msg := c.read()
+		if msg.Method != "" {
+			c.pending = append(c.pending, msg)
+			continue
+		}
+		if string(msg.ID) != string(id) {
+			c.t.Fatalf("unexpected response id: %s", msg.ID)
+		}
+		return msg
+	}
+}
+
+func (c *client) request(method string, params interface{}, result interface{}) {
+	c.t.Helper()
+	msg := c.call(method, params)
+	if msg.Error != nil {
+		c.t.Fatalf("%s failed: %s", method, msg.Error)
+	}
+	if err := json.Unmarshal(msg.Result, result); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// waitNotification returns the next notification of method.
+func (c *client) waitNotification(method string, params interface{}) {
+	c.t.Helper()
+	for {
+		var msg *incoming
+		if len(c.pending) > 0 {
+			msg, c.pending = c.pending[0], c.pending[1:]
+		} else {
+			msg = c.read()
+		}
+		if msg.Method != method {
+			continue
+		}
+		if err := json.Unmarshal(msg.Params, params); err != nil {
+			c.t.Fatal(err)
+		}
+		return
+	}
+}
+
+func (c *client) open(uri, text string) *PublishDiagnosticsParams {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "watson", "version": 1, "text": text},
+	})
+	diags := &PublishDiagnosticsParams{}
+	c.waitNotification("textDocument/publishDiagnostics", diags)
+	return diags
+}
+
+func (c *client) hover(uri string, line, char int) *Hover {
+	c.t.Helper()
+	var h *Hover
+	c.request("textDocument/hover", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     Position{Line: line, Character: char},
+	}, &h)
+	return h
+}
+
+func (c *client) inlayHints(uri string, r Range) []InlayHint {
+	c.t.Helper()
+	var hints []InlayHint
+	c.request("textDocument/inlayHint", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"range":        r,
+	}, &hints)
+	return hints
+}
+
+func (c *client) close() {
+	c.t.Helper()
+	var result interface{}
+	c.request("shutdown", nil, &result)
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		c.t.Fatalf("server failed: %s", err)
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) json.RawMessage {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func rng(startLine, startChar, endLine, endChar int) Range {
+	return Range{Start: Position{startLine, startChar}, End: Position{endLine, endChar}}
+}
+
+var wholeDocument = rng(0, 0, 1000, 0)
+
+func TestDiagnosticsOfTypeMismatch(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	// Inew Bnew Iadd
+	diags := c.open("file:///a.watson", "B\nza")
+	want := &PublishDiagnosticsParams{
+		URI: "file:///a.watson",
+		Diagnostics: []Diagnostic{{
+			Range:    rng(1, 1, 1, 2),
+			Severity: SeverityError,
+			Source:   "watson",
+			Message:  "Iadd: type mismatch",
+		}},
+	}
+	if diff := cmp.Diff(want, diags); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestDiagnosticsAreUpdated(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	// Inew Gpop Gpop
+	diags := c.open("file:///a.watson", "B##")
+	want := []Diagnostic{{Range: rng(0, 2, 0, 3), Severity: SeverityError, Source: "watson", Message: "Gpop: stack is empty"}}
+	if diff := cmp.Diff(want, diags.Diagnostics); diff != "" {
+		t.Error(diff)
+	}
+
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": "file:///a.watson", "version": 2},
+		"contentChanges": []map[string]interface{}{{"text": "B#"}},
+	})
+	diags = &PublishDiagnosticsParams{}
+	c.waitNotification("textDocument/publishDiagnostics", diags)
+	if len(diags.Diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got %#v", diags.Diagnostics)
+	}
+
+	c.notify("textDocument/didClose", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": "file:///a.watson"},
+	})
+	c.waitNotification("textDocument/publishDiagnostics", diags)
+	if h := c.hover("file:///a.watson", 0, 0); h != nil {
+		t.Errorf("expected no hover on a closed document, got %#v", h)
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	// Snew (A), then "a" in mode S: Inew Iinc Ishl Iinc Ishl*5 Iinc Sadd, then Inew (S).
+	c.open("file:///a.watson", "?Shahaaaaah-\nS")
+
+	h := c.hover("file:///a.watson", 0, 0)
+	want := &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "**Snew** (mode A)\n\nstack (top first):\n\n```\nString: \"\"\n```"},
+		Range:    &Range{Start: Position{0, 0}, End: Position{0, 1}},
+	}
+	if diff := cmp.Diff(want, h); diff != "" {
+		t.Error(diff)
+	}
+
+	h = c.hover("file:///a.watson", 1, 0)
+	wantValue := "**Inew** (mode S)\n\nstack (top first):\n\n```\nInt: 0\nString: \"a\"\n```"
+	if diff := cmp.Diff(wantValue, h.Contents.Value); diff != "" {
+		t.Error(diff)
+	}
+
+	// Positions without tokens.
+	for _, p := range []Position{{0, 12}, {2, 0}} {
+		if h := c.hover("file:///a.watson", p.Line, p.Character); h != nil {
+			t.Errorf("expected no hover at %#v, got %#v", p, h)
+		}
+	}
+}
+
+func TestHoverAfterError(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	c.open("file:///a.watson", "#BB")
+	h := c.hover("file:///a.watson", 0, 0)
+	if diff := cmp.Diff("**Gpop** (mode A)\n\nerror: stack is empty", h.Contents.Value); diff != "" {
+		t.Error(diff)
+	}
+	h = c.hover("file:///a.watson", 0, 2)
+	if diff := cmp.Diff("**Inew** (mode A)\n\nnot executed: the VM stopped at line 1, column 1", h.Contents.Value); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestInlayHints(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	// 3, 1u, "a", then an Array that is not a literal.
+	c.open("file:///a.watson", "Bubu\nBu' ?Shahaaaaah-\n$@")
+	hints := c.inlayHints("file:///a.watson", wholeDocument)
+	want := []InlayHint{
+		{Position: Position{0, 4}, Label: "3", PaddingLeft: true},
+		{Position: Position{1, 3}, Label: "1u", PaddingLeft: true},
+		{Position: Position{1, 16}, Label: `"a"`, PaddingLeft: true},
+		{Position: Position{2, 1}, Label: `""`, PaddingLeft: true},
+	}
+	if diff := cmp.Diff(want, hints); diff != "" {
+		t.Error(diff)
+	}
+
+	hints = c.inlayHints("file:///a.watson", rng(1, 0, 1, 10))
+	if diff := cmp.Diff(want[1:2], hints); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestInlayHintsOfKeysAndIntValues(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	// {"a": 1}: Onew, then "a" and 1 in mode S, and Oadd.
+	c.open("file:///a.watson", "~?Shahaaaaah-\nSh\ng")
+	hints := c.inlayHints("file:///a.watson", wholeDocument)
+	want := []InlayHint{
+		{Position: Position{0, 13}, Label: `"a"`, PaddingLeft: true},
+		{Position: Position{1, 2}, Label: "1", PaddingLeft: true},
+	}
+	if diff := cmp.Diff(want, hints); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestPositionsAreInUTF16(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	// "あ" is 3 bytes in UTF-8 but 1 code unit in UTF-16, and "😀" is 4 bytes and 2 code units.
+	c.open("file:///a.watson", "あBu😀u")
+	hints := c.inlayHints("file:///a.watson", wholeDocument)
+	want := []InlayHint{{Position: Position{0, 6}, Label: "2", PaddingLeft: true}}
+	if diff := cmp.Diff(want, hints); diff != "" {
+		t.Error(diff)
+	}
+	h := c.hover("file:///a.watson", 0, 5)
+	if h == nil || h.Range.Start != (Position{0, 5}) {
+		t.Fatalf("unexpected hover: %#v", h)
+	}
+	if diff := cmp.Diff("**Iinc** (mode A)\n\nstack (top first):\n\n```\nInt: 2\n```", h.Contents.Value); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestInitialMode(t *testing.T) {
+	c := newClient(t, WithInitialMode(lexer.S))
+	defer c.close()
+	diags := c.open("file:///a.watson", "Sh")
+	if len(diags.Diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got %#v", diags.Diagnostics)
+	}
+	h := c.hover("file:///a.watson", 0, 1)
+	if diff := cmp.Diff("**Iinc** (mode S)\n\nstack (top first):\n\n```\nInt: 1\n```", h.Contents.Value); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	msg := c.call("textDocument/definition", map[string]interface{}{})
+	if msg.Error == nil || msg.Error.Code != codeMethodNotFound {
+		t.Errorf("expected MethodNotFound, got %#v", msg)
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	c := newClient(t)
+	c.notify("exit", nil)
+	if err := <-c.done; !errors.Is(err, ErrExitWithoutShutdown) {
+		t.Errorf("expected ErrExitWithoutShutdown, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package schema
+
+import (
+	"sort"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+// DefaultMaxEnum is the maximum number of distinct strings that Infer turns into an enum by default.
+const DefaultMaxEnum = 5
+
+// InferOption configures Infer.
+type InferOption interface {
+	apply(*inferConfig)
+}
+
+type inferOption func(*inferConfig)
+
+func (opt inferOption) apply(c *inferConfig) {
+	opt(c)
+}
+
+// WithMaxEnum sets the maximum number of distinct strings that are turned into an enum. 0 disables enums.
+func WithMaxEnum(n int) InferOption {
+	return inferOption(func(c *inferConfig) {
+		c.maxEnum = n
+	})
+}
+
+type inferConfig struct {
+	maxEnum int
+}
+
+// Infer returns a schema that all of samples conform to, in the form that Parse accepts.
+//
+// The schema is built by the following rules:
+//   * "kind" lists all Kinds observed at the same position.
+//   * Objects have "properties" of all observed keys, and "additionalProperties" is false.
+//     Keys that are present in all Objects at the same position are "required"; others are optional.
+//   * Arrays have "items" that unifies all of their elements.
+//   * Strings become an "enum" if there are at most maxEnum distinct ones and at least one of them occurs twice or more,
+//     so that strings that are unique to each sample, such as names, are not turned into enums.
+func Infer(samples []*types.Value, opts ...InferOption) *types.Value {
+	c := &inferConfig{maxEnum: DefaultMaxEnum}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	root := newObservation()
+	for _, v := range samples {
+		root.observe(v, c)
+	}
+	return root.schema(c)
+}
+
+// observation is a summary of Values observed at the same position.
+type observation struct {
+	kinds   map[types.Kind]bool
+	strings map[string]bool
+	nstring int
+	objects int
+	fields  map[string]*fieldObservation
+	elem    *observation
+}
+
+type fieldObservation struct {
+	count int
+	obs   *observation
+}
+
+func newObservation() *observation {
+	return &observation{
+		kinds:   map[types.Kind]bool{},
+		strings: map[string]bool{},
+		fields:  map[string]*fieldObservation{},
+	}
+}
+
+func (o *observation) observe(v *types.Value, c *inferConfig) {
+	o.kinds[v.Kind] = true
+	switch v.Kind {
+	case types.String:
+		o.nstring++
+		// Strings more than maxEnum can't be an enum anyway, so there's no need to remember them.
+		if len(o.strings) <= c.maxEnum {
+			o.strings[string(v.Bytes())] = true
+		}
+	case types.Object:
+		o.objects++
+		for k, elem := range v.Object() {
+			f, ok := o.fields[k]
+			if !ok {
+				f = &fieldObservation{obs: newObservation()}
+				o.fields[k] = f
+			}
+			f.count++
+			f.obs.observe(elem, c)
+		}
+	case types.Array:
+		if o.elem == nil {
+			o.elem = newObservation()
+		}
+		for _, elem := range v.Array() {
+			o.elem.observe(elem, c)
+		}
+	}
+}
+
+func (o *observation) schema(c *inferConfig) *types.Value {
+	s := map[string]*types.Value{}
+	kinds := make([]string, 0, len(o.kinds))
+	for k := range o.kinds {
+		kinds = append(kinds, KindName(k))
+	}
+	sort.Strings(kinds)
+	switch len(kinds) {
+	case 0:
+		// Nothing is observed, e.g. elements of empty arrays. Anything is allowed.
+		return types.NewObjectValue(s)
+	case 1:
+		s["kind"] = types.NewStringValue([]byte(kinds[0]))
+	default:
+		names := make([]*types.Value, 0, len(kinds))
+		for _, k := range kinds {
+			names = append(names, types.NewStringValue([]byte(k)))
+		}
+		s["kind"] = types.NewArrayValue(names)
+	}
+	if o.isEnum(c) {
+		strs := make([]string, 0, len(o.strings))
+		for str := range o.strings {
+			strs = append(strs, str)
+		}
+		sort.Strings(strs)
+		enum := make([]*types.Value, 0, len(strs)+1)
+		for _, str := range strs {
+			enum = append(enum, types.NewStringValue([]byte(str)))
+		}
+		if o.kinds[types.Nil] {
+			enum = append(enum, types.NewNilValue())
+		}
+		s["enum"] = types.NewArrayValue(enum)
+	}
+	if o.kinds[types.Object] {
+		keys := make([]string, 0, len(o.fields))
+		for k := range o.fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		props := map[string]*types.Value{}
+		required := make([]*types.Value, 0)
+		for _, k := range keys {
+			f := o.fields[k]
+			props[k] = f.obs.schema(c)
+			if f.count == o.objects {
+				required = append(required, types.NewStringValue([]byte(k)))
+			}
+		}
+		s["properties"] = types.NewObjectValue(props)
+		s["required"] = types.NewArrayValue(required)
+		s["additionalProperties"] = types.NewBoolValue(false)
+	}
+	if o.kinds[types.Array] && o.elem != nil {
+		s["items"] = o.elem.schema(c)
+	}
+	return types.NewObjectValue(s)
+}
+
+// isEnum reports whether the observed strings should be an enum.
+func (o *observation) isEnum(c *inferConfig) bool {
+	for k := range o.kinds {
+		if k != types.String && k != types.Nil {
+			return false
+		}
+	}
+	n := len(o.strings)
+	return n > 0 && n <= c.maxEnum && o.nstring > n
+}
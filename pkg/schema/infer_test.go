@@ -0,0 +1,103 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func strs(ss ...string) *types.Value {
+	vs := make([]*types.Value, 0, len(ss))
+	for _, s := range ss {
+		vs = append(vs, str(s))
+	}
+	return arr(vs...)
+}
+
+var inferSamples = []*types.Value{
+	obj(
+		"name", str("a"),
+		"env", str("dev"),
+		"port", types.NewIntValue(80),
+		"tags", arr(),
+		"servers", arr(obj("host", str("x"), "weight", types.NewUintValue(1))),
+	),
+	obj(
+		"name", str("b"),
+		"env", str("prod"),
+		"port", types.NewNilValue(),
+		"tags", strs("t1"),
+		"servers", arr(obj("host", str("y")), obj("host", str("z"), "weight", types.NewFloatValue(0.5))),
+	),
+	obj(
+		"name", str("c"),
+		"env", str("dev"),
+		"tags", arr(),
+		"servers", arr(),
+	),
+}
+
+func TestInfer(t *testing.T) {
+	got := Infer(inferSamples)
+	want := obj(
+		"kind", str("object"),
+		"additionalProperties", types.NewBoolValue(false),
+		"required", strs("env", "name", "servers", "tags"),
+		"properties", obj(
+			"name", obj("kind", str("string")),
+			"env", obj("kind", str("string"), "enum", strs("dev", "prod")),
+			"port", obj("kind", strs("int", "nil")),
+			"tags", obj("kind", str("array"), "items", obj("kind", str("string"))),
+			"servers", obj(
+				"kind", str("array"),
+				"items", obj(
+					"kind", str("object"),
+					"additionalProperties", types.NewBoolValue(false),
+					"required", strs("host"),
+					"properties", obj(
+						"host", obj("kind", str("string")),
+						"weight", obj("kind", strs("float", "uint")),
+					),
+				),
+			),
+		),
+	)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestInferredSchemaAcceptsSamples(t *testing.T) {
+	s := mustParse(t, Infer(inferSamples))
+	for i, v := range inferSamples {
+		if vs := s.Validate(v); vs != nil {
+			t.Errorf("sample %d: unexpected violations: %v", i, messages(vs))
+		}
+	}
+	want := []string{
+		"<root>.env: value is not one of the enum",
+		"<root>.extra: additional key is not allowed",
+		"<root>.servers: required key is missing",
+	}
+	bad := obj("name", str("d"), "env", str("stage"), "tags", arr(), "extra", types.NewBoolValue(true))
+	if diff := cmp.Diff(want, messages(s.Validate(bad))); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestInferMaxEnum(t *testing.T) {
+	samples := []*types.Value{str("a"), str("b"), str("a"), types.NewNilValue()}
+	want := obj("kind", strs("nil", "string"), "enum", arr(str("a"), str("b"), types.NewNilValue()))
+	if diff := cmp.Diff(want, Infer(samples)); diff != "" {
+		t.Error(diff)
+	}
+	want = obj("kind", strs("nil", "string"))
+	if diff := cmp.Diff(want, Infer(samples, WithMaxEnum(1))); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(want, Infer(samples, WithMaxEnum(0))); diff != "" {
+		t.Error(diff)
+	}
+}
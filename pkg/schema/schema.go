@@ -0,0 +1,376 @@
+// Package schema provides a schema language for Values and a validator of it.
+//
+// A schema is itself a Value, so it can be written in Watson or in any format that can be converted into a Value, such as YAML.
+// A schema is an Object that has some of the following keywords. A keyword that is absent imposes no constraint.
+//   * "kind": a name of a Kind ("int", "uint", "float", "string", "object", "array", "bool", or "nil"),
+//     "number" that stands for any of int, uint, and float, or an Array of them.
+//   * "enum": an Array of allowed values. Values are compared including their Kinds, so 1 (Int) doesn't match 1 (Uint).
+//   * "minimum", "maximum": inclusive bounds of numbers. Ints, Uints, and Floats are compared by their mathematical values.
+//   * "pattern": a regular expression (in the syntax of the regexp package) that strings must contain a match of.
+//   * "minLength", "maxLength": bounds of the length of strings in bytes.
+//   * "properties": an Object that maps keys of Objects to schemas of their values.
+//   * "required": an Array of keys that Objects must have.
+//   * "additionalProperties": either a Bool or a schema. false rejects keys not listed in "properties",
+//     and a schema validates their values.
+//   * "items": a schema of all elements of Arrays.
+//   * "minItems", "maxItems": bounds of the length of Arrays.
+//   * "description": an arbitrary String that is ignored by the validator.
+//
+// Keywords that don't apply to the Kind of a value are ignored, e.g. "pattern" doesn't reject Ints.
+package schema
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+const rootPath = "<root>"
+
+// Schema is a compiled schema.
+type Schema struct {
+	kinds      map[types.Kind]bool
+	enum       []*types.Value
+	minimum    *types.Value
+	maximum    *types.Value
+	pattern    *regexp.Regexp
+	minLength  *int
+	maxLength  *int
+	properties map[string]*Schema
+	required   []string
+	closed     bool
+	additional *Schema
+	items      *Schema
+	minItems   *int
+	maxItems   *int
+}
+
+// SchemaError is an error in a schema.
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("invalid schema at %s: %s", e.Path, e.Message)
+}
+
+// Violation is a part of a value that doesn't conform to a schema.
+type Violation struct {
+	// Path is a path to the value, e.g. "<root>.servers[1].port".
+	Path string
+	// Value is the value that violates the schema. It is nil if the value is missing.
+	Value   *types.Value
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+var kindNames = map[string][]types.Kind{
+	"int":    {types.Int},
+	"uint":   {types.Uint},
+	"float":  {types.Float},
+	"number": {types.Int, types.Uint, types.Float},
+	"string": {types.String},
+	"object": {types.Object},
+	"array":  {types.Array},
+	"bool":   {types.Bool},
+	"nil":    {types.Nil},
+}
+
+// KindName returns the name of k that is used in schemas.
+func KindName(k types.Kind) string {
+	switch k {
+	case types.Int:
+		return "int"
+	case types.Uint:
+		return "uint"
+	case types.Float:
+		return "float"
+	case types.String:
+		return "string"
+	case types.Object:
+		return "object"
+	case types.Array:
+		return "array"
+	case types.Bool:
+		return "bool"
+	case types.Nil:
+		return "nil"
+	default:
+		panic(fmt.Errorf("invalid kind: %d", k))
+	}
+}
+
+// Parse compiles v into a Schema.
+func Parse(v *types.Value) (*Schema, error) {
+	return parse(v, rootPath)
+}
+
+func parse(v *types.Value, path string) (*Schema, error) {
+	if v.Kind != types.Object {
+		return nil, &SchemaError{Path: path, Message: "schema must be an object"}
+	}
+	s := &Schema{}
+	var err error
+	for _, k := range sortedKeys(v.Object()) {
+		elem := v.Object()[k]
+		elemPath := path + "." + k
+		switch k {
+		case "kind":
+			s.kinds, err = parseKinds(elem, elemPath)
+		case "enum":
+			if elem.Kind != types.Array {
+				return nil, &SchemaError{Path: elemPath, Message: "enum must be an array"}
+			}
+			s.enum = elem.Array()
+		case "minimum":
+			s.minimum, err = parseNumber(elem, elemPath)
+		case "maximum":
+			s.maximum, err = parseNumber(elem, elemPath)
+		case "pattern":
+			if elem.Kind != types.String {
+				return nil, &SchemaError{Path: elemPath, Message: "pattern must be a string"}
+			}
+			s.pattern, err = regexp.Compile(string(elem.Bytes()))
+			if err != nil {
+				return nil, &SchemaError{Path: elemPath, Message: err.Error()}
+			}
+		case "minLength":
+			s.minLength, err = parseLength(elem, elemPath)
+		case "maxLength":
+			s.maxLength, err = parseLength(elem, elemPath)
+		case "properties":
+			if elem.Kind != types.Object {
+				return nil, &SchemaError{Path: elemPath, Message: "properties must be an object"}
+			}
+			s.properties = map[string]*Schema{}
+			for _, name := range sortedKeys(elem.Object()) {
+				s.properties[name], err = parse(elem.Object()[name], elemPath+"."+name)
+				if err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			s.required, err = parseRequired(elem, elemPath)
+		case "additionalProperties":
+			if elem.Kind == types.Bool {
+				s.closed = !elem.Bool()
+			} else {
+				s.additional, err = parse(elem, elemPath)
+			}
+		case "items":
+			s.items, err = parse(elem, elemPath)
+		case "minItems":
+			s.minItems, err = parseLength(elem, elemPath)
+		case "maxItems":
+			s.maxItems, err = parseLength(elem, elemPath)
+		case "description":
+			if elem.Kind != types.String {
+				return nil, &SchemaError{Path: elemPath, Message: "description must be a string"}
+			}
+		default:
+			return nil, &SchemaError{Path: elemPath, Message: "unknown keyword"}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func parseKinds(v *types.Value, path string) (map[types.Kind]bool, error) {
+	names := []*types.Value{v}
+	if v.Kind == types.Array {
+		names = v.Array()
+	}
+	kinds := map[types.Kind]bool{}
+	for _, name := range names {
+		if name.Kind != types.String {
+			return nil, &SchemaError{Path: path, Message: "kind must be a string or an array of strings"}
+		}
+		ks, ok := kindNames[string(name.Bytes())]
+		if !ok {
+			return nil, &SchemaError{Path: path, Message: fmt.Sprintf("unknown kind: %s", name.Bytes())}
+		}
+		for _, k := range ks {
+			kinds[k] = true
+		}
+	}
+	return kinds, nil
+}
+
+func parseNumber(v *types.Value, path string) (*types.Value, error) {
+	if !isNumber(v) || v.IsNaN() {
+		return nil, &SchemaError{Path: path, Message: "bound must be a number"}
+	}
+	return v, nil
+}
+
+func parseLength(v *types.Value, path string) (*int, error) {
+	var n int
+	switch {
+	case v.Kind == types.Int && v.Int() >= 0 && v.Int() <= math.MaxInt32:
+		n = int(v.Int())
+	case v.Kind == types.Uint && v.Uint() <= math.MaxInt32:
+		n = int(v.Uint())
+	default:
+		return nil, &SchemaError{Path: path, Message: "length must be a non-negative integer"}
+	}
+	return &n, nil
+}
+
+func parseRequired(v *types.Value, path string) ([]string, error) {
+	if v.Kind != types.Array {
+		return nil, &SchemaError{Path: path, Message: "required must be an array of strings"}
+	}
+	keys := make([]string, 0, len(v.Array()))
+	for _, k := range v.Array() {
+		if k.Kind != types.String {
+			return nil, &SchemaError{Path: path, Message: "required must be an array of strings"}
+		}
+		keys = append(keys, string(k.Bytes()))
+	}
+	return keys, nil
+}
+
+// Validate checks whether v conforms to s and returns all violations in the order of their paths.
+// It returns nil if v conforms to s.
+func (s *Schema) Validate(v *types.Value) []*Violation {
+	vs := make([]*Violation, 0)
+	s.validate(v, rootPath, &vs)
+	if len(vs) == 0 {
+		return nil
+	}
+	sort.SliceStable(vs, func(i, j int) bool {
+		return vs[i].Path < vs[j].Path
+	})
+	return vs
+}
+
+func (s *Schema) validate(v *types.Value, path string, vs *[]*Violation) {
+	report := func(format string, args ...interface{}) {
+		*vs = append(*vs, &Violation{Path: path, Value: v, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.kinds != nil && !s.kinds[v.Kind] {
+		report("expected %s, got %s", s.kindNames(), KindName(v.Kind))
+		return
+	}
+	if s.enum != nil && !s.inEnum(v) {
+		report("value is not one of the enum")
+	}
+	if isNumber(v) {
+		if s.minimum != nil && (v.IsNaN() || compare(v, s.minimum) < 0) {
+			report("value is less than %s", numberString(s.minimum))
+		}
+		if s.maximum != nil && (v.IsNaN() || compare(v, s.maximum) > 0) {
+			report("value is greater than %s", numberString(s.maximum))
+		}
+	}
+	switch v.Kind {
+	case types.String:
+		if s.pattern != nil && !s.pattern.Match(v.Bytes()) {
+			report("value does not match %s", s.pattern.String())
+		}
+		if s.minLength != nil && len(v.Bytes()) < *s.minLength {
+			report("length %d is less than %d", len(v.Bytes()), *s.minLength)
+		}
+		if s.maxLength != nil && len(v.Bytes()) > *s.maxLength {
+			report("length %d is greater than %d", len(v.Bytes()), *s.maxLength)
+		}
+	case types.Object:
+		for _, k := range s.required {
+			if _, ok := v.Object()[k]; !ok {
+				*vs = append(*vs, &Violation{Path: path + "." + k, Message: "required key is missing"})
+			}
+		}
+		for _, k := range sortedKeys(v.Object()) {
+			elem := v.Object()[k]
+			elemPath := path + "." + k
+			if prop, ok := s.properties[k]; ok {
+				prop.validate(elem, elemPath, vs)
+			} else if s.closed {
+				*vs = append(*vs, &Violation{Path: elemPath, Value: elem, Message: "additional key is not allowed"})
+			} else if s.additional != nil {
+				s.additional.validate(elem, elemPath, vs)
+			}
+		}
+	case types.Array:
+		if s.minItems != nil && len(v.Array()) < *s.minItems {
+			report("length %d is less than %d", len(v.Array()), *s.minItems)
+		}
+		if s.maxItems != nil && len(v.Array()) > *s.maxItems {
+			report("length %d is greater than %d", len(v.Array()), *s.maxItems)
+		}
+		if s.items != nil {
+			for i, elem := range v.Array() {
+				s.items.validate(elem, fmt.Sprintf("%s[%d]", path, i), vs)
+			}
+		}
+	}
+}
+
+func (s *Schema) kindNames() string {
+	names := make([]string, 0, len(s.kinds))
+	for k := range s.kinds {
+		names = append(names, KindName(k))
+	}
+	sort.Strings(names)
+	return strings.Join(names, " or ")
+}
+
+func (s *Schema) inEnum(v *types.Value) bool {
+	for _, e := range s.enum {
+		if v.Equal(e) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumber(v *types.Value) bool {
+	return v.Kind == types.Int || v.Kind == types.Uint || v.Kind == types.Float
+}
+
+// compare compares numbers a and b, neither of which is NaN, without losing precision.
+func compare(a, b *types.Value) int {
+	return toBigFloat(a).Cmp(toBigFloat(b))
+}
+
+func toBigFloat(v *types.Value) *big.Float {
+	switch v.Kind {
+	case types.Int:
+		return new(big.Float).SetInt64(v.Int())
+	case types.Uint:
+		return new(big.Float).SetUint64(v.Uint())
+	default:
+		return new(big.Float).SetFloat64(v.Float())
+	}
+}
+
+func numberString(v *types.Value) string {
+	switch v.Kind {
+	case types.Int:
+		return fmt.Sprintf("%d", v.Int())
+	case types.Uint:
+		return fmt.Sprintf("%d", v.Uint())
+	default:
+		return fmt.Sprintf("%g", v.Float())
+	}
+}
+
+func sortedKeys(obj map[string]*types.Value) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,196 @@
+package schema
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func str(s string) *types.Value {
+	return types.NewStringValue([]byte(s))
+}
+
+func obj(kvs ...interface{}) *types.Value {
+	o := map[string]*types.Value{}
+	for i := 0; i < len(kvs); i += 2 {
+		o[kvs[i].(string)] = kvs[i+1].(*types.Value)
+	}
+	return types.NewObjectValue(o)
+}
+
+func arr(vs ...*types.Value) *types.Value {
+	return types.NewArrayValue(vs)
+}
+
+func mustParse(t *testing.T, v *types.Value) *Schema {
+	t.Helper()
+	s, err := Parse(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// messages returns "path: message" of each violation.
+func messages(vs []*Violation) []string {
+	if vs == nil {
+		return nil
+	}
+	ms := make([]string, 0, len(vs))
+	for _, v := range vs {
+		ms = append(ms, v.Error())
+	}
+	return ms
+}
+
+func TestValidateKind(t *testing.T) {
+	s := mustParse(t, obj("kind", str("uint")))
+	if vs := s.Validate(types.NewUintValue(1)); vs != nil {
+		t.Errorf("unexpected violations: %v", messages(vs))
+	}
+	want := []string{"<root>: expected uint, got int"}
+	if diff := cmp.Diff(want, messages(s.Validate(types.NewIntValue(1)))); diff != "" {
+		t.Error(diff)
+	}
+
+	s = mustParse(t, obj("kind", arr(str("number"), str("nil"))))
+	for _, v := range []*types.Value{types.NewIntValue(1), types.NewUintValue(1), types.NewFloatValue(1), types.NewNilValue()} {
+		if vs := s.Validate(v); vs != nil {
+			t.Errorf("unexpected violations: %v", messages(vs))
+		}
+	}
+	want = []string{"<root>: expected float or int or nil or uint, got string"}
+	if diff := cmp.Diff(want, messages(s.Validate(str("1")))); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestValidateEnum(t *testing.T) {
+	s := mustParse(t, obj("enum", arr(types.NewIntValue(1), str("a"), arr(types.NewBoolValue(true)))))
+	for _, v := range []*types.Value{types.NewIntValue(1), str("a"), arr(types.NewBoolValue(true))} {
+		if vs := s.Validate(v); vs != nil {
+			t.Errorf("unexpected violations: %v", messages(vs))
+		}
+	}
+	for _, v := range []*types.Value{types.NewUintValue(1), str("b"), arr()} {
+		if vs := s.Validate(v); len(vs) != 1 {
+			t.Errorf("expected a violation, got %v", messages(vs))
+		}
+	}
+}
+
+func TestValidateRange(t *testing.T) {
+	s := mustParse(t, obj("minimum", types.NewIntValue(-1), "maximum", types.NewUintValue(math.MaxUint64)))
+	for _, v := range []*types.Value{types.NewIntValue(-1), types.NewFloatValue(0.5), types.NewUintValue(math.MaxUint64), str("ignored")} {
+		if vs := s.Validate(v); vs != nil {
+			t.Errorf("unexpected violations: %v", messages(vs))
+		}
+	}
+	want := []string{"<root>: value is less than -1"}
+	if diff := cmp.Diff(want, messages(s.Validate(types.NewFloatValue(-1.5)))); diff != "" {
+		t.Error(diff)
+	}
+	want = []string{"<root>: value is greater than 18446744073709551615"}
+	if diff := cmp.Diff(want, messages(s.Validate(types.NewFloatValue(math.Inf(1))))); diff != "" {
+		t.Error(diff)
+	}
+	if vs := s.Validate(types.NewFloatValue(math.NaN())); len(vs) != 2 {
+		t.Errorf("expected NaN to be out of range, got %v", messages(vs))
+	}
+}
+
+func TestValidateString(t *testing.T) {
+	s := mustParse(t, obj("pattern", str("^[a-z]+$"), "minLength", types.NewIntValue(2), "maxLength", types.NewUintValue(3)))
+	if vs := s.Validate(str("abc")); vs != nil {
+		t.Errorf("unexpected violations: %v", messages(vs))
+	}
+	want := []string{
+		"<root>: value does not match ^[a-z]+$",
+		"<root>: length 1 is less than 2",
+	}
+	if diff := cmp.Diff(want, messages(s.Validate(str("A")))); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestValidateObject(t *testing.T) {
+	s := mustParse(t, obj(
+		"kind", str("object"),
+		"required", arr(str("name"), str("port")),
+		"properties", obj(
+			"name", obj("kind", str("string")),
+			"port", obj("kind", str("int"), "minimum", types.NewIntValue(1)),
+		),
+		"additionalProperties", types.NewBoolValue(false),
+	))
+	if vs := s.Validate(obj("name", str("a"), "port", types.NewIntValue(80))); vs != nil {
+		t.Errorf("unexpected violations: %v", messages(vs))
+	}
+	vs := s.Validate(obj("port", types.NewIntValue(0), "extra", types.NewBoolValue(true)))
+	want := []string{
+		"<root>.extra: additional key is not allowed",
+		"<root>.name: required key is missing",
+		"<root>.port: value is less than 1",
+	}
+	if diff := cmp.Diff(want, messages(vs)); diff != "" {
+		t.Error(diff)
+	}
+	if vs[1].Value != nil {
+		t.Errorf("missing key must not have a value: %#v", vs[1].Value)
+	}
+
+	s = mustParse(t, obj("additionalProperties", obj("kind", str("bool"))))
+	want = []string{"<root>.b: expected bool, got int"}
+	if diff := cmp.Diff(want, messages(s.Validate(obj("a", types.NewBoolValue(true), "b", types.NewIntValue(1))))); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestValidateArray(t *testing.T) {
+	s := mustParse(t, obj(
+		"items", obj("properties", obj("port", obj("kind", str("int")))),
+		"minItems", types.NewIntValue(1),
+		"maxItems", types.NewIntValue(2),
+	))
+	if vs := s.Validate(arr(obj("port", types.NewIntValue(1)))); vs != nil {
+		t.Errorf("unexpected violations: %v", messages(vs))
+	}
+	vs := s.Validate(arr(obj(), obj("port", str("80")), obj("port", types.NewIntValue(1))))
+	want := []string{
+		"<root>: length 3 is greater than 2",
+		"<root>[1].port: expected int, got string",
+	}
+	if diff := cmp.Diff(want, messages(vs)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	cases := []struct {
+		schema *types.Value
+		want   string
+	}{
+		{str("int"), "invalid schema at <root>: schema must be an object"},
+		{obj("type", str("int")), "invalid schema at <root>.type: unknown keyword"},
+		{obj("kind", str("integer")), "invalid schema at <root>.kind: unknown kind: integer"},
+		{obj("pattern", str("(")), "invalid schema at <root>.pattern: error parsing regexp: missing closing ): `(`"},
+		{obj("minItems", types.NewIntValue(-1)), "invalid schema at <root>.minItems: length must be a non-negative integer"},
+		{obj("maximum", types.NewFloatValue(math.NaN())), "invalid schema at <root>.maximum: bound must be a number"},
+		{obj("properties", obj("a", obj("items", types.NewNilValue()))), "invalid schema at <root>.properties.a.items: schema must be an object"},
+	}
+	for _, c := range cases {
+		_, err := Parse(c.schema)
+		var se *SchemaError
+		if !errors.As(err, &se) {
+			t.Errorf("expected SchemaError, got %#v", err)
+			continue
+		}
+		if err.Error() != c.want {
+			t.Errorf("expected %q, got %q", c.want, err.Error())
+		}
+	}
+}
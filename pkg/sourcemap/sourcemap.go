@@ -0,0 +1,287 @@
+// Package sourcemap records where each decoded value comes from.
+//
+// A Recorder observes a VM that executes tokens, and builds a SourceMap that maps the path of each value in the result,
+// such as "<root>.servers[1].port", to the Span of the tokens that produced it. Paths are written in the same form
+// as the ones in errors of types.Value.Bind and violations of schemas, so they can be used to locate such errors in files.
+package sourcemap
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+// Position is a position of a token. Line and Column are zero-based like the ones of lexer.Token.
+type Position struct {
+	FileName string
+	Line     int
+	Column   int
+}
+
+func positionOf(tok *lexer.Token) Position {
+	return Position{FileName: tok.FileName, Line: tok.Line, Column: tok.Column}
+}
+
+// String returns the position in the form of "file:line:column", whose line and column are one-based.
+// The file name is omitted if it is empty.
+func (p Position) String() string {
+	s := fmt.Sprintf("%d:%d", p.Line+1, p.Column+1)
+	if p.FileName != "" {
+		s = p.FileName + ":" + s
+	}
+	return s
+}
+
+func (p Position) before(q Position) bool {
+	return p.Line < q.Line || (p.Line == q.Line && p.Column < q.Column)
+}
+
+func earlier(p, q Position) Position {
+	if q.before(p) {
+		return q
+	}
+	return p
+}
+
+// Span is a range of tokens. Start is the position of the first token, and End is the position of the last token.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// String returns the start of the span.
+func (s Span) String() string {
+	return s.Start.String()
+}
+
+// SourceMap maps paths of values to the Spans that produced them.
+type SourceMap struct {
+	spans   map[string]Span
+	entries map[string]Span
+}
+
+// Lookup returns the Span of the value at path, e.g. "<root>.servers[1].port".
+func (m *SourceMap) Lookup(path string) (Span, bool) {
+	s, ok := m.spans[path]
+	return s, ok
+}
+
+// LookupEntry returns the Span of the Ops that add the value at path to its parent.
+// For an element of an Object, it starts with the key and ends with Oadd; for an element of an Array, it ends with Aadd.
+// It returns false for "<root>" since the root has no parent.
+func (m *SourceMap) LookupEntry(path string) (Span, bool) {
+	s, ok := m.entries[path]
+	return s, ok
+}
+
+// LookupNearest is the same as Lookup except that it falls back to the Span of the nearest parent of path
+// if path is not found, e.g. the Span of "<root>.servers[1]" for a missing key "<root>.servers[1].port".
+func (m *SourceMap) LookupNearest(path string) (Span, bool) {
+	for {
+		if s, ok := m.spans[path]; ok {
+			return s, true
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			return Span{}, false
+		}
+		path = path[:i]
+	}
+}
+
+// Paths returns the paths of all values in the sorted order.
+func (m *SourceMap) Paths() []string {
+	paths := make([]string, 0, len(m.spans))
+	for p := range m.spans {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Error is an error that occurs at Span.
+type Error struct {
+	Span Span
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Span.String(), e.Err.Error())
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Annotate wraps err in an *Error if err (or an error that it wraps) has a method `Path() string`, such as *types.TypeMismatch,
+// and m knows the Span of the path. Otherwise it returns err as is.
+func (m *SourceMap) Annotate(err error) error {
+	var p interface{ Path() string }
+	if !errors.As(err, &p) {
+		return err
+	}
+	s, ok := m.Lookup(p.Path())
+	if !ok {
+		return err
+	}
+	return &Error{Span: s, Err: err}
+}
+
+// node is a shadow of a value in the stack of a VM.
+type node struct {
+	span Span
+	// entry is the span of the Ops that add the node to its parent, or the zero value if the node has no parent.
+	entry  Span
+	fields map[string]*node
+	elems  []*node
+}
+
+func (n *node) deepCopy() *node {
+	clone := &node{span: n.span, entry: n.entry}
+	if n.fields != nil {
+		clone.fields = make(map[string]*node, len(n.fields))
+		for k, f := range n.fields {
+			clone.fields[k] = f.deepCopy()
+		}
+	}
+	for _, e := range n.elems {
+		clone.elems = append(clone.elems, e.deepCopy())
+	}
+	return clone
+}
+
+// Recorder is a vm.Tracer that records the Spans of values in the stack of a VM.
+// It keeps a shadow stack that is updated in the same way as the stack of the VM.
+//
+// A Recorder must be installed to a VM with vm.WithTracer, and tokens must be fed via Feed so that it knows their positions.
+type Recorder struct {
+	stack []*node
+	tok   *lexer.Token
+	// key is the key of the pending Oadd.
+	key string
+	// err is the first error of the VM. The Recorder stops recording after that.
+	err error
+}
+
+// NewRecorder creates a new Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Feed feeds tok to m, which must have r as its Tracer.
+func (r *Recorder) Feed(m *vm.VM, tok *lexer.Token) error {
+	r.tok = tok
+	return m.Feed(tok.Op)
+}
+
+func (r *Recorder) BeforeOp(op vm.Op, stack []*types.Value) {
+	if op == vm.Oadd && len(stack) >= 2 {
+		r.key = string(stack[len(stack)-2].Bytes())
+	}
+}
+
+func (r *Recorder) AfterOp(op vm.Op, stack []*types.Value, err error) {
+	if r.err != nil {
+		return
+	}
+	if err != nil {
+		r.err = err
+		return
+	}
+	if r.tok == nil {
+		r.err = errors.New("sourcemap: the Op is not fed by Recorder.Feed")
+		return
+	}
+	pos := positionOf(r.tok)
+	r.tok = nil
+	switch op {
+	case vm.Inew, vm.Finf, vm.Fnan, vm.Snew, vm.Onew, vm.Anew, vm.Bnew, vm.Nnew:
+		n := &node{span: Span{Start: pos, End: pos}}
+		if op == vm.Onew {
+			n.fields = map[string]*node{}
+		}
+		r.push(n)
+	case vm.Iinc, vm.Ishl, vm.Ineg, vm.Itof, vm.Itou, vm.Fneg, vm.Bneg:
+		r.top().span.End = pos
+	case vm.Iadd, vm.Isht:
+		b := r.pop()
+		a := r.pop()
+		r.push(&node{span: Span{Start: earlier(a.span.Start, b.span.Start), End: pos}})
+	case vm.Sadd:
+		r.pop()
+		r.top().span.End = pos
+	case vm.Oadd:
+		v := r.pop()
+		k := r.pop()
+		v.entry = Span{Start: earlier(k.span.Start, v.span.Start), End: pos}
+		o := r.top()
+		o.fields[r.key] = v
+		o.span.End = pos
+	case vm.Aadd:
+		x := r.pop()
+		x.entry = Span{Start: x.span.Start, End: pos}
+		a := r.top()
+		a.elems = append(a.elems, x)
+		a.span.End = pos
+	case vm.Gdup:
+		r.push(r.top().deepCopy())
+	case vm.Gpop:
+		r.pop()
+	case vm.Gswp:
+		a := r.pop()
+		b := r.pop()
+		r.push(a)
+		r.push(b)
+	default:
+		panic(fmt.Errorf("invalid opcode: %d", op))
+	}
+}
+
+func (r *Recorder) push(n *node) {
+	r.stack = append(r.stack, n)
+}
+
+func (r *Recorder) pop() *node {
+	n := r.stack[len(r.stack)-1]
+	r.stack[len(r.stack)-1] = nil
+	r.stack = r.stack[:len(r.stack)-1]
+	return n
+}
+
+func (r *Recorder) top() *node {
+	return r.stack[len(r.stack)-1]
+}
+
+// SourceMap returns the SourceMap of the value at the top of the stack.
+// It returns an error if the VM has failed, or if its stack is empty.
+func (r *Recorder) SourceMap() (*SourceMap, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(r.stack) == 0 {
+		return nil, vm.ErrStackEmpty
+	}
+	m := &SourceMap{spans: map[string]Span{}, entries: map[string]Span{}}
+	m.add("<root>", r.top())
+	delete(m.entries, "<root>")
+	return m, nil
+}
+
+func (m *SourceMap) add(path string, n *node) {
+	m.spans[path] = n.span
+	m.entries[path] = n.entry
+	for k, f := range n.fields {
+		m.add(path+"."+k, f)
+	}
+	for i, e := range n.elems {
+		m.add(path+"["+strconv.Itoa(i)+"]", e)
+	}
+}
+
+var _ vm.Tracer = &Recorder{}
@@ -0,0 +1,238 @@
+package sourcemap
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+func intOps(n int) []vm.Op {
+	ops := []vm.Op{vm.Inew}
+	for i := 7; i >= 0; i-- {
+		ops = append(ops, vm.Ishl)
+		if n&(1<<uint(i)) != 0 {
+			ops = append(ops, vm.Iinc)
+		}
+	}
+	return ops
+}
+
+func stringOps(s string) []vm.Op {
+	ops := []vm.Op{vm.Snew}
+	for i := 0; i < len(s); i++ {
+		ops = append(ops, intOps(int(s[i]))...)
+		ops = append(ops, vm.Sadd)
+	}
+	return ops
+}
+
+func concat(groups ...[]vm.Op) []vm.Op {
+	var ops []vm.Op
+	for _, g := range groups {
+		ops = append(ops, g...)
+	}
+	return ops
+}
+
+// watsonLines encodes each element of lines into a separate line.
+func watsonLines(t *testing.T, lines ...[]vm.Op) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	u := lexer.NewUnlexer(buf)
+	for _, line := range lines {
+		for _, op := range line {
+			if err := u.Write(op); err != nil {
+				t.Fatal(err)
+			}
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+func record(t *testing.T, src []byte) (*types.Value, *SourceMap) {
+	t.Helper()
+	rec := NewRecorder()
+	m := vm.NewVM(vm.WithTracer(rec))
+	lex := lexer.NewLexer(bytes.NewReader(src), lexer.WithFileName("a.watson"))
+	for {
+		tok, err := lex.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if err := rec.Feed(m, tok); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v, err := m.Top()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm, err := rec.SourceMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v, sm
+}
+
+func pos(line, col int) Position {
+	return Position{FileName: "a.watson", Line: line, Column: col}
+}
+
+func TestRecorderRecordsSpansOfValues(t *testing.T) {
+	key := stringOps("k")
+	arr := stringOps("arr")
+	sum := concat(intOps(1), intOps(2), []vm.Op{vm.Iadd})
+	src := watsonLines(t,
+		[]vm.Op{vm.Onew},
+		concat(key, intOps(1), []vm.Op{vm.Oadd}),
+		concat(arr, []vm.Op{vm.Anew, vm.Bnew, vm.Bneg, vm.Aadd}, sum, []vm.Op{vm.Aadd, vm.Oadd}),
+	)
+	_, sm := record(t, src)
+
+	want := map[string]Span{
+		"<root>":        {Start: pos(0, 0), End: pos(2, len(arr)+4+len(sum)+1)},
+		"<root>.k":      {Start: pos(1, len(key)), End: pos(1, len(key)+len(intOps(1))-1)},
+		"<root>.arr":    {Start: pos(2, len(arr)), End: pos(2, len(arr)+4+len(sum))},
+		"<root>.arr[0]": {Start: pos(2, len(arr)+1), End: pos(2, len(arr)+2)},
+		"<root>.arr[1]": {Start: pos(2, len(arr)+4), End: pos(2, len(arr)+4+len(sum)-1)},
+	}
+	if diff := cmp.Diff([]string{"<root>", "<root>.arr", "<root>.arr[0]", "<root>.arr[1]", "<root>.k"}, sm.Paths()); diff != "" {
+		t.Fatalf("paths mismatch (-want +got):\n%s", diff)
+	}
+	for path, span := range want {
+		got, ok := sm.Lookup(path)
+		if !ok {
+			t.Errorf("%s is not found", path)
+			continue
+		}
+		if diff := cmp.Diff(span, got); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", path, diff)
+		}
+	}
+
+	wantEntries := map[string]Span{
+		"<root>.k":      {Start: pos(1, 0), End: pos(1, len(key)+len(intOps(1)))},
+		"<root>.arr":    {Start: pos(2, 0), End: pos(2, len(arr)+4+len(sum)+1)},
+		"<root>.arr[1]": {Start: pos(2, len(arr)+4), End: pos(2, len(arr)+4+len(sum))},
+	}
+	for path, span := range wantEntries {
+		got, ok := sm.LookupEntry(path)
+		if !ok {
+			t.Errorf("entry of %s is not found", path)
+			continue
+		}
+		if diff := cmp.Diff(span, got); diff != "" {
+			t.Errorf("entry of %s: mismatch (-want +got):\n%s", path, diff)
+		}
+	}
+	if _, ok := sm.LookupEntry("<root>"); ok {
+		t.Errorf("<root> must not have an entry")
+	}
+}
+
+func TestLookupNearestFallsBackToParents(t *testing.T) {
+	arr := stringOps("arr")
+	src := watsonLines(t,
+		[]vm.Op{vm.Onew},
+		concat(arr, []vm.Op{vm.Anew, vm.Onew, vm.Aadd, vm.Oadd}),
+	)
+	_, sm := record(t, src)
+	for path, want := range map[string]string{
+		"<root>.arr[0]":        "<root>.arr[0]",
+		"<root>.arr[0].port":   "<root>.arr[0]",
+		"<root>.arr[1].port":   "<root>.arr",
+		"<root>.missing.a.b":   "<root>",
+		"<root>.arr[0].a[3].b": "<root>.arr[0]",
+	} {
+		got, ok := sm.LookupNearest(path)
+		if !ok {
+			t.Errorf("%s is not found", path)
+			continue
+		}
+		if span, _ := sm.Lookup(want); got != span {
+			t.Errorf("%s: expected the span of %s but got %#v", path, want, got)
+		}
+	}
+	if _, ok := sm.LookupNearest("unknown"); ok {
+		t.Errorf("paths outside <root> must not be found")
+	}
+}
+
+func TestRecorderFollowsGenericOps(t *testing.T) {
+	// [nil, true], whose nil is pushed before the array.
+	src := watsonLines(t,
+		[]vm.Op{vm.Nnew},
+		[]vm.Op{vm.Anew},
+		[]vm.Op{vm.Gswp, vm.Aadd, vm.Bnew, vm.Bneg, vm.Gdup, vm.Gpop, vm.Aadd},
+	)
+	v, sm := record(t, src)
+	if diff := cmp.Diff(types.NewArrayValue([]*types.Value{types.NewNilValue(), types.NewBoolValue(true)}), v); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if got, _ := sm.Lookup("<root>[0]"); got != (Span{Start: pos(0, 0), End: pos(0, 0)}) {
+		t.Errorf("unexpected span of nil: %#v", got)
+	}
+	if got, _ := sm.Lookup("<root>[1]"); got != (Span{Start: pos(2, 2), End: pos(2, 3)}) {
+		t.Errorf("unexpected span of true: %#v", got)
+	}
+}
+
+func TestAnnotateCitesPositionsOfBindErrors(t *testing.T) {
+	key := stringOps("port")
+	src := watsonLines(t,
+		[]vm.Op{vm.Onew},
+		concat([]vm.Op{vm.Nnew, vm.Gpop}, key, stringOps("80"), []vm.Op{vm.Oadd}),
+	)
+	v, sm := record(t, src)
+	var conf struct {
+		Port int `watson:"port"`
+	}
+	err := sm.Annotate(v.Bind(&conf))
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expected *Error but got %v", err)
+	}
+	if want := "a.watson:2:" + strconv.Itoa(3+len(key)); e.Span.String() != want {
+		t.Errorf("unexpected position: %s", e.Span.String())
+	}
+	var tm *types.TypeMismatch
+	if !errors.As(err, &tm) {
+		t.Errorf("expected the error to wrap TypeMismatch but got %v", err)
+	}
+
+	plain := errors.New("no path")
+	if sm.Annotate(plain) != plain || sm.Annotate(nil) != nil {
+		t.Errorf("errors without paths must be returned as is")
+	}
+}
+
+func TestPositionString(t *testing.T) {
+	if s := (Position{FileName: "a.watson", Line: 0, Column: 2}).String(); s != "a.watson:1:3" {
+		t.Errorf("unexpected string: %s", s)
+	}
+	if s := (Position{Line: 4, Column: 0}).String(); s != "5:1" {
+		t.Errorf("unexpected string: %s", s)
+	}
+}
+
+func TestRecorderReportsErrorsOfVM(t *testing.T) {
+	rec := NewRecorder()
+	m := vm.NewVM(vm.WithTracer(rec))
+	if err := rec.Feed(m, &lexer.Token{Op: vm.Gpop}); err != vm.ErrStackEmpty {
+		t.Fatalf("expected ErrStackEmpty but got %v", err)
+	}
+	if _, err := rec.SourceMap(); err != vm.ErrStackEmpty {
+		t.Errorf("expected ErrStackEmpty but got %v", err)
+	}
+}
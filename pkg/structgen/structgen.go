@@ -0,0 +1,319 @@
+// Package structgen generates Go struct definitions from sample Values.
+//
+// Each field of the generated structs has a `watson:"key"` tag so that the structs can be bound by watson.Unmarshal.
+// Types of fields are inferred by the following rules:
+//   * Int, Uint, Float, String, and Bool are converted into int64, uint64, float64, string, and bool respectively.
+//   * Objects are converted into named structs.
+//   * Arrays are converted into slices. Shapes of all elements are unified, so Objects in an Array are converted into one struct that has all of their keys.
+//   * Values that are Nil in some samples are converted into pointers, except for slices and interface{}.
+//   * Values that have two or more different Kinds, and values that are always Nil, are converted into interface{}.
+//
+// A key that is missing in some samples of an Object is tagged with `omitempty`.
+package structgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+// DefaultTypeName is the name of the top-level struct by default.
+const DefaultTypeName = "Root"
+
+// DefaultPackageName is the name of the package of the generated code by default.
+const DefaultPackageName = "main"
+
+// Option configures Generate.
+type Option interface {
+	apply(*config)
+}
+
+type option func(*config)
+
+func (opt option) apply(c *config) {
+	opt(c)
+}
+
+// WithTypeName sets the name of the top-level type.
+func WithTypeName(name string) Option {
+	return option(func(c *config) {
+		c.typeName = name
+	})
+}
+
+// WithPackageName sets the name of the package of the generated code.
+func WithPackageName(name string) Option {
+	return option(func(c *config) {
+		c.packageName = name
+	})
+}
+
+type config struct {
+	typeName    string
+	packageName string
+}
+
+// Generate writes gofmt-formatted Go source that defines types that can hold all of samples to w.
+func Generate(w io.Writer, samples []*types.Value, opts ...Option) error {
+	c := &config{
+		typeName:    DefaultTypeName,
+		packageName: DefaultPackageName,
+	}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	root := newShape()
+	for _, v := range samples {
+		root.observe(v)
+	}
+	g := &generator{
+		used: map[string]bool{},
+	}
+	g.buf.WriteString(fmt.Sprintf("package %s\n", c.packageName))
+	if root.onlyObjects() {
+		g.define(c.typeName, root)
+	} else {
+		g.buf.WriteString(fmt.Sprintf("\ntype %s %s\n", c.typeName, g.typeOf(c.typeName, "", root)))
+	}
+	for len(g.queue) > 0 {
+		d := g.queue[0]
+		g.queue = g.queue[1:]
+		g.define(d.name, d.shape)
+	}
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		// This should not happen.
+		return fmt.Errorf("generated invalid source: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// shape is a union of Values observed at the same position.
+type shape struct {
+	kinds    map[types.Kind]bool
+	nullable bool
+	objects  int
+	fields   map[string]*field
+	elem     *shape
+}
+
+type field struct {
+	count int
+	shape *shape
+}
+
+func newShape() *shape {
+	return &shape{
+		kinds:  map[types.Kind]bool{},
+		fields: map[string]*field{},
+	}
+}
+
+func (s *shape) observe(v *types.Value) {
+	switch v.Kind {
+	case types.Nil:
+		s.nullable = true
+		return
+	case types.Object:
+		s.objects++
+		for k, elem := range v.Object() {
+			f, ok := s.fields[k]
+			if !ok {
+				f = &field{shape: newShape()}
+				s.fields[k] = f
+			}
+			f.count++
+			f.shape.observe(elem)
+		}
+	case types.Array:
+		if s.elem == nil {
+			s.elem = newShape()
+		}
+		for _, elem := range v.Array() {
+			s.elem.observe(elem)
+		}
+	}
+	s.kinds[v.Kind] = true
+}
+
+func (s *shape) onlyObjects() bool {
+	return len(s.kinds) == 1 && s.kinds[types.Object] && !s.nullable
+}
+
+type definition struct {
+	name  string
+	shape *shape
+}
+
+type generator struct {
+	buf   bytes.Buffer
+	used  map[string]bool
+	queue []definition
+}
+
+func (g *generator) define(name string, s *shape) {
+	g.used[name] = true
+	keys := make([]string, 0, len(s.fields))
+	for k := range s.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	names := map[string]bool{}
+	fmt.Fprintf(&g.buf, "\ntype %s struct {\n", name)
+	for _, k := range keys {
+		if !isTaggable(k) {
+			fmt.Fprintf(&g.buf, "// The key %s can't be represented by a tag.\n", strconv.Quote(k))
+			continue
+		}
+		f := s.fields[k]
+		fieldName := uniqueName(fieldNameOf(k), names)
+		names[fieldName] = true
+		attrs := ""
+		if f.count < s.objects {
+			attrs = ",omitempty"
+		}
+		tag := fmt.Sprintf("watson:%s", strconv.Quote(k+attrs))
+		fmt.Fprintf(&g.buf, "%s %s `%s`\n", fieldName, g.typeOf(name, fieldName, f.shape), tag)
+	}
+	g.buf.WriteString("}\n")
+}
+
+// typeOf returns the type of s, which is at the field fieldName of the type parent.
+func (g *generator) typeOf(parent, fieldName string, s *shape) string {
+	if len(s.kinds) != 1 {
+		return "interface{}"
+	}
+	var t string
+	for kind := range s.kinds {
+		switch kind {
+		case types.Int:
+			t = "int64"
+		case types.Uint:
+			t = "uint64"
+		case types.Float:
+			t = "float64"
+		case types.String:
+			t = "string"
+		case types.Bool:
+			t = "bool"
+		case types.Object:
+			t = g.reserve(parent, fieldName, s)
+		case types.Array:
+			return "[]" + g.typeOf(parent, singularize(fieldName), s.elem)
+		}
+	}
+	if s.nullable {
+		return "*" + t
+	}
+	return t
+}
+
+// reserve reserves a name of a new struct and returns it.
+func (g *generator) reserve(parent, fieldName string, s *shape) string {
+	candidates := []string{fieldName, parent + fieldName}
+	if fieldName == "" {
+		candidates = []string{parent + "Elem"}
+	}
+	name := ""
+	for _, c := range candidates {
+		if !g.used[c] {
+			name = c
+			break
+		}
+	}
+	if name == "" {
+		name = uniqueName(candidates[len(candidates)-1], g.used)
+	}
+	g.used[name] = true
+	g.queue = append(g.queue, definition{name: name, shape: s})
+	return name
+}
+
+func uniqueName(name string, used map[string]bool) string {
+	if !used[name] {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := name + strconv.Itoa(i)
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
+
+// isTaggable reports whether k can be represented by a `watson` tag.
+func isTaggable(k string) bool {
+	return k != "" && k != "-" && !strings.ContainsAny(k, ",`")
+}
+
+var initialisms = map[string]bool{
+	"API": true, "CPU": true, "CSS": true, "DNS": true, "HTML": true, "HTTP": true, "HTTPS": true,
+	"ID": true, "IP": true, "JSON": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true, "TTL": true,
+	"UDP": true, "UI": true, "URI": true, "URL": true, "UUID": true, "XML": true, "YAML": true,
+}
+
+// fieldNameOf converts a key into an exported identifier, e.g. "server_url" into "ServerURL".
+func fieldNameOf(key string) string {
+	words := make([]string, 0)
+	word := []rune{}
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = []rune{}
+		}
+	}
+	var prev rune
+	for _, r := range key {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+		prev = r
+	}
+	flush()
+	name := strings.Builder{}
+	for _, w := range words {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			name.WriteString(upper)
+			continue
+		}
+		rs := []rune(w)
+		name.WriteRune(unicode.ToUpper(rs[0]))
+		name.WriteString(string(rs[1:]))
+	}
+	s := name.String()
+	if s == "" {
+		return "Field"
+	}
+	if unicode.IsDigit([]rune(s)[0]) {
+		return "X" + s
+	}
+	return s
+}
+
+// singularize converts a plural noun into its singular form in a naive way, e.g. "Servers" into "Server".
+func singularize(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "sses"), strings.HasSuffix(name, "xes"):
+		return name[:len(name)-2]
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") && len(name) > 1:
+		return name[:len(name)-1]
+	default:
+		return name
+	}
+}
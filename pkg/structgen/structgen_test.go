@@ -0,0 +1,154 @@
+package structgen
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func str(s string) *types.Value {
+	return types.NewStringValue([]byte(s))
+}
+
+var sample = types.NewObjectValue(map[string]*types.Value{
+	"name":       str("app"),
+	"server_url": str("http://localhost"),
+	"replicas":   types.NewIntValue(3),
+	"ratio":      types.NewFloatValue(0.5),
+	"owner":      types.NewNilValue(),
+	"labels":     types.NewArrayValue([]*types.Value{}),
+	"mixed":      types.NewArrayValue([]*types.Value{types.NewIntValue(1), str("x")}),
+	"servers": types.NewArrayValue([]*types.Value{
+		types.NewObjectValue(map[string]*types.Value{
+			"host": str("a"),
+			"port": types.NewIntValue(80),
+		}),
+		types.NewObjectValue(map[string]*types.Value{
+			"host": str("b"),
+			"tls":  types.NewObjectValue(map[string]*types.Value{"enabled": types.NewBoolValue(true)}),
+		}),
+		types.NewObjectValue(map[string]*types.Value{
+			"host": str("c"),
+			"tls":  types.NewNilValue(),
+		}),
+	}),
+})
+
+func TestGenerate(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := Generate(buf, []*types.Value{sample}, WithTypeName("Config"), WithPackageName("config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "package config\n" +
+		"\n" +
+		"type Config struct {\n" +
+		"\tLabels    []interface{} `watson:\"labels\"`\n" +
+		"\tMixed     []interface{} `watson:\"mixed\"`\n" +
+		"\tName      string        `watson:\"name\"`\n" +
+		"\tOwner     interface{}   `watson:\"owner\"`\n" +
+		"\tRatio     float64       `watson:\"ratio\"`\n" +
+		"\tReplicas  int64         `watson:\"replicas\"`\n" +
+		"\tServerURL string        `watson:\"server_url\"`\n" +
+		"\tServers   []Server      `watson:\"servers\"`\n" +
+		"}\n" +
+		"\n" +
+		"type Server struct {\n" +
+		"\tHost string `watson:\"host\"`\n" +
+		"\tPort int64  `watson:\"port,omitempty\"`\n" +
+		"\tTLS  *TLS   `watson:\"tls,omitempty\"`\n" +
+		"}\n" +
+		"\n" +
+		"type TLS struct {\n" +
+		"\tEnabled bool `watson:\"enabled\"`\n" +
+		"}\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// The types below are copied from the output of TestGenerate.
+
+type Config struct {
+	Labels    []interface{} `watson:"labels"`
+	Mixed     []interface{} `watson:"mixed"`
+	Name      string        `watson:"name"`
+	Owner     interface{}   `watson:"owner"`
+	Ratio     float64       `watson:"ratio"`
+	Replicas  int64         `watson:"replicas"`
+	ServerURL string        `watson:"server_url"`
+	Servers   []Server      `watson:"servers"`
+}
+
+type Server struct {
+	Host string `watson:"host"`
+	Port int64  `watson:"port,omitempty"`
+	TLS  *TLS   `watson:"tls,omitempty"`
+}
+
+type TLS struct {
+	Enabled bool `watson:"enabled"`
+}
+
+func TestGeneratedTypesCanBeBound(t *testing.T) {
+	var got Config
+	err := sample.Bind(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{
+		Labels:    []interface{}{},
+		Mixed:     []interface{}{int64(1), "x"},
+		Name:      "app",
+		Ratio:     0.5,
+		Replicas:  3,
+		ServerURL: "http://localhost",
+		Servers: []Server{
+			{Host: "a", Port: 80},
+			{Host: "b", TLS: &TLS{Enabled: true}},
+			{Host: "c"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateNonObjectRoot(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	err := Generate(buf, []*types.Value{
+		types.NewArrayValue([]*types.Value{
+			types.NewObjectValue(map[string]*types.Value{"id": types.NewUintValue(1)}),
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "package main\n" +
+		"\n" +
+		"type Root []RootElem\n" +
+		"\n" +
+		"type RootElem struct {\n" +
+		"\tID uint64 `watson:\"id\"`\n" +
+		"}\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFieldNameOf(t *testing.T) {
+	test := func(key, want string) {
+		if got := fieldNameOf(key); got != want {
+			t.Errorf("%#v: expected %#v but got %#v", key, want, got)
+		}
+	}
+	test("name", "Name")
+	test("server-url", "ServerURL")
+	test("userId", "UserID")
+	test("HTTPServer", "HTTPServer")
+	test("2fa", "X2fa")
+	test("$$", "Field")
+}
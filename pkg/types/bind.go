@@ -57,7 +57,7 @@ func bindInt(v *Value, to *int, path path) error {
 			path: path,
 		}
 	}
-	*to = int(v.Int)
+	*to = int(v.Int())
 	return nil
 }
 
@@ -69,7 +69,7 @@ func bindInt8(v *Value, to *int8, path path) error {
 			path: path,
 		}
 	}
-	*to = int8(v.Int)
+	*to = int8(v.Int())
 	return nil
 }
 
@@ -81,7 +81,7 @@ func bindInt16(v *Value, to *int16, path path) error {
 			path: path,
 		}
 	}
-	*to = int16(v.Int)
+	*to = int16(v.Int())
 	return nil
 }
 
@@ -93,7 +93,7 @@ func bindInt32(v *Value, to *int32, path path) error {
 			path: path,
 		}
 	}
-	*to = int32(v.Int)
+	*to = int32(v.Int())
 	return nil
 }
 
@@ -105,7 +105,7 @@ func bindInt64(v *Value, to *int64, path path) error {
 			path: path,
 		}
 	}
-	*to = int64(v.Int)
+	*to = int64(v.Int())
 	return nil
 }
 
@@ -117,7 +117,7 @@ func bindUint(v *Value, to *uint, path path) error {
 			path: path,
 		}
 	}
-	*to = uint(v.Uint)
+	*to = uint(v.Uint())
 	return nil
 }
 
@@ -129,7 +129,7 @@ func bindUint8(v *Value, to *uint8, path path) error {
 			path: path,
 		}
 	}
-	*to = uint8(v.Uint)
+	*to = uint8(v.Uint())
 	return nil
 }
 
@@ -141,7 +141,7 @@ func bindUint16(v *Value, to *uint16, path path) error {
 			path: path,
 		}
 	}
-	*to = uint16(v.Uint)
+	*to = uint16(v.Uint())
 	return nil
 }
 
@@ -153,7 +153,7 @@ func bindUint32(v *Value, to *uint32, path path) error {
 			path: path,
 		}
 	}
-	*to = uint32(v.Uint)
+	*to = uint32(v.Uint())
 	return nil
 }
 
@@ -165,7 +165,7 @@ func bindUint64(v *Value, to *uint64, path path) error {
 			path: path,
 		}
 	}
-	*to = uint64(v.Uint)
+	*to = uint64(v.Uint())
 	return nil
 }
 
@@ -177,7 +177,7 @@ func bindFloat32(v *Value, to *float32, path path) error {
 			path: path,
 		}
 	}
-	*to = float32(v.Float)
+	*to = float32(v.Float())
 	return nil
 }
 
@@ -189,7 +189,7 @@ func bindFloat64(v *Value, to *float64, path path) error {
 			path: path,
 		}
 	}
-	*to = float64(v.Float)
+	*to = float64(v.Float())
 	return nil
 }
 
@@ -201,7 +201,7 @@ func bindString(v *Value, to *string, path path) error {
 			path: path,
 		}
 	}
-	*to = string(v.String)
+	*to = string(v.Bytes())
 	return nil
 }
 
@@ -213,7 +213,7 @@ func bindBool(v *Value, to *bool, path path) error {
 			path: path,
 		}
 	}
-	*to = v.Bool
+	*to = v.Bool()
 	return nil
 }
 
@@ -235,7 +235,7 @@ func (v *Value) bindToUnmarshalerByReflection(to reflect.Value, path path) error
 	unmarshal := to.MethodByName("UnmarshalWatson")
 	ret := unmarshal.Call([]reflect.Value{reflect.ValueOf(v)})[0].Interface()
 	if err, ok := ret.(error); ok {
-		return err
+		return RebaseError(err, path.string())
 	}
 	return nil
 }
@@ -311,7 +311,7 @@ func (v *Value) castToInt(t reflect.Type, path path) (reflect.Value, error) {
 			path: path,
 		}
 	}
-	return reflect.ValueOf(int(v.Int)), nil
+	return reflect.ValueOf(int(v.Int())), nil
 }
 
 func (v *Value) castToInt8(t reflect.Type, path path) (reflect.Value, error) {
@@ -322,7 +322,7 @@ func (v *Value) castToInt8(t reflect.Type, path path) (reflect.Value, error) {
 			path: path,
 		}
 	}
-	return reflect.ValueOf(int8(v.Int)), nil
+	return reflect.ValueOf(int8(v.Int())), nil
 }
 
 func (v *Value) castToInt16(t reflect.Type, path path) (reflect.Value, error) {
@@ -333,7 +333,7 @@ func (v *Value) castToInt16(t reflect.Type, path path) (reflect.Value, error) {
 			path: path,
 		}
 	}
-	return reflect.ValueOf(int16(v.Int)), nil
+	return reflect.ValueOf(int16(v.Int())), nil
 }
 
 func (v *Value) castToInt32(t reflect.Type, path path) (reflect.Value, error) {
@@ -344,7 +344,7 @@ func (v *Value) castToInt32(t reflect.Type, path path) (reflect.Value, error) {
 			path: path,
 		}
 	}
-	return reflect.ValueOf(int32(v.Int)), nil
+	return reflect.ValueOf(int32(v.Int())), nil
 }
 
 func (v *Value) castToInt64(t reflect.Type, path path) (reflect.Value, error) {
@@ -355,7 +355,7 @@ func (v *Value) castToInt64(t reflect.Type, path path) (reflect.Value, error) {
 			path: path,
 		}
 	}
-	return reflect.ValueOf(int64(v.Int)), nil
+	return reflect.ValueOf(int64(v.Int())), nil
 }
 
 func (v *Value) castToUint(t reflect.Type, path path) (reflect.Value, error) {
@@ -366,7 +366,7 @@ func (v *Value) castToUint(t reflect.Type, path path) (reflect.Value, error) {
 			path: path,
 		}
 	}
-	return reflect.ValueOf(uint(v.Uint)), nil
+	return reflect.ValueOf(uint(v.Uint())), nil
 }
 
 func (v *Value) castToUint8(t reflect.Type, path path) (reflect.Value, error) {
@@ -377,7 +377,7 @@ func (v *Value) castToUint8(t reflect.Type, path path) (reflect.Value, error) {
 			path: path,
 		}
 	}
-	return reflect.ValueOf(uint8(v.Uint)), nil
+	return reflect.ValueOf(uint8(v.Uint())), nil
 }
 
 func (v *Value) castToUint16(t reflect.Type, path path) (reflect.Value, error) {
@@ -388,7 +388,7 @@ func (v *Value) castToUint16(t reflect.Type, path path) (reflect.Value, error) {
 			path: path,
 		}
 	}
-	return reflect.ValueOf(uint16(v.Uint)), nil
+	return reflect.ValueOf(uint16(v.Uint())), nil
 }
 
 func (v *Value) castToUint32(t reflect.Type, path path) (reflect.Value, error) {
@@ -399,7 +399,7 @@ func (v *Value) castToUint32(t reflect.Type, path path) (reflect.Value, error) {
 			path: path,
 		}
 	}
-	return reflect.ValueOf(uint32(v.Uint)), nil
+	return reflect.ValueOf(uint32(v.Uint())), nil
 }
 
 func (v *Value) castToUint64(t reflect.Type, path path) (reflect.Value, error) {
@@ -410,7 +410,7 @@ func (v *Value) castToUint64(t reflect.Type, path path) (reflect.Value, error) {
 			path: path,
 		}
 	}
-	return reflect.ValueOf(uint64(v.Uint)), nil
+	return reflect.ValueOf(uint64(v.Uint())), nil
 }
 
 func (v *Value) castToFloat32(t reflect.Type, path path) (reflect.Value, error) {
@@ -421,7 +421,7 @@ func (v *Value) castToFloat32(t reflect.Type, path path) (reflect.Value, error)
 			path: path,
 		}
 	}
-	return reflect.ValueOf(float32(v.Float)), nil
+	return reflect.ValueOf(float32(v.Float())), nil
 }
 
 func (v *Value) castToFloat64(t reflect.Type, path path) (reflect.Value, error) {
@@ -432,7 +432,7 @@ func (v *Value) castToFloat64(t reflect.Type, path path) (reflect.Value, error)
 			path: path,
 		}
 	}
-	return reflect.ValueOf(v.Float), nil
+	return reflect.ValueOf(v.Float()), nil
 }
 
 func (v *Value) castToString(t reflect.Type, path path) (reflect.Value, error) {
@@ -443,7 +443,7 @@ func (v *Value) castToString(t reflect.Type, path path) (reflect.Value, error) {
 			path: path,
 		}
 	}
-	return reflect.ValueOf(string(v.String)), nil
+	return reflect.ValueOf(string(v.Bytes())), nil
 }
 
 func (v *Value) castToBool(t reflect.Type, path path) (reflect.Value, error) {
@@ -454,7 +454,7 @@ func (v *Value) castToBool(t reflect.Type, path path) (reflect.Value, error) {
 			path: path,
 		}
 	}
-	return reflect.ValueOf(v.Bool), nil
+	return reflect.ValueOf(v.Bool()), nil
 }
 
 func (v *Value) castToSlice(t reflect.Type, path path) (reflect.Value, error) {
@@ -462,7 +462,7 @@ func (v *Value) castToSlice(t reflect.Type, path path) (reflect.Value, error) {
 		return reflect.Zero(t), nil
 	}
 	if v.Kind == Array {
-		arr := reflect.MakeSlice(t, len(v.Array), len(v.Array))
+		arr := reflect.MakeSlice(t, len(v.Array()), len(v.Array()))
 		err := v.setToArray(arr, path)
 		if err != nil {
 			return reflect.Value{}, err
@@ -484,7 +484,7 @@ func (v *Value) castToArray(t reflect.Type, path path) (reflect.Value, error) {
 			path: path,
 		}
 	}
-	if len(v.Array) > t.Len() {
+	if len(v.Array()) > t.Len() {
 		return reflect.Value{}, &TypeMismatch{
 			val:  v,
 			t:    t,
@@ -509,7 +509,7 @@ func (v *Value) setToArray(arr reflect.Value, path path) error {
 		}
 	}
 	elemType := t.Elem()
-	for i, e := range v.Array {
+	for i, e := range v.Array() {
 		elem, err := e.cast(elemType, newIndexPath(path, i))
 		if err != nil {
 			return err
@@ -556,7 +556,7 @@ func (v *Value) addToMap(obj reflect.Value, path path) error {
 			path: path,
 		}
 	}
-	for k, e := range v.Object {
+	for k, e := range v.Object() {
 		key := reflect.ValueOf(k)
 		elem, err := e.cast(elemType, newFieldPath(path, k))
 		if err != nil {
@@ -605,7 +605,7 @@ func (v *Value) castToStruct(t reflect.Type, path path) (reflect.Value, error) {
 	}
 	pobj := reflect.New(t)
 	obj := pobj.Elem()
-	for k, v := range v.Object {
+	for k, v := range v.Object() {
 		tag, ok := findField(k, obj)
 		if !ok {
 			continue
@@ -614,14 +614,14 @@ func (v *Value) castToStruct(t reflect.Type, path path) (reflect.Value, error) {
 			continue
 		}
 		field := tag.FieldOf(obj)
-		err := v.BindByReflection(field.Addr())
+		err := v.bindByReflection(field.Addr(), newFieldPath(path, k))
 		if err != nil {
 			return reflect.Value{}, err
 		}
 	}
 	for _, tag := range inlineFields(obj) {
 		field := tag.FieldOf(obj)
-		err := v.BindByReflection(field.Addr())
+		err := v.bindByReflection(field.Addr(), path)
 		if err != nil {
 			return reflect.Value{}, err
 		}
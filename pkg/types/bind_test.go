@@ -1,6 +1,7 @@
 package types_test
 
 import (
+	"errors"
 	"reflect"
 	"regexp"
 	"testing"
@@ -1277,3 +1278,45 @@ func TestBindByReflectionReturnsErrorWhenTypeMismatchInArrayElem(t *testing.T) {
 		t.Errorf("expected \"%s\" to match /%s/, but it didn't", err.Error(), pat.String())
 	}
 }
+
+func TestBindReturnsErrorWhenTypeMismatchInStructField(t *testing.T) {
+	var err error
+	var val = types.NewObjectValue(map[string]*types.Value{
+		"servers": types.NewArrayValue([]*types.Value{
+			types.NewObjectValue(map[string]*types.Value{"port": types.NewStringValue([]byte("80"))}),
+		}),
+	})
+	var bindTo struct {
+		Servers []struct {
+			Port int `watson:"port"`
+		} `watson:"servers"`
+	}
+	err = val.Bind(&bindTo)
+	var tm *types.TypeMismatch
+	if !errors.As(err, &tm) {
+		t.Fatalf("expected TypeMismatch but got %v", err)
+	}
+	if tm.Path() != "<root>.servers[0].port" {
+		t.Errorf("unexpected path: %s", tm.Path())
+	}
+}
+
+func TestRebaseErrorPlacesPathUnderParent(t *testing.T) {
+	err := types.NewTypeMismatch(types.NewNilValue(), reflect.TypeOf(0), "<root>.port")
+	rebased := types.RebaseError(err, "<root>.servers[1]")
+	var tm *types.TypeMismatch
+	if !errors.As(rebased, &tm) {
+		t.Fatalf("expected TypeMismatch but got %v", rebased)
+	}
+	if tm.Path() != "<root>.servers[1].port" {
+		t.Errorf("unexpected path: %s", tm.Path())
+	}
+	if err.Path() != "<root>.port" {
+		t.Errorf("the original error is modified: %s", err.Path())
+	}
+
+	other := errors.New("other")
+	if types.RebaseError(other, "<root>.a") != other {
+		t.Errorf("errors other than TypeMismatch must be returned as is")
+	}
+}
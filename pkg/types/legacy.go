@@ -0,0 +1,61 @@
+package types
+
+import "fmt"
+
+// Fields has the same layout as Value had before its payload was made unexported, so that code written against the exported fields can be migrated mechanically:
+//   * `&types.Value{Kind: types.Int, Int: 1}` becomes `types.Fields{Kind: types.Int, Int: 1}.Value()`.
+//   * `v.String` becomes `v.Fields().String`, and so on.
+//
+// Deprecated: use the constructors and the accessors of Value instead. Fields will be removed in the next release.
+type Fields struct {
+	Kind   Kind
+	Int    int64
+	Uint   uint64
+	Float  float64
+	String []byte
+	Object map[string]*Value
+	Array  []*Value
+	Bool   bool
+}
+
+// Value creates a new Value from the field that corresponds to f.Kind. The other fields are ignored.
+//
+// Deprecated: use the constructors such as NewIntValue instead.
+func (f Fields) Value() *Value {
+	switch f.Kind {
+	case Int:
+		return NewIntValue(f.Int)
+	case Uint:
+		return NewUintValue(f.Uint)
+	case Float:
+		return NewFloatValue(f.Float)
+	case String:
+		return NewStringValue(f.String)
+	case Object:
+		return NewObjectValue(f.Object)
+	case Array:
+		return NewArrayValue(f.Array)
+	case Bool:
+		return NewBoolValue(f.Bool)
+	case Nil:
+		return NewNilValue()
+	default:
+		panic(fmt.Errorf("invalid kind: %d", f.Kind))
+	}
+}
+
+// Fields returns the payload of v in the old layout. Strings, Objects and Arrays are shared with v.
+//
+// Deprecated: use the accessors such as Int and Bytes instead.
+func (v *Value) Fields() Fields {
+	return Fields{
+		Kind:   v.Kind,
+		Int:    v.Int(),
+		Uint:   v.Uint(),
+		Float:  v.Float(),
+		String: v.Bytes(),
+		Object: v.Object(),
+		Array:  v.Array(),
+		Bool:   v.Bool(),
+	}
+}
@@ -0,0 +1,128 @@
+package types
+
+// ObjectStrategy determines how Merge combines two Objects.
+type ObjectStrategy int
+
+const (
+	MergeObjects   ObjectStrategy = iota // merge two objects key by key recursively
+	ReplaceObjects                       // replace the base object with the overlay
+)
+
+// ArrayStrategy determines how Merge combines two Arrays.
+type ArrayStrategy int
+
+const (
+	ReplaceArrays ArrayStrategy = iota // replace the base array with the overlay
+	AppendArrays                       // append elements of the overlay to the base array
+)
+
+// NilStrategy determines how Merge treats Nil in the overlay.
+type NilStrategy int
+
+const (
+	NilReplaces NilStrategy = iota // Nil replaces the corresponding value of the base
+	NilDeletes                     // Nil deletes the corresponding key from the base object
+	NilIgnored                     // Nil leaves the corresponding value of the base as it is
+)
+
+// MergeOption configures the behavior of Merge.
+type MergeOption interface {
+	apply(*merger)
+}
+
+type mergeOption func(*merger)
+
+func (opt mergeOption) apply(m *merger) {
+	opt(m)
+}
+
+// WithObjectStrategy sets the strategy that is used when both of the base and the overlay are Objects.
+func WithObjectStrategy(s ObjectStrategy) MergeOption {
+	return mergeOption(func(m *merger) {
+		m.objects = s
+	})
+}
+
+// WithArrayStrategy sets the strategy that is used when both of the base and the overlay are Arrays.
+func WithArrayStrategy(s ArrayStrategy) MergeOption {
+	return mergeOption(func(m *merger) {
+		m.arrays = s
+	})
+}
+
+// WithNilStrategy sets the strategy that is used when the overlay is Nil.
+func WithNilStrategy(s NilStrategy) MergeOption {
+	return mergeOption(func(m *merger) {
+		m.nils = s
+	})
+}
+
+type merger struct {
+	objects ObjectStrategy
+	arrays  ArrayStrategy
+	nils    NilStrategy
+}
+
+// Merge returns a new Value that is made by laying overlay on top of base.
+// Neither base nor overlay is modified.
+//
+// By default, Objects are merged recursively, Arrays and any other values in the overlay replace the ones in the base,
+// and Nil in the overlay replaces the corresponding value in the base. These can be changed by MergeOptions.
+//
+// If overlay is Nil and NilDeletes is specified, Merge returns Nil since there is no key to delete.
+func Merge(base, overlay *Value, opts ...MergeOption) *Value {
+	m := &merger{}
+	for _, opt := range opts {
+		opt.apply(m)
+	}
+	return m.merge(base, overlay)
+}
+
+func (m *merger) merge(base, overlay *Value) *Value {
+	if overlay.Kind == Nil {
+		if m.nils == NilIgnored {
+			return base.DeepCopy()
+		}
+		return NewNilValue()
+	}
+	if base.Kind == Object && overlay.Kind == Object && m.objects == MergeObjects {
+		return m.mergeObjects(base.Object(), overlay.Object())
+	}
+	if base.Kind == Array && overlay.Kind == Array && m.arrays == AppendArrays {
+		arr := make([]*Value, 0, len(base.Array())+len(overlay.Array()))
+		for _, v := range base.Array() {
+			arr = append(arr, v.DeepCopy())
+		}
+		for _, v := range overlay.Array() {
+			arr = append(arr, v.DeepCopy())
+		}
+		return NewArrayValue(arr)
+	}
+	return overlay.DeepCopy()
+}
+
+func (m *merger) mergeObjects(base, overlay map[string]*Value) *Value {
+	obj := make(map[string]*Value, len(base))
+	for k, v := range base {
+		obj[k] = v.DeepCopy()
+	}
+	for k, v := range overlay {
+		if v.Kind == Nil {
+			switch m.nils {
+			case NilDeletes:
+				delete(obj, k)
+			case NilIgnored:
+				// nop
+			default:
+				obj[k] = NewNilValue()
+			}
+			continue
+		}
+		if orig, ok := base[k]; ok {
+			obj[k] = m.merge(orig, v)
+		} else {
+			obj[k] = v.DeepCopy()
+		}
+	}
+	return NewObjectValue(obj)
+}
@@ -0,0 +1,157 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeReplacesScalars(t *testing.T) {
+	base := NewIntValue(1)
+	overlay := NewStringValue([]byte("two"))
+	got := Merge(base, overlay)
+	if diff := cmp.Diff(overlay, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeMergesObjectsRecursively(t *testing.T) {
+	base := NewObjectValue(map[string]*Value{
+		"name": NewStringValue([]byte("app")),
+		"spec": NewObjectValue(map[string]*Value{
+			"replicas": NewIntValue(1),
+			"image":    NewStringValue([]byte("nginx")),
+		}),
+	})
+	overlay := NewObjectValue(map[string]*Value{
+		"spec": NewObjectValue(map[string]*Value{
+			"replicas": NewIntValue(3),
+		}),
+		"env": NewStringValue([]byte("prod")),
+	})
+	want := NewObjectValue(map[string]*Value{
+		"name": NewStringValue([]byte("app")),
+		"spec": NewObjectValue(map[string]*Value{
+			"replicas": NewIntValue(3),
+			"image":    NewStringValue([]byte("nginx")),
+		}),
+		"env": NewStringValue([]byte("prod")),
+	})
+	got := Merge(base, overlay)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeDoesNotModifyArguments(t *testing.T) {
+	base := NewObjectValue(map[string]*Value{
+		"a": NewObjectValue(map[string]*Value{"x": NewIntValue(1)}),
+	})
+	overlay := NewObjectValue(map[string]*Value{
+		"a": NewObjectValue(map[string]*Value{"y": NewIntValue(2)}),
+	})
+	origBase := base.DeepCopy()
+	origOverlay := overlay.DeepCopy()
+	got := Merge(base, overlay)
+	got.Object()["a"].Object()["x"] = NewIntValue(100)
+	got.Object()["a"].Object()["y"] = NewIntValue(200)
+	if diff := cmp.Diff(origBase, base); diff != "" {
+		t.Errorf("base is modified (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(origOverlay, overlay); diff != "" {
+		t.Errorf("overlay is modified (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeWithReplaceObjects(t *testing.T) {
+	base := NewObjectValue(map[string]*Value{
+		"a": NewIntValue(1),
+	})
+	overlay := NewObjectValue(map[string]*Value{
+		"b": NewIntValue(2),
+	})
+	got := Merge(base, overlay, WithObjectStrategy(ReplaceObjects))
+	if diff := cmp.Diff(overlay, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeReplacesArraysByDefault(t *testing.T) {
+	base := NewArrayValue([]*Value{NewIntValue(1), NewIntValue(2)})
+	overlay := NewArrayValue([]*Value{NewIntValue(3)})
+	got := Merge(base, overlay)
+	if diff := cmp.Diff(overlay, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeWithAppendArrays(t *testing.T) {
+	base := NewArrayValue([]*Value{NewIntValue(1), NewIntValue(2)})
+	overlay := NewArrayValue([]*Value{NewIntValue(3)})
+	want := NewArrayValue([]*Value{NewIntValue(1), NewIntValue(2), NewIntValue(3)})
+	got := Merge(base, overlay, WithArrayStrategy(AppendArrays))
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeWithNilReplaces(t *testing.T) {
+	base := NewObjectValue(map[string]*Value{
+		"a": NewIntValue(1),
+	})
+	overlay := NewObjectValue(map[string]*Value{
+		"a": NewNilValue(),
+		"b": NewNilValue(),
+	})
+	want := NewObjectValue(map[string]*Value{
+		"a": NewNilValue(),
+		"b": NewNilValue(),
+	})
+	got := Merge(base, overlay, WithNilStrategy(NilReplaces))
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeWithNilDeletes(t *testing.T) {
+	base := NewObjectValue(map[string]*Value{
+		"a": NewIntValue(1),
+		"b": NewIntValue(2),
+	})
+	overlay := NewObjectValue(map[string]*Value{
+		"a": NewNilValue(),
+		"c": NewNilValue(),
+	})
+	want := NewObjectValue(map[string]*Value{
+		"b": NewIntValue(2),
+	})
+	got := Merge(base, overlay, WithNilStrategy(NilDeletes))
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeWithNilIgnored(t *testing.T) {
+	base := NewObjectValue(map[string]*Value{
+		"a": NewIntValue(1),
+	})
+	overlay := NewObjectValue(map[string]*Value{
+		"a": NewNilValue(),
+		"b": NewNilValue(),
+	})
+	want := NewObjectValue(map[string]*Value{
+		"a": NewIntValue(1),
+	})
+	got := Merge(base, overlay, WithNilStrategy(NilIgnored))
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeWithNilIgnoredAtRoot(t *testing.T) {
+	base := NewIntValue(1)
+	got := Merge(base, NewNilValue(), WithNilStrategy(NilIgnored))
+	if diff := cmp.Diff(base, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -48,3 +48,13 @@ func newIndexPath(parent path, idx int) path {
 func (p *indexPath) string() string {
 	return fmt.Sprintf("%s[%d]", p.parent.string(), p.idx)
 }
+
+type literalPath string
+
+func newLiteralPath(s string) path {
+	return literalPath(s)
+}
+
+func (p literalPath) string() string {
+	return string(p)
+}
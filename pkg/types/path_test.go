@@ -30,3 +30,12 @@ func TestIndexPath(t *testing.T) {
 		t.Errorf("expected %#v but got %#v", expected, actual)
 	}
 }
+
+func TestLiteralPath(t *testing.T) {
+	path := newIndexPath(newLiteralPath("<root>.a"), 1)
+	expected := "<root>.a[1]"
+	actual := path.string()
+	if expected != actual {
+		t.Errorf("expected %#v but got %#v", expected, actual)
+	}
+}
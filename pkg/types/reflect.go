@@ -187,6 +187,40 @@ type TypeMismatch struct {
 	path path
 }
 
+// NewTypeMismatch creates a TypeMismatch that indicates that val at path can't be converted into t.
+// path must be written in the same form as the one in error messages, e.g. "<root>.a[1]".
+//
+// This is intended to be used by implementations of Unmarshaler, such as the ones generated by watson-gen.
+func NewTypeMismatch(val *Value, t reflect.Type, path string) *TypeMismatch {
+	return &TypeMismatch{
+		val:  val,
+		t:    t,
+		path: newLiteralPath(path),
+	}
+}
+
+// Path returns the path to the value that can't be converted, e.g. "<root>.a[1]".
+func (e *TypeMismatch) Path() string {
+	return e.path.string()
+}
+
+// RebaseError places the path of err under path if err is a *TypeMismatch, and returns it.
+// Other errors are returned as is.
+//
+// Unmarshalers report paths relative to the Value given to them, so this is intended to be used
+// to convert errors of Unmarshalers of nested values into the ones of their parents.
+func RebaseError(err error, path string) error {
+	e, ok := err.(*TypeMismatch)
+	if !ok {
+		return err
+	}
+	return &TypeMismatch{
+		val:  e.val,
+		t:    e.t,
+		path: newLiteralPath(path + strings.TrimPrefix(e.path.string(), "<root>")),
+	}
+}
+
 func (e *TypeMismatch) Error() string {
 	return fmt.Sprintf("can't convert %#v to %s (at %s)",
 		e.val.Kind, e.t.String(), e.path.string())
@@ -16,27 +16,27 @@ import (
 func (val *Value) ToGoObject() interface{} {
 	switch val.Kind {
 	case Int:
-		return val.Int
+		return val.Int()
 	case Uint:
-		return val.Uint
+		return val.Uint()
 	case Float:
-		return val.Float
+		return val.Float()
 	case String:
-		return string(val.String)
+		return string(val.Bytes())
 	case Object:
 		obj := map[string]interface{}{}
-		for k, v := range val.Object {
+		for k, v := range val.Object() {
 			obj[k] = v.ToGoObject()
 		}
 		return obj
 	case Array:
-		arr := make([]interface{}, 0, len(val.Array))
-		for _, v := range val.Array {
+		arr := make([]interface{}, 0, len(val.Array()))
+		for _, v := range val.Array() {
 			arr = append(arr, v.ToGoObject())
 		}
 		return arr
 	case Bool:
-		return val.Bool
+		return val.Bool()
 	case Nil:
 		return nil
 	default:
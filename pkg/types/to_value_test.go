@@ -209,7 +209,7 @@ func TestToValueConvertsFloat32(t *testing.T) {
 	if got.Kind != types.Float {
 		t.Fatalf("expected Float but got %#v", got)
 	}
-	if !closeEnough(want.Float, got.Float) {
+	if !closeEnough(want.Float(), got.Float()) {
 		t.Fatalf("expected %#v but got %#v", want, got)
 	}
 }
@@ -729,7 +729,7 @@ func TestToValueByReflectionConvertsFloat32(t *testing.T) {
 	if got.Kind != types.Float {
 		t.Fatalf("expected Float but got %#v", got)
 	}
-	if !closeEnough(want.Float, got.Float) {
+	if !closeEnough(want.Float(), got.Float()) {
 		t.Fatalf("expected %#v but got %#v", want, got)
 	}
 }
@@ -2,55 +2,61 @@
 package types
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 )
 
 // Value is an arbitrary value that can be represented as Watson.
+//
+// A Value only stores the payload of its Kind, which is read by the accessor of the Kind, e.g. Int for Int and Bytes for String.
+// Values are created by constructors such as NewIntValue, and their scalars can't be modified afterwards.
+// Code that used the exported fields of the old layout can be migrated via Fields.
 type Value struct {
-	Kind   Kind
-	Int    int64
-	Uint   uint64
-	Float  float64
-	String []byte
-	Object map[string]*Value
-	Array  []*Value
-	Bool   bool
+	Kind Kind
+	// scalar holds the payload of Int, Uint, Float and Bool.
+	scalar uint64
+	// ref holds the payload of String, Object and Array, which is []byte, map[string]*Value and []*Value respectively.
+	ref interface{}
 }
 
 // NewIntValue creates a new Value that contains an integer.
 func NewIntValue(val int64) *Value {
-	return &Value{Kind: Int, Int: val}
+	return &Value{Kind: Int, scalar: uint64(val)}
 }
 
 // NewUintValue creates a new Value that contains an unsigned integer.
 func NewUintValue(val uint64) *Value {
-	return &Value{Kind: Uint, Uint: val}
+	return &Value{Kind: Uint, scalar: val}
 }
 
 // NewFloatValue creates a new Value that contains a floating point number.
 func NewFloatValue(val float64) *Value {
-	return &Value{Kind: Float, Float: val}
+	return &Value{Kind: Float, scalar: math.Float64bits(val)}
 }
 
 // NewStringValue creates a new Value that contains a string.
 func NewStringValue(val []byte) *Value {
-	return &Value{Kind: String, String: val}
+	return &Value{Kind: String, ref: val}
 }
 
 // NewObjectValue creates a new Value that contains an object.
 func NewObjectValue(val map[string]*Value) *Value {
-	return &Value{Kind: Object, Object: val}
+	return &Value{Kind: Object, ref: val}
 }
 
 // NewArrayValue creates a new value that contains an array.
 func NewArrayValue(val []*Value) *Value {
-	return &Value{Kind: Array, Array: val}
+	return &Value{Kind: Array, ref: val}
 }
 
 // NewBoolValue creates a new Value that contains a bool.
 func NewBoolValue(val bool) *Value {
-	return &Value{Kind: Bool, Bool: val}
+	v := &Value{Kind: Bool}
+	if val {
+		v.scalar = 1
+	}
+	return v
 }
 
 // NewNilValue creates a new Value that contains nil.
@@ -58,42 +64,132 @@ func NewNilValue() *Value {
 	return &Value{Kind: Nil}
 }
 
+// Int returns the integer that v contains. It returns 0 if v is not an Int.
+func (v *Value) Int() int64 {
+	if v.Kind != Int {
+		return 0
+	}
+	return int64(v.scalar)
+}
+
+// Uint returns the unsigned integer that v contains. It returns 0 if v is not a Uint.
+func (v *Value) Uint() uint64 {
+	if v.Kind != Uint {
+		return 0
+	}
+	return v.scalar
+}
+
+// Float returns the floating point number that v contains. It returns 0 if v is not a Float.
+func (v *Value) Float() float64 {
+	if v.Kind != Float {
+		return 0
+	}
+	return math.Float64frombits(v.scalar)
+}
+
+// Bytes returns the string that v contains. It returns nil if v is not a String.
+// The returned slice is shared with v.
+func (v *Value) Bytes() []byte {
+	b, _ := v.ref.([]byte)
+	return b
+}
+
+// Object returns the object that v contains. It returns nil if v is not an Object.
+// The returned map is shared with v, so modifying it modifies v.
+func (v *Value) Object() map[string]*Value {
+	o, _ := v.ref.(map[string]*Value)
+	return o
+}
+
+// Array returns the array that v contains. It returns nil if v is not an Array.
+// The returned slice is shared with v, so modifying its elements modifies v.
+func (v *Value) Array() []*Value {
+	a, _ := v.ref.([]*Value)
+	return a
+}
+
+// Bool returns the bool that v contains. It returns false if v is not a Bool.
+func (v *Value) Bool() bool {
+	return v.Kind == Bool && v.scalar != 0
+}
+
 // IsNaN returns true if v is a NaN; otherwise it returns false.
 func (v *Value) IsNaN() bool {
-	return v.Kind == Float && math.IsNaN(v.Float)
+	return v.Kind == Float && math.IsNaN(v.Float())
 }
 
-// DeepCopy returns a deep copy of v.
-func (v *Value) DeepCopy() *Value {
-	clone := &Value{Kind: v.Kind}
+// Equal returns true if v and other have the same Kind and the same payload, comparing Objects and Arrays deeply.
+// Unlike ==, NaNs are equal to each other so that a Value is always equal to its DeepCopy.
+func (v *Value) Equal(other *Value) bool {
+	if v == nil || other == nil {
+		return v == other
+	}
+	if v.Kind != other.Kind {
+		return false
+	}
 	switch v.Kind {
-	case Int:
-		clone.Int = v.Int
-	case Uint:
-		clone.Uint = v.Uint
+	case Int, Uint, Bool:
+		return v.scalar == other.scalar
 	case Float:
-		clone.Float = v.Float
+		return v.Float() == other.Float() || (v.IsNaN() && other.IsNaN())
 	case String:
-		clone.String = make([]byte, len(v.String))
-		copy(clone.String, v.String)
+		return bytes.Equal(v.Bytes(), other.Bytes())
 	case Object:
-		clone.Object = map[string]*Value{}
-		for k, v := range v.Object {
-			clone.Object[k] = v.DeepCopy()
+		a, b := v.Object(), other.Object()
+		if len(a) != len(b) {
+			return false
+		}
+		for k, av := range a {
+			bv, ok := b[k]
+			if !ok || !av.Equal(bv) {
+				return false
+			}
 		}
+		return true
 	case Array:
-		clone.Array = make([]*Value, 0, len(v.Array))
-		for _, v := range v.Array {
-			clone.Array = append(clone.Array, v.DeepCopy())
+		a, b := v.Array(), other.Array()
+		if len(a) != len(b) {
+			return false
 		}
-	case Bool:
-		clone.Bool = v.Bool
+		for i := range a {
+			if !a[i].Equal(b[i]) {
+				return false
+			}
+		}
+		return true
 	case Nil:
-		// nop
+		return true
+	default:
+		panic(fmt.Errorf("invalid kind: %d", v.Kind))
+	}
+}
+
+// DeepCopy returns a deep copy of v.
+func (v *Value) DeepCopy() *Value {
+	switch v.Kind {
+	case Int, Uint, Float, Bool, Nil:
+		// Kind is exported, so even scalars are copied rather than shared.
+		return &Value{Kind: v.Kind, scalar: v.scalar}
+	case String:
+		s := make([]byte, len(v.Bytes()))
+		copy(s, v.Bytes())
+		return NewStringValue(s)
+	case Object:
+		obj := make(map[string]*Value, len(v.Object()))
+		for k, v := range v.Object() {
+			obj[k] = v.DeepCopy()
+		}
+		return NewObjectValue(obj)
+	case Array:
+		arr := make([]*Value, 0, len(v.Array()))
+		for _, v := range v.Array() {
+			arr = append(arr, v.DeepCopy())
+		}
+		return NewArrayValue(arr)
 	default:
 		panic(fmt.Errorf("unknown kind: %d", v.Kind))
 	}
-	return clone
 }
 
 func (v *Value) GoString() string {
@@ -103,19 +199,19 @@ func (v *Value) GoString() string {
 func (v *Value) goStringValue() string {
 	switch v.Kind {
 	case Int:
-		return fmt.Sprintf("%d", v.Int)
+		return fmt.Sprintf("%d", v.Int())
 	case Uint:
-		return fmt.Sprintf("%d", v.Uint)
+		return fmt.Sprintf("%d", v.Uint())
 	case Float:
-		return fmt.Sprintf("%f", v.Float)
+		return fmt.Sprintf("%f", v.Float())
 	case String:
-		return fmt.Sprintf("%#v", v.String)
+		return fmt.Sprintf("%#v", v.Bytes())
 	case Object:
-		return fmt.Sprintf("%#v", v.Object)
+		return fmt.Sprintf("%#v", v.Object())
 	case Array:
-		return fmt.Sprintf("%#v", v.Array)
+		return fmt.Sprintf("%#v", v.Array())
 	case Bool:
-		return fmt.Sprintf("%t", v.Bool)
+		return fmt.Sprintf("%t", v.Bool())
 	case Nil:
 		return "nil"
 	default:
@@ -2,7 +2,9 @@ package types
 
 import (
 	"bytes"
+	"math"
 	"testing"
+	"unsafe"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -14,8 +16,7 @@ func TestDeepCopyWithInt(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 
-	clone.Uint = 456
-	if orig.Uint == clone.Uint {
+	if orig == clone {
 		t.Errorf("DeepCopy returned receiver itself")
 	}
 }
@@ -27,8 +28,7 @@ func TestDeepCopyWithUint(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 
-	clone.Int = 456
-	if orig.Int == clone.Int {
+	if orig == clone {
 		t.Errorf("DeepCopy returned receiver itself")
 	}
 }
@@ -40,8 +40,7 @@ func TestDeepCopyWithFloat(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 
-	clone.Float = 4.56
-	if orig.Float == clone.Float {
+	if orig == clone {
 		t.Errorf("DeepCopy returned receiver itself")
 	}
 }
@@ -53,13 +52,12 @@ func TestDeepCopyString(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 
-	clone.String[0] = 0x61 // 'a'
-	if bytes.Equal(orig.String, clone.String) {
+	clone.Bytes()[0] = 0x61 // 'a'
+	if bytes.Equal(orig.Bytes(), clone.Bytes()) {
 		t.Errorf("clone shares the same reference with its origin")
 	}
 
-	clone.String = []byte("world")
-	if bytes.Equal(orig.String, clone.String) {
+	if orig == clone {
 		t.Errorf("DeepCopy returned receiver itself")
 	}
 }
@@ -73,18 +71,17 @@ func TestDeepCopyWithObject(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 
-	clone.Object["hello"].String[0] = 0x61 // 'a'
+	clone.Object()["hello"].Bytes()[0] = 0x61 // 'a'
 	if diff := cmp.Diff(orig, clone); diff == "" {
 		t.Errorf("clone shares the same reference with its origin")
 	}
 
-	clone.Object["hoge"] = NewStringValue([]byte("fuga"))
+	clone.Object()["hoge"] = NewStringValue([]byte("fuga"))
 	if diff := cmp.Diff(orig, clone); diff == "" {
 		t.Errorf("clone shares the same reference with its origin")
 	}
 
-	clone.Object = map[string]*Value{}
-	if diff := cmp.Diff(orig, clone); diff == "" {
+	if orig == clone {
 		t.Errorf("DeepCopy returned receiver itself")
 	}
 }
@@ -98,18 +95,17 @@ func TestDeepCopyWithArray(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 
-	clone.Array[0].String = []byte("shrimp")
+	clone.Array()[0].Bytes()[0] = 0x53 // 'S'
 	if diff := cmp.Diff(orig, clone); diff == "" {
 		t.Errorf("clone shares the same reference with its origin")
 	}
 
-	clone.Array[0] = NewStringValue([]byte("tako"))
+	clone.Array()[0] = NewStringValue([]byte("tako"))
 	if diff := cmp.Diff(orig, clone); diff == "" {
 		t.Errorf("clone shares the same reference with its origin")
 	}
 
-	clone.Array = []*Value{}
-	if diff := cmp.Diff(orig, clone); diff == "" {
+	if orig == clone {
 		t.Errorf("DeepCopy returned receiver itself")
 	}
 }
@@ -121,8 +117,7 @@ func TestDeepCopyWithBool(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 
-	clone.Bool = false
-	if orig.Bool == clone.Bool {
+	if orig == clone {
 		t.Errorf("DeepCopy returned receiver itself")
 	}
 }
@@ -139,3 +134,118 @@ func TestDeepCopyWithNil(t *testing.T) {
 		t.Errorf("DeepCopy returned receiver itself")
 	}
 }
+
+func TestAccessorsReturnZeroValuesForOtherKinds(t *testing.T) {
+	v := NewStringValue([]byte("hello"))
+	if v.Int() != 0 || v.Uint() != 0 || v.Float() != 0 || v.Bool() || v.Object() != nil || v.Array() != nil {
+		t.Errorf("unexpected payload: %#v", v)
+	}
+	if string(v.Bytes()) != "hello" {
+		t.Errorf("expected hello but got %s", v.Bytes())
+	}
+	if NewIntValue(-1).Int() != -1 || NewIntValue(1<<40).Int() != 1<<40 {
+		t.Errorf("Int mismatch")
+	}
+	if NewFloatValue(-1.5).Float() != -1.5 || !NewBoolValue(true).Bool() || NewUintValue(1<<63).Uint() != 1<<63 {
+		t.Errorf("scalar mismatch")
+	}
+}
+
+func TestFieldsRoundTrip(t *testing.T) {
+	values := []*Value{
+		NewIntValue(-1),
+		NewUintValue(1 << 63),
+		NewFloatValue(1.5),
+		NewStringValue([]byte("hello")),
+		NewObjectValue(map[string]*Value{"a": NewNilValue()}),
+		NewArrayValue([]*Value{NewBoolValue(true)}),
+		NewBoolValue(true),
+		NewNilValue(),
+	}
+	for _, v := range values {
+		if got := v.Fields().Value(); !got.Equal(v) {
+			t.Errorf("expected %#v but got %#v", v, got)
+		}
+	}
+	if f := NewStringValue([]byte("hello")).Fields(); string(f.String) != "hello" || f.Int != 0 {
+		t.Errorf("unexpected fields: %#v", f)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	cases := []struct {
+		a, b *Value
+		want bool
+	}{
+		{NewIntValue(1), NewIntValue(1), true},
+		{NewIntValue(1), NewUintValue(1), false},
+		{NewFloatValue(math.NaN()), NewFloatValue(math.NaN()), true},
+		{NewStringValue([]byte("a")), NewStringValue([]byte("a")), true},
+		{NewStringValue([]byte("a")), NewStringValue([]byte("b")), false},
+		{
+			NewObjectValue(map[string]*Value{"a": NewArrayValue([]*Value{NewNilValue()})}),
+			NewObjectValue(map[string]*Value{"a": NewArrayValue([]*Value{NewNilValue()})}),
+			true,
+		},
+		{
+			NewObjectValue(map[string]*Value{"a": NewArrayValue([]*Value{NewNilValue()})}),
+			NewObjectValue(map[string]*Value{"a": NewArrayValue([]*Value{NewBoolValue(false)})}),
+			false,
+		},
+		{NewBoolValue(true), NewBoolValue(false), false},
+		{NewNilValue(), nil, false},
+	}
+	for _, c := range cases {
+		if got := c.a.Equal(c.b); got != c.want {
+			t.Errorf("%#v.Equal(%#v): expected %t but got %t", c.a, c.b, c.want, got)
+		}
+	}
+}
+
+func TestConstructorsReturnDistinctValues(t *testing.T) {
+	// Kind is exported, so a Value that is shared between unrelated callers could be corrupted by one of them.
+	v := NewIntValue(42)
+	v.Kind = Uint
+	if got := NewIntValue(42); got == v || got.Kind != Int {
+		t.Errorf("expected a new Int but got %#v", got)
+	}
+	if NewBoolValue(true) == NewBoolValue(true) || NewNilValue() == NewNilValue() {
+		t.Errorf("constructors returned the same Value twice")
+	}
+	if unsafe.Sizeof(Value{}) > 32 {
+		t.Errorf("Value is too large: %d bytes", unsafe.Sizeof(Value{}))
+	}
+}
+
+// buildDocument builds an Array of n Objects, which resembles a large decoded document.
+func buildDocument(n int) *Value {
+	arr := make([]*Value, 0, n)
+	for i := 0; i < n; i++ {
+		arr = append(arr, NewObjectValue(map[string]*Value{
+			"id":      NewIntValue(int64(i)),
+			"name":    NewStringValue([]byte("watson")),
+			"ratio":   NewFloatValue(float64(i) / 3),
+			"count":   NewUintValue(uint64(i)),
+			"enabled": NewBoolValue(i%2 == 0),
+			"parent":  NewNilValue(),
+			"tags":    NewArrayValue([]*Value{NewIntValue(1), NewIntValue(2), NewIntValue(int64(i) << 20)}),
+		}))
+	}
+	return NewArrayValue(arr)
+}
+
+func BenchmarkBuildDocument(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = buildDocument(10000)
+	}
+}
+
+func BenchmarkDeepCopyDocument(b *testing.B) {
+	doc := buildDocument(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = doc.DeepCopy()
+	}
+}
@@ -89,14 +89,14 @@ func (u *customUnmarshaler) UnmarshalWatson(v *types.Value) error {
 	if v.Kind != types.Object {
 		return fmt.Errorf("value is not an Object")
 	}
-	k, ok := v.Object["customKey"]
+	k, ok := v.Object()["customKey"]
 	if !ok {
 		return fmt.Errorf("value does not have customKey")
 	}
 	if k.Kind != types.Int {
 		return fmt.Errorf("custom key is not an int")
 	}
-	u.SomeField = int(k.Int)
+	u.SomeField = int(k.Int())
 	return nil
 }
 
@@ -108,14 +108,14 @@ func (p *primitiveUnmarshaler) UnmarshalWatson(v *types.Value) error {
 	if v.Kind != types.Object {
 		return fmt.Errorf("value is not an Object")
 	}
-	k, ok := v.Object["customKey"]
+	k, ok := v.Object()["customKey"]
 	if !ok {
 		return fmt.Errorf("value does not have customKey")
 	}
 	if k.Kind != types.Int {
 		return fmt.Errorf("custom key is not an int")
 	}
-	*p = primitiveUnmarshaler(k.Int)
+	*p = primitiveUnmarshaler(k.Int())
 	return nil
 }
 
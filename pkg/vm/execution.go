@@ -16,16 +16,43 @@ var (
 
 // Top returns a value in the top of the stack.
 // This returns ErrStackEmpty if the stack is empty.
+// The value is never modified by the VM afterwards, but it must not be modified by the caller either.
 func (vm *VM) Top() (*types.Value, error) {
 	if vm.sp < 0 {
 		return nil, ErrStackEmpty
 	}
+	vm.share()
 	return vm.stack[vm.sp], nil
 }
 
+// Stack returns a read-only view of the stack, i.e. values in the stack from the bottom to the top.
+// The returned slice is a copy, and the values are never modified by the VM afterwards, but they must not be modified by the caller either.
+func (vm *VM) Stack() []*types.Value {
+	vm.share()
+	stack := make([]*types.Value, vm.sp+1)
+	copy(stack, vm.stack[:vm.sp+1])
+	return stack
+}
+
+// share marks all values in the stack as shared with the outside of the VM.
+// Ops that modify values in place copy shared values before modifying them, so that values outside the VM are never changed.
+func (vm *VM) share() {
+	vm.shared = vm.sp + 1
+}
+
 // Feed takes a op and executes corresponding operation.
 // This can fail in various ways; e.g. type mismatch, stack overflow, etc.
 func (vm *VM) Feed(op Op) error {
+	if vm.tracer == nil {
+		return vm.feed(op)
+	}
+	vm.tracer.BeforeOp(op, vm.stack[:vm.sp+1])
+	err := vm.feed(op)
+	vm.tracer.AfterOp(op, vm.stack[:vm.sp+1], err)
+	return err
+}
+
+func (vm *VM) feed(op Op) error {
 	switch op {
 	case Inew:
 		return vm.feedInew()
@@ -249,6 +276,7 @@ func (vm *VM) feedNnew() error {
 }
 
 func (vm *VM) feedGdup() error {
+	shared := vm.shared
 	v, err := vm.pop()
 	if err != nil {
 		return err
@@ -257,6 +285,8 @@ func (vm *VM) feedGdup() error {
 	if err != nil {
 		return err
 	}
+	// v is back in the same place.
+	vm.shared = shared
 	return vm.push(v.DeepCopy())
 }
 
@@ -266,6 +296,7 @@ func (vm *VM) feedGpop() error {
 }
 
 func (vm *VM) feedGswp() error {
+	shared := vm.shared
 	a, err := vm.pop()
 	if err != nil {
 		return err
@@ -278,7 +309,17 @@ func (vm *VM) feedGswp() error {
 	if err != nil {
 		return err
 	}
-	return vm.push(b)
+	err = vm.push(b)
+	if err != nil {
+		return err
+	}
+	// If either of a and b is shared, the places of both are regarded as shared.
+	if shared > vm.sp-1 {
+		vm.shared = vm.sp + 1
+	} else {
+		vm.shared = shared
+	}
+	return nil
 }
 
 //
@@ -327,13 +368,23 @@ func (vm *VM) pushNil() error {
 }
 
 func (vm *VM) pop() (*types.Value, error) {
+	v, _, err := vm.popShared()
+	return v, err
+}
+
+// popShared pops a value and reports whether it is shared with the outside of the VM.
+func (vm *VM) popShared() (*types.Value, bool, error) {
 	if vm.sp < 0 {
-		return nil, ErrStackEmpty
+		return nil, false, ErrStackEmpty
 	}
 	top := vm.stack[vm.sp]
 	vm.stack[vm.sp] = nil
+	shared := vm.sp < vm.shared
+	if shared {
+		vm.shared = vm.sp
+	}
 	vm.sp--
-	return top, nil
+	return top, shared, nil
 }
 
 func (vm *VM) popInt() (int64, error) {
@@ -344,7 +395,7 @@ func (vm *VM) popInt() (int64, error) {
 	if v.Kind != types.Int {
 		return 0, ErrTypeMismatch
 	}
-	return v.Int, nil
+	return v.Int(), nil
 }
 
 func (vm *VM) popFloat() (float64, error) {
@@ -355,40 +406,62 @@ func (vm *VM) popFloat() (float64, error) {
 	if v.Kind != types.Float {
 		return 0, ErrTypeMismatch
 	}
-	return v.Float, nil
+	return v.Float(), nil
 }
 
+// popString pops a string that can be appended to without modifying other values.
 func (vm *VM) popString() ([]byte, error) {
-	v, err := vm.pop()
+	v, shared, err := vm.popShared()
 	if err != nil {
 		return nil, err
 	}
 	if v.Kind != types.String {
 		return nil, ErrTypeMismatch
 	}
-	return v.String, nil
+	s := v.Bytes()
+	if shared {
+		// Forces append to allocate a new array.
+		s = s[:len(s):len(s)]
+	}
+	return s, nil
 }
 
+// popObject pops an object that can be modified without modifying other values.
 func (vm *VM) popObject() (map[string]*types.Value, error) {
-	v, err := vm.pop()
+	v, shared, err := vm.popShared()
 	if err != nil {
 		return nil, err
 	}
 	if v.Kind != types.Object {
 		return nil, ErrTypeMismatch
 	}
-	return v.Object, nil
+	o := v.Object()
+	if shared {
+		// Elements are never modified in place, so a shallow copy is sufficient.
+		clone := make(map[string]*types.Value, len(o)+1)
+		for k, e := range o {
+			clone[k] = e
+		}
+		o = clone
+	}
+	return o, nil
 }
 
+// popArray pops an array that can be appended to without modifying other values.
 func (vm *VM) popArray() ([]*types.Value, error) {
-	v, err := vm.pop()
+	v, shared, err := vm.popShared()
 	if err != nil {
 		return nil, err
 	}
 	if v.Kind != types.Array {
 		return nil, ErrTypeMismatch
 	}
-	return v.Array, nil
+	a := v.Array()
+	if shared {
+		// Forces append to allocate a new array.
+		a = a[:len(a):len(a)]
+	}
+	return a, nil
 }
 
 func (vm *VM) popBool() (bool, error) {
@@ -399,5 +472,5 @@ func (vm *VM) popBool() (bool, error) {
 	if v.Kind != types.Bool {
 		return false, ErrTypeMismatch
 	}
-	return v.Bool, nil
+	return v.Bool(), nil
 }
@@ -855,8 +855,8 @@ func TestFeedOaddAddsACopyOfAValue(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 
-	got.Object["user"].Object["name"] = types.NewStringValue([]byte("jiro"))
-	if diff := cmp.Diff(addedVal, got.Object["user"].Object); diff == "" {
+	got.Object()["user"].Object()["name"] = types.NewStringValue([]byte("jiro"))
+	if diff := cmp.Diff(addedVal, got.Object()["user"].Object()); diff == "" {
 		t.Errorf("the added value does not seem to be a clone of the value on the stack")
 	}
 }
@@ -1047,8 +1047,8 @@ func TestFeedAaddAppendsACopyOfAValue(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 
-	got.Array[1].Object["name"] = types.NewStringValue([]byte("jiro"))
-	if diff := cmp.Diff(addedVal, got.Array[1].Object); diff == "" {
+	got.Array()[1].Object()["name"] = types.NewStringValue([]byte("jiro"))
+	if diff := cmp.Diff(addedVal, got.Array()[1].Object()); diff == "" {
 		t.Errorf("the added value does not seem to be a clone of the value on the stack")
 	}
 }
@@ -1264,7 +1264,7 @@ func TestGdupPushesACopy(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 
-	clone.Object["ebi"] = types.NewStringValue([]byte("shrimp"))
+	clone.Object()["ebi"] = types.NewStringValue([]byte("shrimp"))
 	if diff := cmp.Diff(clone, orig); diff == "" {
 		t.Errorf("Gdup does not seem to copy arg1")
 	}
@@ -1416,3 +1416,23 @@ func TestFeedMultiExecutesOpsSequentially(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestStackReturnsValuesFromBottomToTop(t *testing.T) {
+	vm := NewVM()
+	if got := vm.Stack(); len(got) != 0 {
+		t.Fatalf("expected empty stack, got %#v", got)
+	}
+	err := vm.FeedMulti([]Op{Inew, Bnew, Nnew})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*types.Value{types.NewIntValue(0), types.NewBoolValue(false), types.NewNilValue()}
+	got := vm.Stack()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("stack mismatch (-want +got):\n%s", diff)
+	}
+	got[0] = nil
+	if diff := cmp.Diff(want, vm.Stack()); diff != "" {
+		t.Fatalf("modifying the result affected the VM (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,31 @@
+package vm
+
+import (
+	"sync"
+)
+
+// Pool is a set of VMs that can be reused, which saves allocating a stack for each VM.
+// It is safe for concurrent use by multiple goroutines.
+type Pool struct {
+	pool sync.Pool
+}
+
+// NewPool creates a new Pool of VMs that are built with opts.
+func NewPool(opts ...VMOption) *Pool {
+	p := &Pool{}
+	p.pool.New = func() interface{} {
+		return NewVM(opts...)
+	}
+	return p
+}
+
+// Get returns a VM whose stack is empty.
+func (p *Pool) Get() *VM {
+	return p.pool.Get().(*VM)
+}
+
+// Put resets vm and returns it to p. vm must not be used after that.
+func (p *Pool) Put(vm *VM) {
+	vm.Reset()
+	p.pool.Put(vm)
+}
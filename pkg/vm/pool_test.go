@@ -0,0 +1,21 @@
+package vm
+
+import (
+	"testing"
+)
+
+func TestPoolReturnsEmptyVMs(t *testing.T) {
+	p := NewPool(WithStackSize(4))
+	vm := p.Get()
+	if len(vm.stack) != 4 {
+		t.Fatalf("expected a stack of size 4 but got %d", len(vm.stack))
+	}
+	if err := vm.FeedMulti([]Op{Inew, Snew}); err != nil {
+		t.Fatal(err)
+	}
+	p.Put(vm)
+	vm = p.Get()
+	if len(vm.Stack()) != 0 {
+		t.Fatalf("expected an empty stack but got %#v", vm.Stack())
+	}
+}
@@ -0,0 +1,124 @@
+package vm
+
+import (
+	"math"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+// Run executes ops sequentially like FeedMulti, but faster.
+//
+// Run fuses the following sequences of Ops, which are what encoders emit for literals, into direct construction of their values:
+//   * Integers: Inew followed by Iinc, Ishl and Ineg, optionally ended with Itou or Itof.
+//   * Characters: an integer followed by Sadd, which appends a byte to the string below it.
+//   * Strings: Snew followed by any number of characters.
+// Intermediate values of these sequences are never pushed onto the stack.
+//
+// The result is the same as the one of FeedMulti: the VM ends up in the same state, and it returns the same error as FeedMulti does.
+// A sequence is only fused when it is known to succeed; otherwise its Ops are fed one by one.
+// Nothing is fused if the VM has a Tracer, so that the Tracer observes every Op.
+func (vm *VM) Run(ops []Op) error {
+	if vm.tracer != nil {
+		return vm.FeedMulti(ops)
+	}
+	for i := 0; i < len(ops); {
+		var n int
+		switch ops[i] {
+		case Inew:
+			n = vm.runInt(ops[i:])
+		case Snew:
+			n = vm.runString(ops[i:])
+		}
+		if n > 0 {
+			i += n
+			continue
+		}
+		if err := vm.Feed(ops[i]); err != nil {
+			return err
+		}
+		i++
+	}
+	return nil
+}
+
+// free returns the number of values that can be pushed onto the stack.
+func (vm *VM) free() int {
+	return len(vm.stack) - 1 - vm.sp
+}
+
+// intLiteral evaluates an integer that starts with Inew at ops[0], and returns it with the number of Ops that it consists of.
+func intLiteral(ops []Op) (int64, int) {
+	var n int64
+	i := 1
+	for ; i < len(ops); i++ {
+		switch ops[i] {
+		case Iinc:
+			n++
+		case Ishl:
+			n <<= 1
+		case Ineg:
+			n = -n
+		default:
+			return n, i
+		}
+	}
+	return n, i
+}
+
+// runInt executes an integer, which may be converted into another type or appended to a string, at the beginning of ops.
+// It returns the number of Ops executed, or 0 if it can't be fused.
+func (vm *VM) runInt(ops []Op) int {
+	if vm.free() < 1 {
+		return 0
+	}
+	n, i := intLiteral(ops)
+	if i < len(ops) {
+		switch ops[i] {
+		case Itou:
+			vm.sp++
+			vm.stack[vm.sp] = types.NewUintValue(uint64(n))
+			return i + 1
+		case Itof:
+			vm.sp++
+			vm.stack[vm.sp] = types.NewFloatValue(math.Float64frombits(uint64(n)))
+			return i + 1
+		case Sadd:
+			if vm.sp < 0 || vm.stack[vm.sp].Kind != types.String {
+				// Sadd fails, so it is left to Feed.
+				break
+			}
+			s := vm.stack[vm.sp].Bytes()
+			if vm.sp < vm.shared {
+				s = s[:len(s):len(s)]
+				vm.shared = vm.sp
+			}
+			vm.stack[vm.sp] = types.NewStringValue(append(s, byte(n)))
+			return i + 1
+		}
+	}
+	vm.sp++
+	vm.stack[vm.sp] = types.NewIntValue(n)
+	return i
+}
+
+// runString executes Snew and the characters that follow it at the beginning of ops.
+// It returns the number of Ops executed, or 0 if it can't be fused.
+func (vm *VM) runString(ops []Op) int {
+	// One for the string and one for each character that is pushed before it is appended to the string.
+	if vm.free() < 2 {
+		return 0
+	}
+	s := []byte{}
+	i := 1
+	for i < len(ops) && ops[i] == Inew {
+		n, k := intLiteral(ops[i:])
+		if i+k >= len(ops) || ops[i+k] != Sadd {
+			break
+		}
+		s = append(s, byte(n))
+		i += k + 1
+	}
+	vm.sp++
+	vm.stack[vm.sp] = types.NewStringValue(s)
+	return i
+}
@@ -0,0 +1,151 @@
+package vm
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+// intOps returns Ops that push n.
+func intOps(n int64) []Op {
+	ops := []Op{Inew}
+	for i := 63; i >= 0; i-- {
+		ops = append(ops, Ishl)
+		if uint64(n)&(1<<uint(i)) != 0 {
+			ops = append(ops, Iinc)
+		}
+	}
+	return ops
+}
+
+// stringOps returns Ops that push s.
+func stringOps(s string) []Op {
+	ops := []Op{Snew}
+	for i := 0; i < len(s); i++ {
+		ops = append(ops, intOps(int64(s[i]))...)
+		ops = append(ops, Sadd)
+	}
+	return ops
+}
+
+func TestRunFusesLiterals(t *testing.T) {
+	ops := []Op{Onew}
+	ops = append(ops, stringOps("key")...)
+	ops = append(ops, Anew)
+	ops = append(ops, intOps(-12345)...)
+	ops = append(ops, Aadd)
+	ops = append(ops, intOps(42)...)
+	ops = append(ops, Itou, Aadd)
+	ops = append(ops, intOps(int64(0x3ff8000000000000))...)
+	ops = append(ops, Itof, Aadd)
+	ops = append(ops, stringOps("")...)
+	ops = append(ops, Aadd, Oadd)
+
+	vm := NewVM()
+	if err := vm.Run(ops); err != nil {
+		t.Fatal(err)
+	}
+	want := types.NewObjectValue(map[string]*types.Value{
+		"key": types.NewArrayValue([]*types.Value{
+			types.NewIntValue(-12345),
+			types.NewUintValue(42),
+			types.NewFloatValue(1.5),
+			types.NewStringValue([]byte{}),
+		}),
+	})
+	if diff := cmp.Diff([]*types.Value{want}, vm.Stack()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunFailsLikeFeedMulti(t *testing.T) {
+	cases := []struct {
+		name      string
+		stackSize int
+		ops       []Op
+		err       error
+	}{
+		{name: "Sadd without a string", stackSize: 8, ops: []Op{Inew, Iinc, Sadd}, err: ErrStackEmpty},
+		{name: "Sadd on an integer", stackSize: 8, ops: []Op{Inew, Inew, Iinc, Sadd}, err: ErrTypeMismatch},
+		{name: "integer on a full stack", stackSize: 1, ops: []Op{Bnew, Inew, Iinc}, err: ErrMaximumStackSizeExceeded},
+		{name: "character on a full stack", stackSize: 1, ops: []Op{Snew, Inew, Iinc, Sadd}, err: ErrMaximumStackSizeExceeded},
+		{name: "string on a full stack", stackSize: 2, ops: []Op{Bnew, Snew, Inew, Sadd}, err: ErrMaximumStackSizeExceeded},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fed := NewVM(WithStackSize(c.stackSize))
+			feedErr := fed.FeedMulti(c.ops)
+			run := NewVM(WithStackSize(c.stackSize))
+			runErr := run.Run(c.ops)
+			if runErr != c.err || feedErr != c.err {
+				t.Fatalf("expected %v but got %v (Run) and %v (FeedMulti)", c.err, runErr, feedErr)
+			}
+			if diff := cmp.Diff(fed.Stack(), run.Stack()); diff != "" {
+				t.Errorf("stack mismatch (-FeedMulti +Run):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestRunIsEquivalentToFeedMulti compares Run with FeedMulti on random sequences of Ops that are likely to contain literals.
+func TestRunIsEquivalentToFeedMulti(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	weighted := []Op{Inew, Inew, Iinc, Iinc, Ishl, Ishl, Ineg, Itou, Itof, Snew, Sadd, Sadd, Gpop, Gdup, Gswp, Anew, Aadd, Bnew}
+	for n := 0; n < 2000; n++ {
+		ops := make([]Op, r.Intn(40))
+		for i := range ops {
+			ops[i] = weighted[r.Intn(len(weighted))]
+		}
+		size := 1 + r.Intn(6)
+		fed := NewVM(WithStackSize(size))
+		feedErr := fed.FeedMulti(ops)
+		run := NewVM(WithStackSize(size))
+		runErr := run.Run(ops)
+		if feedErr != runErr {
+			t.Fatalf("%#v: expected %v but got %v", ops, feedErr, runErr)
+		}
+		if diff := cmp.Diff(fed.Stack(), run.Stack(), cmpopts.EquateNaNs()); diff != "" {
+			t.Fatalf("%#v: stack mismatch (-FeedMulti +Run):\n%s", ops, diff)
+		}
+	}
+}
+
+// benchmarkOps is a sequence of Ops that builds an array of objects, which is what encoders emit for typical documents.
+var benchmarkOps = func() []Op {
+	ops := []Op{Anew}
+	for i := 0; i < 1000; i++ {
+		ops = append(ops, Onew)
+		ops = append(ops, stringOps("name")...)
+		ops = append(ops, stringOps("watson")...)
+		ops = append(ops, Oadd)
+		ops = append(ops, stringOps("id")...)
+		ops = append(ops, intOps(int64(i))...)
+		ops = append(ops, Oadd)
+		ops = append(ops, stringOps("ratio")...)
+		ops = append(ops, intOps(int64(0x3fe0000000000000))...)
+		ops = append(ops, Itof, Oadd, Aadd)
+	}
+	return ops
+}()
+
+func BenchmarkFeedMulti(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		vm := NewVM()
+		if err := vm.FeedMulti(benchmarkOps); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRun(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		vm := NewVM()
+		if err := vm.Run(benchmarkOps); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package vm
+
+import (
+	"github.com/genkami/watson/pkg/types"
+)
+
+// Snapshot is a checkpoint of the stack of a VM, which can be restored any number of times.
+//
+// Taking a Snapshot only copies the pointers to the values in the stack.
+// Ops that modify values in place copy values that are in Snapshots before modifying them, so that Snapshots are never changed.
+type Snapshot struct {
+	stack []*types.Value
+}
+
+// Len returns the number of values in the stack when the Snapshot is taken.
+func (s *Snapshot) Len() int {
+	return len(s.stack)
+}
+
+// Snapshot returns a checkpoint of the current stack.
+func (vm *VM) Snapshot() *Snapshot {
+	vm.share()
+	stack := make([]*types.Value, vm.sp+1)
+	copy(stack, vm.stack[:vm.sp+1])
+	return &Snapshot{stack: stack}
+}
+
+// Restore rolls the stack back to s, which may be taken from another VM.
+// This returns ErrMaximumStackSizeExceeded if s doesn't fit in the stack of vm, in which case the stack is left unchanged.
+func (vm *VM) Restore(s *Snapshot) error {
+	if len(s.stack) > len(vm.stack) {
+		return ErrMaximumStackSizeExceeded
+	}
+	vm.Reset()
+	copy(vm.stack, s.stack)
+	vm.sp = len(s.stack) - 1
+	vm.share()
+	return nil
+}
+
+// Reset empties the stack so that the VM can be reused.
+func (vm *VM) Reset() {
+	for i := 0; i <= vm.sp; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = -1
+	vm.shared = 0
+}
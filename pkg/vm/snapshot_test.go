@@ -0,0 +1,168 @@
+package vm
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func TestResetEmptiesTheStack(t *testing.T) {
+	vm := NewVM()
+	if err := vm.FeedMulti([]Op{Inew, Snew, Onew}); err != nil {
+		t.Fatal(err)
+	}
+	vm.Reset()
+	if len(vm.Stack()) != 0 {
+		t.Fatalf("expected an empty stack but got %#v", vm.Stack())
+	}
+	for _, v := range vm.stack {
+		if v != nil {
+			t.Fatalf("reset VM still refers to %#v", v)
+		}
+	}
+	if err := vm.FeedMulti([]Op{Inew, Iinc}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*types.Value{types.NewIntValue(1)}, vm.Stack()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRestoreRollsBackToSnapshot(t *testing.T) {
+	vm := NewVM()
+	// {"a": 1}
+	prefix := []Op{Onew}
+	prefix = append(prefix, stringOps("a")...)
+	prefix = append(prefix, Inew, Iinc, Oadd)
+	if err := vm.Run(prefix); err != nil {
+		t.Fatal(err)
+	}
+	s := vm.Snapshot()
+	want := vm.Stack()[0].DeepCopy()
+
+	for _, key := range []string{"b", "c"} {
+		ops := stringOps(key)
+		ops = append(ops, Bnew, Oadd)
+		if err := vm.FeedMulti(ops); err != nil {
+			t.Fatal(err)
+		}
+		if err := vm.Restore(s); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]*types.Value{want}, vm.Stack()); diff != "" {
+			t.Fatalf("mismatch after adding %s (-want +got):\n%s", key, diff)
+		}
+	}
+}
+
+func TestStackIsNotModifiedByTheVM(t *testing.T) {
+	vm := NewVM()
+	if err := vm.Run([]Op{Anew, Snew, Inew, Sadd}); err != nil {
+		t.Fatal(err)
+	}
+	stack := vm.Stack()
+	want := []*types.Value{types.NewArrayValue([]*types.Value{}), types.NewStringValue([]byte{0})}
+	if err := vm.Run([]Op{Inew, Iinc, Sadd, Aadd}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, stack); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRestoreFailsIfSnapshotDoesNotFit(t *testing.T) {
+	big := NewVM()
+	if err := big.FeedMulti([]Op{Inew, Inew, Inew}); err != nil {
+		t.Fatal(err)
+	}
+	small := NewVM(WithStackSize(2))
+	if err := small.Feed(Bnew); err != nil {
+		t.Fatal(err)
+	}
+	if err := small.Restore(big.Snapshot()); err != ErrMaximumStackSizeExceeded {
+		t.Fatalf("expected ErrMaximumStackSizeExceeded but got %v", err)
+	}
+	if diff := cmp.Diff([]*types.Value{types.NewBoolValue(false)}, small.Stack()); diff != "" {
+		t.Errorf("stack is modified (-want +got):\n%s", diff)
+	}
+}
+
+// TestSnapshotsAreNeverModified takes snapshots at random points of random executions and checks that they are kept intact.
+func TestSnapshotsAreNeverModified(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	weighted := []Op{Inew, Iinc, Ishl, Snew, Sadd, Sadd, Onew, Oadd, Oadd, Anew, Aadd, Aadd, Gdup, Gswp, Gpop, Bnew}
+	for n := 0; n < 500; n++ {
+		vm := NewVM(WithStackSize(8))
+		type checkpoint struct {
+			snapshot *Snapshot
+			want     []*types.Value
+		}
+		var checkpoints []checkpoint
+		for step := 0; step < 10; step++ {
+			ops := make([]Op, r.Intn(20))
+			for i := range ops {
+				ops[i] = weighted[r.Intn(len(weighted))]
+			}
+			if r.Intn(2) == 0 {
+				_ = vm.Run(ops)
+			} else {
+				_ = vm.FeedMulti(ops)
+			}
+			switch r.Intn(3) {
+			case 0:
+				s := vm.Snapshot()
+				want := make([]*types.Value, 0, s.Len())
+				for _, v := range vm.Stack() {
+					want = append(want, v.DeepCopy())
+				}
+				checkpoints = append(checkpoints, checkpoint{snapshot: s, want: want})
+			case 1:
+				if len(checkpoints) > 0 {
+					if err := vm.Restore(checkpoints[r.Intn(len(checkpoints))].snapshot); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+		}
+		for i, c := range checkpoints {
+			if diff := cmp.Diff(c.want, c.snapshot.stack); diff != "" {
+				t.Fatalf("snapshot %d is modified (-want +got):\n%s", i, diff)
+			}
+		}
+	}
+}
+
+func TestBranchesFromSnapshotDoNotShareValues(t *testing.T) {
+	for _, fused := range []bool{false, true} {
+		vm := NewVM()
+		run := vm.FeedMulti
+		if fused {
+			run = vm.Run
+		}
+		// A string and an array that have spare capacity.
+		if err := run(append(stringOps("abc"), Anew, Bnew, Aadd, Bnew, Aadd, Bnew, Aadd)); err != nil {
+			t.Fatal(err)
+		}
+		s := vm.Snapshot()
+		branch := func(ops []Op) []*types.Value {
+			if err := vm.Restore(s); err != nil {
+				t.Fatal(err)
+			}
+			if err := run(ops); err != nil {
+				t.Fatal(err)
+			}
+			return vm.Stack()
+		}
+		// Appends x to the string and nil to the array.
+		first := branch(append([]Op{Gswp}, append(intOps('x'), Sadd, Gswp, Nnew, Aadd)...))
+		want := []*types.Value{first[0].DeepCopy(), first[1].DeepCopy()}
+		// Appends y to the string and an empty string to the array.
+		branch(append([]Op{Gswp}, append(intOps('y'), Sadd, Gswp, Snew, Aadd)...))
+		if diff := cmp.Diff(want, first); diff != "" {
+			t.Errorf("fused = %t: mismatch (-want +got):\n%s", fused, diff)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+// Tracer observes the execution of a VM. See WithTracer.
+//
+// stack is the stack of the VM from the bottom to the top. It is only valid during the call, and neither it nor its values must be modified.
+// Tracers that keep values must copy them with DeepCopy.
+type Tracer interface {
+	// BeforeOp is called right before the VM executes op.
+	BeforeOp(op Op, stack []*types.Value)
+	// AfterOp is called right after the VM executes op. err is the error returned by Feed.
+	AfterOp(op Op, stack []*types.Value, err error)
+}
+
+type multiTracer []Tracer
+
+// MultiTracer returns a Tracer that calls each of tracers in order.
+func MultiTracer(tracers ...Tracer) Tracer {
+	return multiTracer(tracers)
+}
+
+func (m multiTracer) BeforeOp(op Op, stack []*types.Value) {
+	for _, t := range m {
+		t.BeforeOp(op, stack)
+	}
+}
+
+func (m multiTracer) AfterOp(op Op, stack []*types.Value, err error) {
+	for _, t := range m {
+		t.AfterOp(op, stack, err)
+	}
+}
+
+// LogTracer writes a line for each Op to an io.Writer, e.g.
+//   Iinc: depth 1, top {Kind: Int, Value: 1}
+//   Sadd: type mismatch
+type LogTracer struct {
+	w io.Writer
+}
+
+// NewLogTracer creates a new LogTracer that writes to w. Errors of w are ignored.
+func NewLogTracer(w io.Writer) *LogTracer {
+	return &LogTracer{w: w}
+}
+
+func (t *LogTracer) BeforeOp(op Op, stack []*types.Value) {}
+
+func (t *LogTracer) AfterOp(op Op, stack []*types.Value, err error) {
+	switch {
+	case err != nil:
+		fmt.Fprintf(t.w, "%#v: %s\n", op, err.Error())
+	case len(stack) == 0:
+		fmt.Fprintf(t.w, "%#v: depth 0\n", op)
+	default:
+		fmt.Fprintf(t.w, "%#v: depth %d, top %#v\n", op, len(stack), stack[len(stack)-1])
+	}
+}
+
+// CountTracer counts how many times each Op is executed, including the ones that fail.
+// Invalid Ops, which are not in AllOps, are not counted.
+type CountTracer struct {
+	counts []int
+}
+
+// NewCountTracer creates a new CountTracer.
+func NewCountTracer() *CountTracer {
+	return &CountTracer{counts: make([]int, len(AllOps()))}
+}
+
+func (t *CountTracer) BeforeOp(op Op, stack []*types.Value) {}
+
+func (t *CountTracer) AfterOp(op Op, stack []*types.Value, err error) {
+	if op < 0 || int(op) >= len(t.counts) {
+		return
+	}
+	t.counts[op]++
+}
+
+// Count returns how many times op is executed. It returns 0 if op is invalid.
+func (t *CountTracer) Count(op Op) int {
+	if op < 0 || int(op) >= len(t.counts) {
+		return 0
+	}
+	return t.counts[op]
+}
+
+// Counts returns the numbers of executions of the Ops that are executed at least once.
+func (t *CountTracer) Counts() map[Op]int {
+	counts := map[Op]int{}
+	for op, n := range t.counts {
+		if n > 0 {
+			counts[Op(op)] = n
+		}
+	}
+	return counts
+}
+
+// MaxDepthTracer records the maximum number of values that the stack has ever held.
+type MaxDepthTracer struct {
+	max int
+}
+
+// NewMaxDepthTracer creates a new MaxDepthTracer.
+func NewMaxDepthTracer() *MaxDepthTracer {
+	return &MaxDepthTracer{}
+}
+
+func (t *MaxDepthTracer) BeforeOp(op Op, stack []*types.Value) {}
+
+func (t *MaxDepthTracer) AfterOp(op Op, stack []*types.Value, err error) {
+	if len(stack) > t.max {
+		t.max = len(stack)
+	}
+}
+
+// MaxDepth returns the maximum depth of the stack.
+func (t *MaxDepthTracer) MaxDepth() int {
+	return t.max
+}
+
+var (
+	_ Tracer = multiTracer(nil)
+	_ Tracer = &LogTracer{}
+	_ Tracer = &CountTracer{}
+	_ Tracer = &MaxDepthTracer{}
+)
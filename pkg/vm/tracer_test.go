@@ -0,0 +1,104 @@
+package vm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+type event struct {
+	After bool
+	Op    Op
+	Depth int
+	Err   error
+}
+
+// recorder is a Tracer that records events.
+type recorder struct {
+	events []event
+}
+
+func (r *recorder) BeforeOp(op Op, stack []*types.Value) {
+	r.events = append(r.events, event{Op: op, Depth: len(stack)})
+}
+
+func (r *recorder) AfterOp(op Op, stack []*types.Value, err error) {
+	r.events = append(r.events, event{After: true, Op: op, Depth: len(stack), Err: err})
+}
+
+func TestTracerObservesEachOp(t *testing.T) {
+	r := &recorder{}
+	vm := NewVM(WithTracer(r))
+	err := vm.FeedMulti([]Op{Inew, Iinc, Gpop, Gpop})
+	if err != ErrStackEmpty {
+		t.Fatalf("expected ErrStackEmpty but got %v", err)
+	}
+	want := []event{
+		{Op: Inew, Depth: 0}, {After: true, Op: Inew, Depth: 1},
+		{Op: Iinc, Depth: 1}, {After: true, Op: Iinc, Depth: 1},
+		{Op: Gpop, Depth: 1}, {After: true, Op: Gpop, Depth: 0},
+		{Op: Gpop, Depth: 0}, {After: true, Op: Gpop, Depth: 0, Err: ErrStackEmpty},
+	}
+	if diff := cmp.Diff(want, r.events, cmp.Comparer(func(a, b error) bool { return a == b })); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunDoesNotFuseOpsWhenTraced(t *testing.T) {
+	counts := NewCountTracer()
+	vm := NewVM(WithTracer(counts))
+	if err := vm.Run(stringOps("ab")); err != nil {
+		t.Fatal(err)
+	}
+	want := map[Op]int{Snew: 1, Inew: 2, Ishl: 128, Iinc: 6, Sadd: 2}
+	if diff := cmp.Diff(want, counts.Counts()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if counts.Count(Sadd) != 2 || counts.Count(Onew) != 0 {
+		t.Errorf("unexpected counts: Sadd = %d, Onew = %d", counts.Count(Sadd), counts.Count(Onew))
+	}
+}
+
+func TestCountTracerIgnoresInvalidOps(t *testing.T) {
+	counts := NewCountTracer()
+	counts.AfterOp(Op(-1), nil, nil)
+	counts.AfterOp(Op(len(AllOps())), nil, nil)
+	counts.AfterOp(Inew, nil, nil)
+	if diff := cmp.Diff(map[Op]int{Inew: 1}, counts.Counts()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if n := counts.Count(Op(-1)); n != 0 {
+		t.Errorf("expected 0 but got %d", n)
+	}
+}
+
+func TestMaxDepthTracer(t *testing.T) {
+	depth := NewMaxDepthTracer()
+	vm := NewVM(WithTracer(depth))
+	if err := vm.FeedMulti([]Op{Anew, Inew, Inew, Gpop, Aadd, Nnew}); err != nil {
+		t.Fatal(err)
+	}
+	if depth.MaxDepth() != 3 {
+		t.Errorf("expected 3 but got %d", depth.MaxDepth())
+	}
+}
+
+func TestLogTracer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	depth := NewMaxDepthTracer()
+	vm := NewVM(WithTracer(MultiTracer(NewLogTracer(buf), depth)))
+	_ = vm.FeedMulti([]Op{Inew, Iinc, Gpop, Sadd})
+	want := "Inew: depth 1, top {Kind: Int, Value: 0}\n" +
+		"Iinc: depth 1, top {Kind: Int, Value: 1}\n" +
+		"Gpop: depth 0\n" +
+		"Sadd: stack is empty\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if depth.MaxDepth() != 1 {
+		t.Errorf("expected 1 but got %d", depth.MaxDepth())
+	}
+}
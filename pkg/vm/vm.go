@@ -15,6 +15,9 @@ const (
 type VM struct {
 	stack []*types.Value
 	sp    int
+	// stack[:shared] may be referenced from the outside of the VM, e.g. by Snapshots.
+	shared int
+	tracer Tracer
 }
 
 // VMOption provides the way to build VMs with custom configurations.
@@ -38,6 +41,14 @@ func WithStackSize(size int) VMOption {
 	})
 }
 
+// WithTracer installs t to a VM, which is called before and after executing each Op.
+// Use MultiTracer to install more than one Tracer.
+func WithTracer(t Tracer) VMOption {
+	return vmOption(func(v *VM) {
+		v.tracer = t
+	})
+}
+
 // Returns a new VM with its stack allocated.
 // For more details see VMOption.
 func NewVM(opts ...VMOption) *VM {
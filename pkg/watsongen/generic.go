@@ -0,0 +1,12 @@
+//go:build !go1.18
+// +build !go1.18
+
+package watsongen
+
+import "go/ast"
+
+// isGeneric reports whether ts declares type parameters.
+// It is always false before Go 1.18, which can't parse generic types in the first place.
+func isGeneric(ts *ast.TypeSpec) bool {
+	return false
+}
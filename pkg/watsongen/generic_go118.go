@@ -0,0 +1,11 @@
+//go:build go1.18
+// +build go1.18
+
+package watsongen
+
+import "go/ast"
+
+// isGeneric reports whether ts declares type parameters.
+func isGeneric(ts *ast.TypeSpec) bool {
+	return ts.TypeParams != nil && len(ts.TypeParams.List) > 0
+}
@@ -0,0 +1,33 @@
+//go:build go1.18
+// +build go1.18
+
+package watsongen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateRejectsGenericTypes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watsongen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := `package p
+
+type Box[T any] struct {
+	Value T
+}
+`
+	err = ioutil.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Generate(dir, []string{"Box"})
+	if err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
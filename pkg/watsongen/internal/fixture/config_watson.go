@@ -0,0 +1,514 @@
+// Code generated by watson-gen. DO NOT EDIT.
+
+package fixture
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+// MarshalWatson converts x into an Object.
+func (x *Config) MarshalWatson() (*types.Value, error) {
+	if x == nil {
+		return types.NewNilValue(), nil
+	}
+	obj := map[string]*types.Value{}
+	{
+		var v1 *types.Value
+		v1 = types.NewStringValue([]byte(x.Name))
+		obj["name"] = v1
+	}
+	if x.Port != 0 {
+		var v2 *types.Value
+		v2 = types.NewIntValue(int64(x.Port))
+		obj["port"] = v2
+	}
+	if x.Ratio != 0 {
+		var v3 *types.Value
+		v3 = types.NewFloatValue(float64(x.Ratio))
+		obj["ratio"] = v3
+	}
+	{
+		var v4 *types.Value
+		v4 = types.NewIntValue(int64(x.Small))
+		obj["small"] = v4
+	}
+	{
+		var v5 *types.Value
+		v5 = types.NewUintValue(uint64(x.Big))
+		obj["big"] = v5
+	}
+	{
+		var v6 *types.Value
+		v6 = types.NewFloatValue(float64(x.Weight))
+		obj["weight"] = v6
+	}
+	{
+		var v7 *types.Value
+		v7 = types.NewBoolValue(x.Enabled)
+		obj["enabled"] = v7
+	}
+	{
+		var v8 *types.Value
+		if x.Tags == nil {
+			v8 = types.NewNilValue()
+		} else {
+			arr9 := make([]*types.Value, 0, len(x.Tags))
+			for i10 := range x.Tags {
+				var e11 *types.Value
+				e11 = types.NewStringValue([]byte(x.Tags[i10]))
+				arr9 = append(arr9, e11)
+			}
+			v8 = types.NewArrayValue(arr9)
+		}
+		obj["tags"] = v8
+	}
+	if x.Labels != nil {
+		var v12 *types.Value
+		if x.Labels == nil {
+			v12 = types.NewNilValue()
+		} else {
+			obj13 := make(map[string]*types.Value, len(x.Labels))
+			for k14, elem15 := range x.Labels {
+				var e16 *types.Value
+				e16 = types.NewStringValue([]byte(elem15))
+				obj13[k14] = e16
+			}
+			v12 = types.NewObjectValue(obj13)
+		}
+		obj["labels"] = v12
+	}
+	{
+		var v17 *types.Value
+		var err error
+		v17, err = x.Server.MarshalWatson()
+		if err != nil {
+			return nil, err
+		}
+		obj["server"] = v17
+	}
+	{
+		var v18 *types.Value
+		var err error
+		v18, err = x.Backup.MarshalWatson()
+		if err != nil {
+			return nil, err
+		}
+		obj["backup"] = v18
+	}
+	{
+		var v19 *types.Value
+		if x.Replicas == nil {
+			v19 = types.NewNilValue()
+		} else {
+			arr20 := make([]*types.Value, 0, len(x.Replicas))
+			for i21 := range x.Replicas {
+				var e22 *types.Value
+				var err error
+				e22, err = x.Replicas[i21].MarshalWatson()
+				if err != nil {
+					return nil, err
+				}
+				arr20 = append(arr20, e22)
+			}
+			v19 = types.NewArrayValue(arr20)
+		}
+		obj["replicas"] = v19
+	}
+	{
+		var v23 *types.Value
+		if x.ByName == nil {
+			v23 = types.NewNilValue()
+		} else {
+			obj24 := make(map[string]*types.Value, len(x.ByName))
+			for k25, elem26 := range x.ByName {
+				var e27 *types.Value
+				var err error
+				e27, err = elem26.MarshalWatson()
+				if err != nil {
+					return nil, err
+				}
+				obj24[k25] = e27
+			}
+			v23 = types.NewObjectValue(obj24)
+		}
+		obj["byName"] = v23
+	}
+	{
+		var v28 *types.Value
+		if x.Matrix == nil {
+			v28 = types.NewNilValue()
+		} else {
+			arr29 := make([]*types.Value, 0, len(x.Matrix))
+			for i30 := range x.Matrix {
+				var e31 *types.Value
+				if x.Matrix[i30] == nil {
+					e31 = types.NewNilValue()
+				} else {
+					arr32 := make([]*types.Value, 0, len(x.Matrix[i30]))
+					for i33 := range x.Matrix[i30] {
+						var e34 *types.Value
+						e34 = types.NewIntValue(int64(x.Matrix[i30][i33]))
+						arr32 = append(arr32, e34)
+					}
+					e31 = types.NewArrayValue(arr32)
+				}
+				arr29 = append(arr29, e31)
+			}
+			v28 = types.NewArrayValue(arr29)
+		}
+		obj["matrix"] = v28
+	}
+	if x.Owner != nil {
+		var v35 *types.Value
+		if x.Owner == nil {
+			v35 = types.NewNilValue()
+		} else {
+			v35 = types.NewStringValue([]byte((*x.Owner)))
+		}
+		obj["owner"] = v35
+	}
+	{
+		var v36 *types.Value
+		var err error
+		v36, err = x.Meta.MarshalWatson()
+		if err != nil {
+			return nil, err
+		}
+		for k, e := range v36.Object() {
+			obj[k] = e
+		}
+	}
+	{
+		var v37 *types.Value
+		var err error
+		v37, err = types.ToValue(x.Extra)
+		if err != nil {
+			return nil, err
+		}
+		obj["extra"] = v37
+	}
+	if !reflect.ValueOf(x.Attrs).IsZero() {
+		var v38 *types.Value
+		var err error
+		v38, err = types.ToValue(x.Attrs)
+		if err != nil {
+			return nil, err
+		}
+		obj["attrs"] = v38
+	}
+	{
+		var v39 *types.Value
+		var err error
+		v39, err = types.ToValue(x.Point)
+		if err != nil {
+			return nil, err
+		}
+		obj["point"] = v39
+	}
+	return types.NewObjectValue(obj), nil
+}
+
+// UnmarshalWatson converts v, which must be an Object, into x.
+// x is left unchanged if it returns an error.
+func (x *Config) UnmarshalWatson(v *types.Value) error {
+	if v.Kind != types.Object {
+		return types.NewTypeMismatch(v, reflect.TypeOf(x).Elem(), "<root>")
+	}
+	var y Config
+	for k, e := range v.Object() {
+		switch k {
+		case "name":
+			if e.Kind != types.String {
+				return types.NewTypeMismatch(e, reflect.TypeOf((*string)(nil)).Elem(), "<root>.name")
+			}
+			y.Name = string(e.Bytes())
+		case "port":
+			if e.Kind != types.Int {
+				return types.NewTypeMismatch(e, reflect.TypeOf((*int)(nil)).Elem(), "<root>.port")
+			}
+			y.Port = int(e.Int())
+		case "ratio":
+			if e.Kind != types.Float {
+				return types.NewTypeMismatch(e, reflect.TypeOf((*float64)(nil)).Elem(), "<root>.ratio")
+			}
+			y.Ratio = float64(e.Float())
+		case "small":
+			if e.Kind != types.Int {
+				return types.NewTypeMismatch(e, reflect.TypeOf((*int8)(nil)).Elem(), "<root>.small")
+			}
+			y.Small = int8(e.Int())
+		case "big":
+			if e.Kind != types.Uint {
+				return types.NewTypeMismatch(e, reflect.TypeOf((*uint64)(nil)).Elem(), "<root>.big")
+			}
+			y.Big = uint64(e.Uint())
+		case "weight":
+			if e.Kind != types.Float {
+				return types.NewTypeMismatch(e, reflect.TypeOf((*float32)(nil)).Elem(), "<root>.weight")
+			}
+			y.Weight = float32(e.Float())
+		case "enabled":
+			if e.Kind != types.Bool {
+				return types.NewTypeMismatch(e, reflect.TypeOf((*bool)(nil)).Elem(), "<root>.enabled")
+			}
+			y.Enabled = e.Bool()
+		case "tags":
+			switch e.Kind {
+			case types.Nil:
+				y.Tags = nil
+			case types.Array:
+				arr40 := make([]string, len(e.Array()))
+				for i41, e42 := range e.Array() {
+					if e42.Kind != types.String {
+						return types.NewTypeMismatch(e42, reflect.TypeOf((*string)(nil)).Elem(), "<root>.tags"+"["+strconv.Itoa(i41)+"]")
+					}
+					arr40[i41] = string(e42.Bytes())
+				}
+				y.Tags = arr40
+			default:
+				return types.NewTypeMismatch(e, reflect.TypeOf((*[]string)(nil)).Elem(), "<root>.tags")
+			}
+		case "labels":
+			switch e.Kind {
+			case types.Nil:
+				y.Labels = nil
+			case types.Object:
+				obj43 := make(map[string]string, len(e.Object()))
+				for k44, e45 := range e.Object() {
+					var elem46 string
+					if e45.Kind != types.String {
+						return types.NewTypeMismatch(e45, reflect.TypeOf((*string)(nil)).Elem(), "<root>.labels"+"."+k44)
+					}
+					elem46 = string(e45.Bytes())
+					obj43[k44] = elem46
+				}
+				y.Labels = obj43
+			default:
+				return types.NewTypeMismatch(e, reflect.TypeOf((*map[string]string)(nil)).Elem(), "<root>.labels")
+			}
+		case "server":
+			if e.Kind != types.Object {
+				return types.NewTypeMismatch(e, reflect.TypeOf((*Server)(nil)).Elem(), "<root>.server")
+			}
+			if err := y.Server.UnmarshalWatson(e); err != nil {
+				return types.RebaseError(err, "<root>.server")
+			}
+		case "backup":
+			if e.Kind == types.Nil {
+				y.Backup = nil
+			} else {
+				var p47 Server
+				if e.Kind != types.Object {
+					return types.NewTypeMismatch(e, reflect.TypeOf((*Server)(nil)).Elem(), "<root>.backup")
+				}
+				if err := p47.UnmarshalWatson(e); err != nil {
+					return types.RebaseError(err, "<root>.backup")
+				}
+				y.Backup = &p47
+			}
+		case "replicas":
+			switch e.Kind {
+			case types.Nil:
+				y.Replicas = nil
+			case types.Array:
+				arr48 := make([]Server, len(e.Array()))
+				for i49, e50 := range e.Array() {
+					if e50.Kind != types.Object {
+						return types.NewTypeMismatch(e50, reflect.TypeOf((*Server)(nil)).Elem(), "<root>.replicas"+"["+strconv.Itoa(i49)+"]")
+					}
+					if err := arr48[i49].UnmarshalWatson(e50); err != nil {
+						return types.RebaseError(err, "<root>.replicas"+"["+strconv.Itoa(i49)+"]")
+					}
+				}
+				y.Replicas = arr48
+			default:
+				return types.NewTypeMismatch(e, reflect.TypeOf((*[]Server)(nil)).Elem(), "<root>.replicas")
+			}
+		case "byName":
+			switch e.Kind {
+			case types.Nil:
+				y.ByName = nil
+			case types.Object:
+				obj51 := make(map[string]*Server, len(e.Object()))
+				for k52, e53 := range e.Object() {
+					var elem54 *Server
+					if e53.Kind == types.Nil {
+						elem54 = nil
+					} else {
+						var p55 Server
+						if e53.Kind != types.Object {
+							return types.NewTypeMismatch(e53, reflect.TypeOf((*Server)(nil)).Elem(), "<root>.byName"+"."+k52)
+						}
+						if err := p55.UnmarshalWatson(e53); err != nil {
+							return types.RebaseError(err, "<root>.byName"+"."+k52)
+						}
+						elem54 = &p55
+					}
+					obj51[k52] = elem54
+				}
+				y.ByName = obj51
+			default:
+				return types.NewTypeMismatch(e, reflect.TypeOf((*map[string]*Server)(nil)).Elem(), "<root>.byName")
+			}
+		case "matrix":
+			switch e.Kind {
+			case types.Nil:
+				y.Matrix = nil
+			case types.Array:
+				arr56 := make([][]int32, len(e.Array()))
+				for i57, e58 := range e.Array() {
+					switch e58.Kind {
+					case types.Nil:
+						arr56[i57] = nil
+					case types.Array:
+						arr59 := make([]int32, len(e58.Array()))
+						for i60, e61 := range e58.Array() {
+							if e61.Kind != types.Int {
+								return types.NewTypeMismatch(e61, reflect.TypeOf((*int32)(nil)).Elem(), "<root>.matrix"+"["+strconv.Itoa(i57)+"]"+"["+strconv.Itoa(i60)+"]")
+							}
+							arr59[i60] = int32(e61.Int())
+						}
+						arr56[i57] = arr59
+					default:
+						return types.NewTypeMismatch(e58, reflect.TypeOf((*[]int32)(nil)).Elem(), "<root>.matrix"+"["+strconv.Itoa(i57)+"]")
+					}
+				}
+				y.Matrix = arr56
+			default:
+				return types.NewTypeMismatch(e, reflect.TypeOf((*[][]int32)(nil)).Elem(), "<root>.matrix")
+			}
+		case "owner":
+			if e.Kind == types.Nil {
+				y.Owner = nil
+			} else {
+				var p62 string
+				if e.Kind != types.String {
+					return types.NewTypeMismatch(e, reflect.TypeOf((*string)(nil)).Elem(), "<root>.owner")
+				}
+				p62 = string(e.Bytes())
+				y.Owner = &p62
+			}
+		case "meta":
+			if e.Kind != types.Object {
+				return types.NewTypeMismatch(e, reflect.TypeOf((*Meta)(nil)).Elem(), "<root>.meta")
+			}
+			if err := y.Meta.UnmarshalWatson(e); err != nil {
+				return types.RebaseError(err, "<root>.meta")
+			}
+		case "extra":
+			if err := e.Bind(&y.Extra); err != nil {
+				return types.RebaseError(err, "<root>.extra")
+			}
+		case "attrs":
+			if err := e.Bind(&y.Attrs); err != nil {
+				return types.RebaseError(err, "<root>.attrs")
+			}
+		case "point":
+			if err := e.Bind(&y.Point); err != nil {
+				return types.RebaseError(err, "<root>.point")
+			}
+		}
+	}
+	if v.Kind != types.Object {
+		return types.NewTypeMismatch(v, reflect.TypeOf((*Meta)(nil)).Elem(), "<root>")
+	}
+	if err := y.Meta.UnmarshalWatson(v); err != nil {
+		return types.RebaseError(err, "<root>")
+	}
+	*x = y
+	return nil
+}
+
+// MarshalWatson converts x into an Object.
+func (x *Server) MarshalWatson() (*types.Value, error) {
+	if x == nil {
+		return types.NewNilValue(), nil
+	}
+	obj := map[string]*types.Value{}
+	{
+		var v63 *types.Value
+		v63 = types.NewStringValue([]byte(x.Host))
+		obj["host"] = v63
+	}
+	{
+		var v64 *types.Value
+		v64 = types.NewUintValue(uint64(x.Port))
+		obj["port"] = v64
+	}
+	return types.NewObjectValue(obj), nil
+}
+
+// UnmarshalWatson converts v, which must be an Object, into x.
+// x is left unchanged if it returns an error.
+func (x *Server) UnmarshalWatson(v *types.Value) error {
+	if v.Kind != types.Object {
+		return types.NewTypeMismatch(v, reflect.TypeOf(x).Elem(), "<root>")
+	}
+	var y Server
+	for k, e := range v.Object() {
+		switch k {
+		case "host":
+			if e.Kind != types.String {
+				return types.NewTypeMismatch(e, reflect.TypeOf((*string)(nil)).Elem(), "<root>.host")
+			}
+			y.Host = string(e.Bytes())
+		case "port":
+			if e.Kind != types.Uint {
+				return types.NewTypeMismatch(e, reflect.TypeOf((*uint16)(nil)).Elem(), "<root>.port")
+			}
+			y.Port = uint16(e.Uint())
+		}
+	}
+	*x = y
+	return nil
+}
+
+// MarshalWatson converts x into an Object.
+func (x *Meta) MarshalWatson() (*types.Value, error) {
+	if x == nil {
+		return types.NewNilValue(), nil
+	}
+	obj := map[string]*types.Value{}
+	{
+		var v65 *types.Value
+		v65 = types.NewIntValue(int64(x.Version))
+		obj["version"] = v65
+	}
+	if x.Updated != nil {
+		var v66 *types.Value
+		var err error
+		v66, err = types.ToValue(x.Updated)
+		if err != nil {
+			return nil, err
+		}
+		obj["updated"] = v66
+	}
+	return types.NewObjectValue(obj), nil
+}
+
+// UnmarshalWatson converts v, which must be an Object, into x.
+// x is left unchanged if it returns an error.
+func (x *Meta) UnmarshalWatson(v *types.Value) error {
+	if v.Kind != types.Object {
+		return types.NewTypeMismatch(v, reflect.TypeOf(x).Elem(), "<root>")
+	}
+	var y Meta
+	for k, e := range v.Object() {
+		switch k {
+		case "version":
+			if e.Kind != types.Int {
+				return types.NewTypeMismatch(e, reflect.TypeOf((*int)(nil)).Elem(), "<root>.version")
+			}
+			y.Version = int(e.Int())
+		case "updated":
+			if err := e.Bind(&y.Updated); err != nil {
+				return types.RebaseError(err, "<root>.updated")
+			}
+		}
+	}
+	*x = y
+	return nil
+}
@@ -0,0 +1,49 @@
+// Package fixture defines types that are used to test code generated by watson-gen.
+package fixture
+
+//go:generate go run ../../../../cmd/watson-gen -type=Config,Server,Meta
+
+// Config has fields of all kinds of types that watson-gen handles.
+type Config struct {
+	Name     string             `watson:"name"`
+	Port     int                `watson:"port,omitempty"`
+	Ratio    float64            `watson:"ratio,omitempty"`
+	Small    int8               // Keyed by "small".
+	Big      uint64             `watson:"big"`
+	Weight   float32            `watson:"weight"`
+	Enabled  bool               `watson:"enabled"`
+	Tags     []string           `watson:"tags"`
+	Labels   map[string]string  `watson:"labels,omitempty"`
+	Server   Server             `watson:"server"`
+	Backup   *Server            `watson:"backup"`
+	Replicas []Server           `watson:"replicas"`
+	ByName   map[string]*Server `watson:"byName"`
+	Matrix   [][]int32          `watson:"matrix"`
+	Owner    *string            `watson:"owner,omitempty"`
+	Meta     Meta               `watson:",inline"`
+	Extra    interface{}        `watson:"extra"`
+	Attrs    Attributes         `watson:"attrs,omitempty"`
+	Point    [2]float32         `watson:"point"`
+	Ignored  string             `watson:"-"`
+	secret   string
+}
+
+// Server is a nested struct.
+type Server struct {
+	Host string `watson:"host"`
+	Port uint16 `watson:"port"`
+}
+
+// Meta is inlined into Config.
+type Meta struct {
+	Version int       `watson:"version"`
+	Updated *Revision `watson:"updated,omitempty"`
+}
+
+// Revision is a struct whose methods are not generated.
+type Revision struct {
+	Number int `watson:"number"`
+}
+
+// Attributes is a named type whose methods are not generated.
+type Attributes map[string]int
@@ -0,0 +1,233 @@
+package fixture
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+// The types below are the same as the ones in fixture.go except that they don't have generated methods,
+// so they are always converted by reflection.
+
+type reflConfig struct {
+	Name     string                 `watson:"name"`
+	Port     int                    `watson:"port,omitempty"`
+	Ratio    float64                `watson:"ratio,omitempty"`
+	Small    int8                   // Keyed by "small".
+	Big      uint64                 `watson:"big"`
+	Weight   float32                `watson:"weight"`
+	Enabled  bool                   `watson:"enabled"`
+	Tags     []string               `watson:"tags"`
+	Labels   map[string]string      `watson:"labels,omitempty"`
+	Server   reflServer             `watson:"server"`
+	Backup   *reflServer            `watson:"backup"`
+	Replicas []reflServer           `watson:"replicas"`
+	ByName   map[string]*reflServer `watson:"byName"`
+	Matrix   [][]int32              `watson:"matrix"`
+	Owner    *string                `watson:"owner,omitempty"`
+	Meta     reflMeta               `watson:",inline"`
+	Extra    interface{}            `watson:"extra"`
+	Attrs    Attributes             `watson:"attrs,omitempty"`
+	Point    [2]float32             `watson:"point"`
+	Ignored  string                 `watson:"-"`
+	secret   string
+}
+
+type reflServer struct {
+	Host string `watson:"host"`
+	Port uint16 `watson:"port"`
+}
+
+type reflMeta struct {
+	Version int       `watson:"version"`
+	Updated *Revision `watson:"updated,omitempty"`
+}
+
+func str(s string) *types.Value {
+	return types.NewStringValue([]byte(s))
+}
+
+func obj(kvs ...interface{}) *types.Value {
+	o := map[string]*types.Value{}
+	for i := 0; i < len(kvs); i += 2 {
+		o[kvs[i].(string)] = kvs[i+1].(*types.Value)
+	}
+	return types.NewObjectValue(o)
+}
+
+func arr(elems ...*types.Value) *types.Value {
+	return types.NewArrayValue(elems)
+}
+
+func server(host string, port uint64) *types.Value {
+	return obj("host", str(host), "port", types.NewUintValue(port))
+}
+
+// full is a Value that sets all fields of Config.
+var full = obj(
+	"name", str("app"),
+	"port", types.NewIntValue(8080),
+	"ratio", types.NewFloatValue(math.Copysign(0, -1)),
+	"small", types.NewIntValue(-3),
+	"big", types.NewUintValue(math.MaxUint64),
+	"weight", types.NewFloatValue(1.5),
+	"enabled", types.NewBoolValue(true),
+	"tags", arr(str("a"), str("b")),
+	"labels", obj("env", str("prod")),
+	"server", server("localhost", 80),
+	"backup", server("backup", 8080),
+	"replicas", arr(server("r1", 1), server("r2", 2)),
+	"byName", obj("main", server("m", 1), "none", types.NewNilValue()),
+	"matrix", arr(arr(types.NewIntValue(1)), types.NewNilValue(), arr()),
+	"owner", str("me"),
+	"version", types.NewIntValue(2),
+	"updated", obj("number", types.NewIntValue(10)),
+	"extra", arr(types.NewIntValue(1), str("x")),
+	"attrs", obj("a", types.NewIntValue(1)),
+	"point", arr(types.NewFloatValue(1), types.NewFloatValue(2)),
+	"ignored", str("ignored"),
+	"secret", str("secret"),
+	"unknown", str("unknown"),
+)
+
+// marshalBoth converts v into Config and reflConfig, then converts them back into Values.
+func marshalBoth(t *testing.T, v *types.Value) (gen, refl *types.Value, genErr, reflErr error) {
+	t.Helper()
+	var c Config
+	genErr = v.Bind(&c)
+	var r reflConfig
+	reflErr = v.BindByReflection(reflect.ValueOf(&r))
+	if genErr != nil || reflErr != nil {
+		return
+	}
+	gen, err := c.MarshalWatson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refl, err = types.ToValue(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestGeneratedMethodsGiveSameResultsAsReflection(t *testing.T) {
+	test := func(v *types.Value) {
+		t.Helper()
+		gen, refl, genErr, reflErr := marshalBoth(t, v)
+		if genErr != nil || reflErr != nil {
+			t.Fatalf("unexpected error: %v, %v", genErr, reflErr)
+		}
+		if diff := cmp.Diff(refl, gen, cmpopts.EquateNaNs()); diff != "" {
+			t.Errorf("mismatch (-reflection +generated):\n%s", diff)
+		}
+	}
+	test(full)
+	test(obj())
+	test(obj("tags", types.NewNilValue(), "labels", obj(), "replicas", arr(), "byName", types.NewNilValue()))
+	test(obj("attrs", obj(), "ratio", types.NewFloatValue(math.NaN()), "backup", types.NewNilValue(), "meta", obj("version", types.NewIntValue(1))))
+}
+
+func TestGeneratedMethodsReturnSameErrorsAsReflection(t *testing.T) {
+	test := func(v *types.Value) {
+		t.Helper()
+		_, _, genErr, reflErr := marshalBoth(t, v)
+		if genErr == nil || reflErr == nil {
+			t.Fatalf("expected errors but got %v, %v", genErr, reflErr)
+		}
+		want := strings.ReplaceAll(reflErr.Error(), "refl", "")
+		if got := genErr.Error(); got != want {
+			t.Errorf("expected %#v but got %#v", want, got)
+		}
+	}
+	test(types.NewNilValue())
+	test(arr())
+	test(obj("name", types.NewIntValue(1)))
+	test(obj("port", types.NewUintValue(1)))
+	test(obj("big", types.NewIntValue(1)))
+	test(obj("tags", arr(str("a"), types.NewIntValue(1))))
+	test(obj("tags", str("a")))
+	test(obj("labels", obj("a", types.NewBoolValue(true))))
+	test(obj("server", types.NewNilValue()))
+	test(obj("server", obj("port", types.NewIntValue(1))))
+	test(obj("backup", str("x")))
+	test(obj("replicas", arr(server("a", 1), types.NewNilValue())))
+	test(obj("byName", obj("a", arr())))
+	test(obj("matrix", arr(arr(), arr(types.NewIntValue(1), types.NewFloatValue(1)))))
+	test(obj("owner", types.NewIntValue(1)))
+	test(obj("version", str("1")))
+	test(obj("updated", obj("number", str("1"))))
+	test(obj("attrs", obj("a", str("1"))))
+	test(obj("point", arr(types.NewFloatValue(1), types.NewFloatValue(2), types.NewFloatValue(3))))
+}
+
+func TestUnmarshalWatsonLeavesReceiverUnchangedOnError(t *testing.T) {
+	c := Config{Name: "original"}
+	err := c.UnmarshalWatson(obj("name", str("new"), "port", str("x")))
+	if err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	if c.Name != "original" {
+		t.Errorf("receiver is modified: %#v", c.Name)
+	}
+}
+
+func TestUnmarshalWatsonResetsReceiver(t *testing.T) {
+	c := Config{Name: "original", Port: 1}
+	err := c.UnmarshalWatson(obj("port", types.NewIntValue(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(Config{Port: 2}, c, cmp.AllowUnexported(Config{})); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshalWatsonHandlesNilReceiver(t *testing.T) {
+	var c *Config
+	got, err := c.MarshalWatson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(types.NewNilValue(), got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func BenchmarkUnmarshalGenerated(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var c Config
+		_ = c.UnmarshalWatson(full)
+	}
+}
+
+func BenchmarkUnmarshalReflection(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var r reflConfig
+		_ = full.BindByReflection(reflect.ValueOf(&r))
+	}
+}
+
+func BenchmarkMarshalGenerated(b *testing.B) {
+	var c Config
+	_ = c.UnmarshalWatson(full)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = c.MarshalWatson()
+	}
+}
+
+func BenchmarkMarshalReflection(b *testing.B) {
+	var r reflConfig
+	_ = full.BindByReflection(reflect.ValueOf(&r))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = types.ToValue(r)
+	}
+}
@@ -0,0 +1,548 @@
+// Package watsongen generates MarshalWatson and UnmarshalWatson methods for struct types so that they can be converted without reflection.
+//
+// The generated methods have pointer receivers, so they implement types.Marshaler and types.Unmarshaler
+// and are used by watson.Marshal, watson.Unmarshal, types.ToValue, and types.Value.Bind.
+// They give the same results as the reflection-based conversion of the types without the generated methods, that is,
+// `watson` tags are handled in the same way, and the same errors are returned from UnmarshalWatson.
+//
+// Fields of the following types are converted without reflection:
+//   * bool, string, and any built-in integer and floating-point types.
+//   * Struct types whose methods are generated at the same time.
+//   * Pointers to, slices of, and maps from string to any of these types.
+//
+// Fields of any other types are converted by types.ToValue and types.Value.Bind.
+package watsongen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Generate parses the Go package in dir and returns gofmt-formatted source that defines MarshalWatson and UnmarshalWatson of the types named typeNames.
+func Generate(dir string, typeNames []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected exactly one package in %s, but found %d", dir, len(pkgs))
+	}
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		pkg = p
+	}
+
+	structs := map[string]*ast.TypeSpec{}
+	fileNames := make([]string, 0, len(pkg.Files))
+	for name := range pkg.Files {
+		fileNames = append(fileNames, name)
+	}
+	sort.Strings(fileNames)
+	for _, name := range fileNames {
+		for _, decl := range pkg.Files[name].Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if _, ok := ts.Type.(*ast.StructType); ok {
+					structs[ts.Name.Name] = ts
+				}
+			}
+		}
+	}
+
+	g := &generator{
+		fset:      fset,
+		generated: map[string]bool{},
+	}
+	specs := make([]*ast.TypeSpec, 0, len(typeNames))
+	for _, name := range typeNames {
+		ts, ok := structs[name]
+		if !ok {
+			return nil, fmt.Errorf("struct type %s not found in %s", name, dir)
+		}
+		if isGeneric(ts) {
+			return nil, fmt.Errorf("generic type %s is not supported", name)
+		}
+		g.generated[name] = true
+		specs = append(specs, ts)
+	}
+	for _, ts := range specs {
+		err := g.generateType(ts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out := bytes.NewBuffer(nil)
+	fmt.Fprintf(out, "// Code generated by watson-gen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg.Name)
+	out.WriteString("\"reflect\"\n")
+	if g.usesStrconv {
+		out.WriteString("\"strconv\"\n")
+	}
+	out.WriteString("\n\"github.com/genkami/watson/pkg/types\"\n)\n")
+	out.Write(g.buf.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		// This should not happen.
+		return nil, fmt.Errorf("generated invalid source: %w", err)
+	}
+	return src, nil
+}
+
+type kind int
+
+const (
+	kindInt kind = iota
+	kindUint
+	kindFloat
+	kindString
+	kindBool
+	kindStruct
+	kindPtr
+	kindSlice
+	kindMap
+	kindOther
+)
+
+// typ is a type of a field.
+type typ struct {
+	kind kind
+	// expr is the type written in Go.
+	expr string
+	// elem is the type of elements of pointers, slices, and maps.
+	elem *typ
+	// nillable reports whether the zero value of the type is nil.
+	nillable bool
+}
+
+var builtinKinds = map[string]kind{
+	"int": kindInt, "int8": kindInt, "int16": kindInt, "int32": kindInt, "int64": kindInt, "rune": kindInt,
+	"uint": kindUint, "uint8": kindUint, "uint16": kindUint, "uint32": kindUint, "uint64": kindUint, "byte": kindUint,
+	"float32": kindFloat, "float64": kindFloat,
+	"string": kindString,
+	"bool":   kindBool,
+}
+
+// field is a field of a struct.
+type field struct {
+	name      string
+	key       string
+	omitted   bool
+	omitempty bool
+	inline    bool
+	t         *typ
+}
+
+type generator struct {
+	fset        *token.FileSet
+	buf         bytes.Buffer
+	generated   map[string]bool
+	counter     int
+	usesStrconv bool
+}
+
+func (g *generator) typeOf(expr ast.Expr) *typ {
+	t := &typ{kind: kindOther, expr: g.exprString(expr)}
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if k, ok := builtinKinds[expr.Name]; ok {
+			t.kind = k
+		} else if g.generated[expr.Name] {
+			t.kind = kindStruct
+		} else if expr.Name == "any" {
+			t.nillable = true
+		}
+	case *ast.StarExpr:
+		t.nillable = true
+		if elem := g.typeOf(expr.X); elem.kind != kindOther {
+			t.kind = kindPtr
+			t.elem = elem
+		}
+	case *ast.ArrayType:
+		if expr.Len != nil {
+			break
+		}
+		t.nillable = true
+		if elem := g.typeOf(expr.Elt); elem.kind != kindOther {
+			t.kind = kindSlice
+			t.elem = elem
+		}
+	case *ast.MapType:
+		t.nillable = true
+		key, ok := expr.Key.(*ast.Ident)
+		if !ok || key.Name != "string" {
+			break
+		}
+		if elem := g.typeOf(expr.Value); elem.kind != kindOther {
+			t.kind = kindMap
+			t.elem = elem
+		}
+	case *ast.InterfaceType, *ast.ChanType, *ast.FuncType:
+		t.nillable = true
+	}
+	return t
+}
+
+func (g *generator) exprString(expr ast.Expr) string {
+	buf := bytes.NewBuffer(nil)
+	err := format.Node(buf, g.fset, expr)
+	if err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+// fieldsOf lists fields of st in the same way as types.parseTag does.
+func (g *generator) fieldsOf(st *ast.StructType) ([]*field, error) {
+	fields := make([]*field, 0, len(st.Fields.List))
+	for _, f := range st.Fields.List {
+		names := make([]string, 0, len(f.Names))
+		for _, name := range f.Names {
+			names = append(names, name.Name)
+		}
+		if len(names) == 0 {
+			name, err := embeddedName(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+		}
+		tag := ""
+		if f.Tag != nil {
+			lit, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+			tag = reflect.StructTag(lit).Get("watson")
+		}
+		for _, name := range names {
+			fd := &field{name: name, t: g.typeOf(f.Type)}
+			if tag != "" {
+				attrs := strings.Split(tag, ",")
+				if attrs[0] == "-" {
+					fd.omitted = true
+				} else {
+					fd.key = attrs[0]
+				}
+				for _, attr := range attrs[1:] {
+					switch attr {
+					case "omitempty":
+						fd.omitempty = true
+					case "inline":
+						fd.inline = true
+					}
+				}
+			}
+			if fd.key == "" {
+				fd.key = strings.ToLower(name)
+			}
+			r, _ := utf8.DecodeRuneInString(name)
+			if unicode.IsLower(r) {
+				fd.omitted = true
+			}
+			if fd.inline && !fd.omitted && fd.t.kind != kindStruct {
+				return nil, fmt.Errorf("inline field %s must have one of the types that are being generated", name)
+			}
+			fields = append(fields, fd)
+		}
+	}
+	return fields, nil
+}
+
+func embeddedName(expr ast.Expr) (string, error) {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name, nil
+	case *ast.StarExpr:
+		return embeddedName(expr.X)
+	case *ast.SelectorExpr:
+		return expr.Sel.Name, nil
+	default:
+		return "", errors.New("unsupported embedded field")
+	}
+}
+
+func (g *generator) p(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteString("\n")
+}
+
+func (g *generator) newVar(prefix string) string {
+	g.counter++
+	return fmt.Sprintf("%s%d", prefix, g.counter)
+}
+
+func (g *generator) generateType(ts *ast.TypeSpec) error {
+	name := ts.Name.Name
+	fields, err := g.fieldsOf(ts.Type.(*ast.StructType))
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	g.generateMarshal(name, fields)
+	g.generateUnmarshal(name, fields)
+	return nil
+}
+
+func (g *generator) generateMarshal(name string, fields []*field) {
+	g.p("\n// MarshalWatson converts x into an Object.")
+	g.p("func (x *%s) MarshalWatson() (*types.Value, error) {", name)
+	g.p("if x == nil {")
+	g.p("return types.NewNilValue(), nil")
+	g.p("}")
+	g.p("obj := map[string]*types.Value{}")
+	for _, f := range fields {
+		if f.omitted {
+			continue
+		}
+		src := "x." + f.name
+		if f.omitempty {
+			g.p("if %s {", g.nonZero(f.t, src))
+		} else {
+			g.p("{")
+		}
+		v := g.newVar("v")
+		g.p("var %s *types.Value", v)
+		g.marshal(f.t, src, v)
+		if f.inline {
+			g.p("for k, e := range %s.Object() {", v)
+			g.p("obj[k] = e")
+			g.p("}")
+		} else {
+			g.p("obj[%s] = %s", strconv.Quote(f.key), v)
+		}
+		g.p("}")
+	}
+	g.p("return types.NewObjectValue(obj), nil")
+	g.p("}")
+}
+
+// nonZero returns an expression that reports whether src is not the zero value in the sense of reflect.Value.IsZero.
+func (g *generator) nonZero(t *typ, src string) string {
+	switch {
+	case t.kind == kindInt || t.kind == kindUint || t.kind == kindFloat:
+		return src + " != 0"
+	case t.kind == kindString:
+		return src + ` != ""`
+	case t.kind == kindBool:
+		return src
+	case t.nillable:
+		return src + " != nil"
+	default:
+		return fmt.Sprintf("!reflect.ValueOf(%s).IsZero()", src)
+	}
+}
+
+// marshal writes code that converts src of type t and assigns it to dst.
+func (g *generator) marshal(t *typ, src, dst string) {
+	switch t.kind {
+	case kindInt:
+		g.p("%s = types.NewIntValue(int64(%s))", dst, src)
+	case kindUint:
+		g.p("%s = types.NewUintValue(uint64(%s))", dst, src)
+	case kindFloat:
+		g.p("%s = types.NewFloatValue(float64(%s))", dst, src)
+	case kindString:
+		g.p("%s = types.NewStringValue([]byte(%s))", dst, src)
+	case kindBool:
+		g.p("%s = types.NewBoolValue(%s)", dst, src)
+	case kindStruct:
+		g.marshalByMethod(src, dst)
+	case kindPtr:
+		if t.elem.kind == kindStruct {
+			// MarshalWatson handles nil by itself.
+			g.marshalByMethod(src, dst)
+			return
+		}
+		g.p("if %s == nil {", src)
+		g.p("%s = types.NewNilValue()", dst)
+		g.p("} else {")
+		g.marshal(t.elem, "(*"+src+")", dst)
+		g.p("}")
+	case kindSlice:
+		arr := g.newVar("arr")
+		i := g.newVar("i")
+		e := g.newVar("e")
+		g.p("if %s == nil {", src)
+		g.p("%s = types.NewNilValue()", dst)
+		g.p("} else {")
+		g.p("%s := make([]*types.Value, 0, len(%s))", arr, src)
+		g.p("for %s := range %s {", i, src)
+		g.p("var %s *types.Value", e)
+		g.marshal(t.elem, fmt.Sprintf("%s[%s]", src, i), e)
+		g.p("%s = append(%s, %s)", arr, arr, e)
+		g.p("}")
+		g.p("%s = types.NewArrayValue(%s)", dst, arr)
+		g.p("}")
+	case kindMap:
+		obj := g.newVar("obj")
+		k := g.newVar("k")
+		elem := g.newVar("elem")
+		e := g.newVar("e")
+		g.p("if %s == nil {", src)
+		g.p("%s = types.NewNilValue()", dst)
+		g.p("} else {")
+		g.p("%s := make(map[string]*types.Value, len(%s))", obj, src)
+		g.p("for %s, %s := range %s {", k, elem, src)
+		g.p("var %s *types.Value", e)
+		g.marshal(t.elem, elem, e)
+		g.p("%s[%s] = %s", obj, k, e)
+		g.p("}")
+		g.p("%s = types.NewObjectValue(%s)", dst, obj)
+		g.p("}")
+	default:
+		g.p("var err error")
+		g.p("%s, err = types.ToValue(%s)", dst, src)
+		g.p("if err != nil {")
+		g.p("return nil, err")
+		g.p("}")
+	}
+}
+
+func (g *generator) marshalByMethod(src, dst string) {
+	g.p("var err error")
+	g.p("%s, err = %s.MarshalWatson()", dst, src)
+	g.p("if err != nil {")
+	g.p("return nil, err")
+	g.p("}")
+}
+
+func (g *generator) generateUnmarshal(name string, fields []*field) {
+	g.p("\n// UnmarshalWatson converts v, which must be an Object, into x.")
+	g.p("// x is left unchanged if it returns an error.")
+	g.p("func (x *%s) UnmarshalWatson(v *types.Value) error {", name)
+	g.p("if v.Kind != types.Object {")
+	g.p(`return types.NewTypeMismatch(v, reflect.TypeOf(x).Elem(), "<root>")`)
+	g.p("}")
+	g.p("var y %s", name)
+
+	// Like types.findField, the first field that has the key wins even if it is omitted.
+	seen := map[string]bool{}
+	cases := make([]*field, 0, len(fields))
+	for _, f := range fields {
+		if seen[f.key] {
+			continue
+		}
+		seen[f.key] = true
+		if !f.omitted {
+			cases = append(cases, f)
+		}
+	}
+	if len(cases) > 0 {
+		g.p("for k, e := range v.Object() {")
+		g.p("switch k {")
+		for _, f := range cases {
+			g.p("case %s:", strconv.Quote(f.key))
+			g.unmarshal(f.t, "e", "y."+f.name, strconv.Quote("<root>."+f.key))
+		}
+		g.p("}")
+		g.p("}")
+	}
+	for _, f := range fields {
+		if f.inline && !f.omitted {
+			g.unmarshal(f.t, "v", "y."+f.name, `"<root>"`)
+		}
+	}
+	g.p("*x = y")
+	g.p("return nil")
+	g.p("}")
+}
+
+// unmarshal writes code that converts src into type t and assigns it to dst.
+// path is an expression that evaluates to the path of src in error messages.
+func (g *generator) unmarshal(t *typ, src, dst, path string) {
+	mismatch := func(t *typ) {
+		g.p("return types.NewTypeMismatch(%s, reflect.TypeOf((*%s)(nil)).Elem(), %s)", src, t.expr, path)
+	}
+	scalar := func(kind, value string) {
+		g.p("if %s.Kind != types.%s {", src, kind)
+		mismatch(t)
+		g.p("}")
+		g.p("%s = %s", dst, value)
+	}
+	switch t.kind {
+	case kindInt:
+		scalar("Int", fmt.Sprintf("%s(%s.Int())", t.expr, src))
+	case kindUint:
+		scalar("Uint", fmt.Sprintf("%s(%s.Uint())", t.expr, src))
+	case kindFloat:
+		scalar("Float", fmt.Sprintf("%s(%s.Float())", t.expr, src))
+	case kindString:
+		scalar("String", fmt.Sprintf("string(%s.Bytes())", src))
+	case kindBool:
+		scalar("Bool", fmt.Sprintf("%s.Bool()", src))
+	case kindStruct:
+		g.p("if %s.Kind != types.Object {", src)
+		mismatch(t)
+		g.p("}")
+		g.p("if err := %s.UnmarshalWatson(%s); err != nil {", dst, src)
+		g.p("return types.RebaseError(err, %s)", path)
+		g.p("}")
+	case kindPtr:
+		tmp := g.newVar("p")
+		g.p("if %s.Kind == types.Nil {", src)
+		g.p("%s = nil", dst)
+		g.p("} else {")
+		g.p("var %s %s", tmp, t.elem.expr)
+		g.unmarshal(t.elem, src, tmp, path)
+		g.p("%s = &%s", dst, tmp)
+		g.p("}")
+	case kindSlice:
+		g.usesStrconv = true
+		arr := g.newVar("arr")
+		i := g.newVar("i")
+		e := g.newVar("e")
+		g.p("switch %s.Kind {", src)
+		g.p("case types.Nil:")
+		g.p("%s = nil", dst)
+		g.p("case types.Array:")
+		g.p("%s := make(%s, len(%s.Array()))", arr, t.expr, src)
+		g.p("for %s, %s := range %s.Array() {", i, e, src)
+		g.unmarshal(t.elem, e, fmt.Sprintf("%s[%s]", arr, i), fmt.Sprintf(`%s + "[" + strconv.Itoa(%s) + "]"`, path, i))
+		g.p("}")
+		g.p("%s = %s", dst, arr)
+		g.p("default:")
+		mismatch(t)
+		g.p("}")
+	case kindMap:
+		obj := g.newVar("obj")
+		k := g.newVar("k")
+		e := g.newVar("e")
+		tmp := g.newVar("elem")
+		g.p("switch %s.Kind {", src)
+		g.p("case types.Nil:")
+		g.p("%s = nil", dst)
+		g.p("case types.Object:")
+		g.p("%s := make(%s, len(%s.Object()))", obj, t.expr, src)
+		g.p("for %s, %s := range %s.Object() {", k, e, src)
+		g.p("var %s %s", tmp, t.elem.expr)
+		g.unmarshal(t.elem, e, tmp, fmt.Sprintf(`%s + "." + %s`, path, k))
+		g.p("%s[%s] = %s", obj, k, tmp)
+		g.p("}")
+		g.p("%s = %s", dst, obj)
+		g.p("default:")
+		mismatch(t)
+		g.p("}")
+	default:
+		g.p("if err := %s.Bind(&%s); err != nil {", src, dst)
+		g.p("return types.RebaseError(err, %s)", path)
+		g.p("}")
+	}
+}
@@ -0,0 +1,53 @@
+package watsongen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGeneratedFixtureIsUpToDate(t *testing.T) {
+	want, err := ioutil.ReadFile(filepath.Join("internal", "fixture", "config_watson.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Generate(filepath.Join("internal", "fixture"), []string{"Config", "Server", "Meta"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(want), string(got)); diff != "" {
+		t.Errorf("config_watson.go is out of date; run `go generate` in internal/fixture (-committed +generated):\n%s", diff)
+	}
+}
+
+func TestGenerateRejectsUnsupportedTypes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watsongen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := `package p
+
+type Inlined struct {
+	Attrs map[string]int ` + "`watson:\",inline\"`" + `
+}
+
+type NotStruct int
+`
+	err = ioutil.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test := func(typeName string) {
+		_, err := Generate(dir, []string{typeName})
+		if err == nil {
+			t.Errorf("%s: expected error but got nil", typeName)
+		}
+	}
+	test("Inlined")
+	test("NotStruct")
+	test("Missing")
+}
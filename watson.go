@@ -7,6 +7,7 @@ import (
 
 	"github.com/genkami/watson/pkg/dumper"
 	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/sourcemap"
 	"github.com/genkami/watson/pkg/types"
 	"github.com/genkami/watson/pkg/vm"
 )
@@ -75,6 +76,11 @@ func (e *Encoder) Encode(v interface{}) error {
 type Decoder struct {
 	l         *lexer.Lexer
 	stackSize int
+	// vm is reused by Decode if the stack size is not the default.
+	vm *vm.VM
+	// sourceMap is the SourceMap of the last decoded value, which is only recorded if recordSourceMap is true.
+	recordSourceMap bool
+	sourceMap       *sourcemap.SourceMap
 }
 
 // NewDecoder creates a new Decoder that reads from r.
@@ -89,21 +95,56 @@ func NewDecoder(r io.Reader) *Decoder {
 // See watson/pkg/vm for more details.
 func (d *Decoder) SetStacksize(size int) {
 	d.stackSize = size
+	d.vm = nil
 }
 
+// SetRecordSourceMap makes Decode record where each part of the decoded value comes from, which can be obtained by SourceMap.
+// Errors of binding the value are then wrapped in *sourcemap.Error so that they cite positions in the input.
+//
+// Decode is slower while it records SourceMaps.
+func (d *Decoder) SetRecordSourceMap(record bool) {
+	d.recordSourceMap = record
+}
+
+// SourceMap returns the SourceMap of the value decoded by the last call of Decode,
+// or nil if it is not recorded. See SetRecordSourceMap.
+func (d *Decoder) SourceMap() *sourcemap.SourceMap {
+	return d.sourceMap
+}
+
+// decodeBatchSize is the number of Ops that Decode reads at once.
+const decodeBatchSize = 1024
+
+// defaultVMPool holds VMs whose stack size is the default, which are shared by all Decoders.
+var defaultVMPool = vm.NewPool()
+
 // Decode reads a Watson value from the underlying io.Reader and converts it into v.
 func (d *Decoder) Decode(v interface{}) error {
-	m := vm.NewVM(vm.WithStackSize(d.stackSize))
+	d.sourceMap = nil
+	if d.recordSourceMap {
+		return d.decodeWithSourceMap(v)
+	}
+	var m *vm.VM
+	if d.stackSize <= 0 {
+		m = defaultVMPool.Get()
+		defer defaultVMPool.Put(m)
+	} else {
+		if d.vm == nil {
+			d.vm = vm.NewVM(vm.WithStackSize(d.stackSize))
+		}
+		m = d.vm
+		defer m.Reset()
+	}
+	ops := make([]vm.Op, decodeBatchSize)
 	for {
-		tok, err := d.l.Next()
-		if err == io.EOF {
-			break
-		} else if err != nil {
+		n, lexErr := d.l.ReadOps(ops)
+		if err := m.Run(ops[:n]); err != nil {
 			return err
 		}
-		err = m.Feed(tok.Op)
-		if err != nil {
-			return err
+		if lexErr == io.EOF {
+			break
+		} else if lexErr != nil {
+			return lexErr
 		}
 	}
 	top, err := m.Top()
@@ -112,3 +153,32 @@ func (d *Decoder) Decode(v interface{}) error {
 	}
 	return top.Bind(v)
 }
+
+func (d *Decoder) decodeWithSourceMap(v interface{}) error {
+	rec := sourcemap.NewRecorder()
+	m := vm.NewVM(vm.WithStackSize(d.stackSize), vm.WithTracer(rec))
+	toks := make([]lexer.Token, decodeBatchSize)
+	for {
+		n, lexErr := d.l.NextN(toks)
+		for i := range toks[:n] {
+			if err := rec.Feed(m, &toks[i]); err != nil {
+				return err
+			}
+		}
+		if lexErr == io.EOF {
+			break
+		} else if lexErr != nil {
+			return lexErr
+		}
+	}
+	top, err := m.Top()
+	if err != nil {
+		return err
+	}
+	sm, err := rec.SourceMap()
+	if err != nil {
+		return err
+	}
+	d.sourceMap = sm
+	return sm.Annotate(top.Bind(v))
+}
@@ -1,12 +1,16 @@
 package watson_test
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 
 	"github.com/genkami/watson"
+	"github.com/genkami/watson/pkg/sourcemap"
 	"github.com/genkami/watson/pkg/types"
 )
 
@@ -34,7 +38,7 @@ func (d *DepartmentName) UnmarshalWatson(v *types.Value) error {
 	if v.Kind != types.String {
 		return fmt.Errorf("expected string but got %#v", v.Kind)
 	}
-	d.Value = string(v.String)
+	d.Value = string(v.Bytes())
 	return nil
 }
 
@@ -65,3 +69,35 @@ func encodeThenDecode(in interface{}, out interface{}) error {
 	}
 	return watson.Unmarshal(encoded, out)
 }
+
+func TestDecoderRecordsSourceMap(t *testing.T) {
+	encoded, err := watson.Marshal(map[string]interface{}{
+		"fullName": "Tanaka Taro",
+		"age":      "forty-one",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := watson.NewDecoder(bytes.NewReader(encoded))
+	dec.SetRecordSourceMap(true)
+	var u User
+	err = dec.Decode(&u)
+	var e *sourcemap.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expected *sourcemap.Error but got %v", err)
+	}
+	sm := dec.SourceMap()
+	if sm == nil {
+		t.Fatal("SourceMap is not recorded")
+	}
+	span, ok := sm.Lookup("<root>.age")
+	if !ok {
+		t.Fatal("<root>.age is not found")
+	}
+	if diff := cmp.Diff(span, e.Span); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if want := span.String() + ": "; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("expected %q to start with %q", err.Error(), want)
+	}
+}